@@ -0,0 +1,61 @@
+// Command ledgercheck verifies the ledger: every journal entry balances, its
+// lines are booked in its currency, and the cash booked for each booking
+// matches what its payments captured net of refunds. It prints the trial
+// balance and exits with status 1 when it finds a problem.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+
+	"github.com/shardy678/pet-freelance/backend/internal/config"
+	"github.com/shardy678/pet-freelance/backend/internal/db"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+func main() {
+	if err := godotenv.Load("./.env"); err != nil {
+		log.Println("No .env file found, continuing with environment variables…")
+	}
+
+	conn, err := db.Connect(config.Load())
+	if err != nil {
+		log.Fatalf("ledgercheck: failed to connect to database: %v", err)
+	}
+	ledger := service.NewLedgerService(repository.NewLedgerRepository(conn), repository.NewPaymentRepository(conn))
+	ctx := context.Background()
+
+	balances, err := ledger.TrialBalance(ctx)
+	if err != nil {
+		log.Fatalf("ledgercheck: trial balance: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "account\tholder\tcurrency\tbalance\t")
+	for _, b := range balances {
+		holder := "platform"
+		if b.UserID != uuid.Nil {
+			holder = b.UserID.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", b.Kind, holder, b.Currency, b.Balance.Decimal())
+	}
+	w.Flush()
+
+	problems, err := ledger.Check(ctx)
+	if err != nil {
+		log.Fatalf("ledgercheck: %v", err)
+	}
+	for _, p := range problems {
+		fmt.Println("problem:", p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ledger is consistent")
+}
@@ -1,17 +1,114 @@
 package config
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
 
 type AppConfig struct {
-	DSN       string
-	JWTSecret string
+	// Env is "development", "dev" or "test" on machines of developers and in
+	// CI, which allows the dev-only secrets and the fake payment provider;
+	// anything else counts as production
+	Env            string
+	DSN            string
+	JWTSecret      string
+	IdempotencyTTL time.Duration
+	// WaitlistOfferTTL is how long a freed seat is held for a waitlisted owner
+	WaitlistOfferTTL time.Duration
+	// CalendarImportInterval is how often imported calendars are fetched again
+	CalendarImportInterval time.Duration
+	// DataKey encrypts sensitive booking details, such as door codes, at rest
+	DataKey string
+	// UploadDir is where uploaded files such as visit photos are stored
+	UploadDir string
+	// PaymentProvider names the payment backend. Only "fake" exists so far,
+	// and only in development; without one, payments are disabled
+	PaymentProvider      string
+	PaymentWebhookSecret string
+	// PaymentTimeout is how long a booking awaits its up-front payment
+	PaymentTimeout time.Duration
+	// CommissionBps is the platform's default cut of each booking, in basis points
+	CommissionBps int
+	// TaxBps is the tax charged on top of each booking's subtotal, in basis points
+	TaxBps int
+	// ServiceFeeBps is the platform's booking fee charged to owners, in basis points
+	ServiceFeeBps int
+	// PayoutHold is how long earnings wait after completion before they are paid out
+	PayoutHold time.Duration
+	// PayoutInterval is how often available earnings are batched into payouts
+	PayoutInterval time.Duration
+	// ExchangeRatesFile, if set, is a JSON rate table loaded at startup while
+	// no rates are stored
+	ExchangeRatesFile string
 }
 
 func Load() *AppConfig {
+	env := getenv("APP_ENV", "production")
+	// secrets only have a default in development; elsewhere Check insists on them
+	devOnly := func(def string) string {
+		if isDevelopment(env) {
+			return def
+		}
+		return ""
+	}
 	return &AppConfig{
-		DSN:       getenv("DATABASE_URL", "host=localhost user=app dbname=app sslmode=disable"),
-		JWTSecret: getenv("JWT_SECRET", "dev‑only‑secret"),
+		Env:                    env,
+		DSN:                    getenv("DATABASE_URL", "host=localhost user=app dbname=app sslmode=disable"),
+		JWTSecret:              getenv("JWT_SECRET", devOnly("dev‑only‑secret")),
+		IdempotencyTTL:         getduration("IDEMPOTENCY_TTL", 24*time.Hour),
+		WaitlistOfferTTL:       getduration("WAITLIST_OFFER_TTL", 2*time.Hour),
+		CalendarImportInterval: getduration("CALENDAR_IMPORT_INTERVAL", time.Hour),
+		DataKey:                getenv("DATA_ENCRYPTION_KEY", devOnly("dev-only-data-key")),
+		UploadDir:              getenv("UPLOAD_DIR", "uploads"),
+		PaymentProvider:        getenv("PAYMENT_PROVIDER", devOnly("fake")),
+		PaymentWebhookSecret:   getenv("PAYMENT_WEBHOOK_SECRET", devOnly("dev-only-webhook-secret")),
+		PaymentTimeout:         getduration("PAYMENT_TIMEOUT", 30*time.Minute),
+		CommissionBps:          getint("COMMISSION_BPS", 1500),
+		TaxBps:                 getint("TAX_BPS", 0),
+		ServiceFeeBps:          getint("SERVICE_FEE_BPS", 0),
+		PayoutHold:             getduration("PAYOUT_HOLD", 72*time.Hour),
+		PayoutInterval:         getduration("PAYOUT_INTERVAL", 24*time.Hour),
+		ExchangeRatesFile:      getenv("EXCHANGE_RATES_FILE", ""),
+	}
+}
+
+// IsDevelopment reports whether the app runs on a developer's machine or in
+// CI rather than in production.
+func (c *AppConfig) IsDevelopment() bool {
+	return isDevelopment(c.Env)
+}
+
+func isDevelopment(env string) bool {
+	return env == "development" || env == "dev" || env == "test"
+}
+
+// Check returns an error naming the secrets that are unset. Outside
+// development they have no defaults, so nothing is signed or sealed with a
+// key that is public. The webhook secret is only needed with a payment
+// provider, and the fake provider is refused outside development.
+func (c *AppConfig) Check() error {
+	var missing []string
+	for _, v := range []struct {
+		name, value string
+		needed      bool
+	}{
+		{"JWT_SECRET", c.JWTSecret, true},
+		{"DATA_ENCRYPTION_KEY", c.DataKey, true},
+		{"PAYMENT_WEBHOOK_SECRET", c.PaymentWebhookSecret, c.PaymentProvider != ""},
+	} {
+		if v.needed && v.value == "" {
+			missing = append(missing, v.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("config: %v must be set when APP_ENV is %q", missing, c.Env)
+	}
+	if c.PaymentProvider == "fake" && !c.IsDevelopment() {
+		return fmt.Errorf("config: the fake payment provider is for development only, not APP_ENV %q", c.Env)
 	}
+	return nil
 }
 
 func getenv(k, def string) string {
@@ -20,3 +117,21 @@ func getenv(k, def string) string {
 	}
 	return def
 }
+
+func getduration(k string, def time.Duration) time.Duration {
+	if v := os.Getenv(k); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+func getint(k string, def int) int {
+	if v := os.Getenv(k); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
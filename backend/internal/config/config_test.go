@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductionNeedsNoPaymentProvider(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "jwt")
+	t.Setenv("DATA_ENCRYPTION_KEY", "key")
+	t.Setenv("PAYMENT_PROVIDER", "")
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "")
+
+	cfg := Load()
+	assert.Equal(t, "", cfg.PaymentProvider)
+	assert.NoError(t, cfg.Check())
+
+	// a provider needs its webhook secret
+	cfg.PaymentProvider = "fake"
+	assert.ErrorContains(t, cfg.Check(), "PAYMENT_WEBHOOK_SECRET")
+	// and the fake one is never allowed in production
+	cfg.PaymentWebhookSecret = "whsec"
+	assert.ErrorContains(t, cfg.Check(), "development only")
+}
+
+func TestDevelopmentDefaultsToFakePayments(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("PAYMENT_PROVIDER", "")
+
+	cfg := Load()
+	assert.Equal(t, "fake", cfg.PaymentProvider)
+	assert.NoError(t, cfg.Check())
+}
+
+func TestAirCountsAsDevelopment(t *testing.T) {
+	t.Setenv("APP_ENV", "dev")
+	t.Setenv("JWT_SECRET", "")
+
+	assert.NoError(t, Load().Check())
+}
@@ -30,14 +30,55 @@ func Init() {
 		&models.User{},
 		&models.Service{},
 		&models.ServiceOffer{},
+		&models.AddOn{},
+		&models.PricingRule{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Package{},
+		&models.CreditPack{},
+		&models.FreelancerSettings{},
+		&models.TimeOff{},
 		&models.AvailabilitySlot{},
 		&models.Booking{},
+		&models.BookingReschedule{},
+		&models.BookingSeries{},
+		&models.StayNight{},
+		&models.WaitlistEntry{},
+		&models.VisitReport{},
+		&models.VisitPhoto{},
+		&models.ReliabilityEvent{},
+		&models.ReliabilityStats{},
+		&models.Payment{},
+		&models.PaymentEvent{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.JournalLine{},
+		&models.Earning{},
+		&models.Payout{},
+		&models.Invoice{},
+		&models.InvoiceCounter{},
 		&models.Activity{},
+		&models.ExchangeRate{},
+		&models.CalendarFeed{},
+		&models.CalendarImport{},
+		&models.IdempotencyKey{},
 	); err != nil {
 		log.Fatalf("db.Init: auto-migrate failed: %v", err)
 	}
 
-	// 4. Assign to global
+	// 4. Slots booked before group capacity existed hold exactly one booking
+	if err := conn.Model(&models.AvailabilitySlot{}).
+		Where("is_booked = ? AND booked_count = 0", true).
+		Update("booked_count", 1).Error; err != nil {
+		log.Fatalf("db.Init: backfill slot booked_count failed: %v", err)
+	}
+
+	// 5. Amounts used to be floats in major units; move them to minor units
+	if err := migrateMoney(conn); err != nil {
+		log.Fatalf("db.Init: migrate amounts to minor units failed: %v", err)
+	}
+
+	// 6. Assign to global
 	DB = conn
 
 }
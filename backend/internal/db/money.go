@@ -0,0 +1,76 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+// floatAmounts lists the float columns in major units replaced by bigint
+// columns in minor units, and where each row's currency is found.
+var floatAmounts = []struct {
+	model    any
+	table    string
+	from, to string
+	currency string
+}{
+	{&models.ServiceOffer{}, "service_offers", "price", "price_minor", "currency"},
+	{&models.Service{}, "services", "base_price", "base_price_minor", "currency"},
+	{&models.Booking{}, "bookings", "refund_amount", "refund_amount_minor", "currency"},
+	{&models.Booking{}, "bookings", "deposit_amount", "deposit_amount_minor", "currency"},
+}
+
+// migrateMoney copies the old float amounts into their minor unit columns,
+// scaled by each currency's exponent, and drops the float columns. Bookings
+// get their offer's currency first. It does nothing once the old columns
+// are gone.
+func migrateMoney(conn *gorm.DB) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE bookings SET currency = o.currency
+			FROM service_offers AS o
+			WHERE o.id = bookings.offer_id AND bookings.currency = ''`).Error; err != nil {
+			return err
+		}
+		for _, c := range floatAmounts {
+			if !tx.Migrator().HasColumn(c.model, c.from) {
+				continue
+			}
+			sql := fmt.Sprintf("UPDATE %s SET %s = ROUND(%s * %s) WHERE %s IS NOT NULL",
+				c.table, c.to, c.from, minorScale(c.currency), c.from)
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(c.model, c.from); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// minorScale returns a SQL expression for the number of minor units in one
+// major unit of the currency held in column.
+func minorScale(column string) string {
+	exps := money.Exponents()
+	codes := make([]string, 0, len(exps))
+	for code := range exps {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE UPPER(%s)", column)
+	for _, code := range codes {
+		scale := 1
+		for i := 0; i < exps[code]; i++ {
+			scale *= 10
+		}
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", code, scale)
+	}
+	b.WriteString(" ELSE 100 END")
+	return b.String()
+}
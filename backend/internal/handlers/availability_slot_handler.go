@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -20,6 +21,7 @@ func NewAvailabilitySlotHandler(s *service.AvailabilitySlotService) *Availabilit
 type createSlotReq struct {
 	StartTime string `json:"start_time" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
 	EndTime   string `json:"end_time"   binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	Capacity  int    `json:"capacity"   binding:"omitempty,min=1,max=100"`
 }
 
 func (h *AvailabilitySlotHandler) Create(c *gin.Context) {
@@ -37,9 +39,9 @@ func (h *AvailabilitySlotHandler) Create(c *gin.Context) {
 	start, _ := time.Parse(time.RFC3339, req.StartTime)
 	end, _ := time.Parse(time.RFC3339, req.EndTime)
 
-	slot, err := h.svc.CreateSlot(c.Request.Context(), offerID, start, end)
+	slot, err := h.svc.CreateSlot(c.Request.Context(), offerID, start, end, req.Capacity)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(slotErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, slot)
@@ -71,6 +73,7 @@ type updateSlotReq struct {
 	StartTime string `json:"start_time" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	EndTime   string `json:"end_time"   binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	IsBooked  *bool  `json:"is_booked"  binding:"omitempty"`
+	Capacity  int    `json:"capacity"   binding:"omitempty,min=1,max=100"`
 }
 
 func (h *AvailabilitySlotHandler) Update(c *gin.Context) {
@@ -90,9 +93,10 @@ func (h *AvailabilitySlotHandler) Update(c *gin.Context) {
 		parseOrDefault(req.StartTime, time.RFC3339),
 		parseOrDefault(req.EndTime, time.RFC3339),
 		boolOrDefault(req.IsBooked, false),
+		req.Capacity,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(slotErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, slot)
@@ -111,6 +115,23 @@ func (h *AvailabilitySlotHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func slotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrOfferNotFound),
+		errors.Is(err, service.ErrSlotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidSlotTimes):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrCapacityBelowBooked),
+		errors.Is(err, service.ErrSlotHasBookings),
+		errors.Is(err, service.ErrScheduleConflict),
+		errors.Is(err, service.ErrInTimeOff):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // helpers
 
 func parseOrDefault(val, layout string) time.Time {
@@ -0,0 +1,52 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateSlotKeepsBookings(t *testing.T) {
+	freelancerID := uuid.New()
+	router, db := setupTimeOffRouter(t, freelancerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	slot := seedSlot(t, db, offer.ID, start)
+	path := "/slots/" + slot.ID.String()
+	update := func(body map[string]any) int {
+		return sendAs(router, http.MethodPut, path, freelancerID, body).Code
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, update(map[string]any{
+		"end_time": start.Add(-time.Hour).Format(time.RFC3339)}))
+	assert.Equal(t, http.StatusNotFound, sendAs(router, http.MethodPut, "/slots/"+uuid.New().String(), freelancerID,
+		map[string]any{"capacity": 2}).Code)
+
+	// a free slot can be moved
+	start = start.Add(2 * time.Hour)
+	assert.Equal(t, http.StatusOK, update(map[string]any{
+		"start_time": start.Format(time.RFC3339), "end_time": start.Add(time.Hour).Format(time.RFC3339)}))
+
+	// once booked, it keeps its times, and its seat count survives other changes
+	assert.NoError(t, db.Model(slot).Updates(map[string]any{"booked_count": 1, "is_booked": true}).Error)
+	assert.Equal(t, http.StatusConflict, update(map[string]any{
+		"start_time": start.Add(time.Hour).Format(time.RFC3339), "end_time": start.Add(2 * time.Hour).Format(time.RFC3339)}))
+	w := sendAs(router, http.MethodPut, path, freelancerID, map[string]any{"capacity": 3})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.AvailabilitySlot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, 2, updated.RemainingSeats)
+	assert.False(t, updated.IsBooked)
+
+	var stored models.AvailabilitySlot
+	assert.NoError(t, db.First(&stored, "id = ?", slot.ID).Error)
+	assert.Equal(t, 1, stored.BookedCount)
+	assert.Equal(t, 3, stored.Capacity)
+	assert.True(t, start.Equal(stored.StartTime))
+}
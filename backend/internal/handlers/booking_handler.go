@@ -1,24 +1,55 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
 	"github.com/shardy678/pet-freelance/backend/internal/service"
 )
 
 type BookingHandler struct {
-	svc *service.BookingService
+	svc    *service.BookingService
+	series *service.BookingSeriesService
+	notes  *service.BookingNotesService
 }
 
-func NewBookingHandler(svc *service.BookingService) *BookingHandler {
-	return &BookingHandler{svc}
+func NewBookingHandler(
+	svc *service.BookingService,
+	series *service.BookingSeriesService,
+	notes *service.BookingNotesService,
+) *BookingHandler {
+	return &BookingHandler{svc, series, notes}
 }
 
 type createBookingReq struct {
 	OfferID string `json:"offer_id" binding:"required,uuid"`
 	SlotID  string `json:"slot_id"  binding:"required,uuid"`
+	extrasReq
+}
+
+// extrasReq picks add-ons, a credit pack to pay with and a coupon, and says
+// how many pets to book for when booking or quoting.
+type extrasReq struct {
+	AddOnIDs     []string `json:"add_on_ids"     binding:"omitempty,max=20,dive,uuid"`
+	CreditPackID string   `json:"credit_pack_id" binding:"omitempty,uuid"`
+	CouponCode   string   `json:"coupon_code"    binding:"max=40"`
+	Pets         int      `json:"pets"           binding:"min=0,max=20"`
+}
+
+func (r extrasReq) extras() service.Extras {
+	e := service.Extras{CouponCode: r.CouponCode, Pets: r.Pets}
+	for _, s := range r.AddOnIDs {
+		id, _ := uuid.Parse(s)
+		e.AddOnIDs = append(e.AddOnIDs, id)
+	}
+	if r.CreditPackID != "" {
+		id, _ := uuid.Parse(r.CreditPackID)
+		e.CreditPackID = &id
+	}
+	return e
 }
 
 func (h *BookingHandler) Create(c *gin.Context) {
@@ -52,19 +83,198 @@ func (h *BookingHandler) Create(c *gin.Context) {
 		return
 	}
 
-	booking, err := h.svc.BookSlot(c.Request.Context(), offerID, slotID, ownerID)
+	booking, err := h.svc.BookSlot(c.Request.Context(), offerID, slotID, ownerID, req.extras())
 	if err != nil {
-		if err == service.ErrSlotAlreadyBooked {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, booking)
 }
 
+// bookingErrorStatus maps BookingService errors onto HTTP status codes.
+func bookingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrOfferNotFound),
+		errors.Is(err, service.ErrSlotNotFound),
+		errors.Is(err, service.ErrBookingNotFound),
+		errors.Is(err, service.ErrCreditPackNotFound),
+		errors.Is(err, service.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrSlotAlreadyBooked),
+		errors.Is(err, service.ErrScheduleConflict),
+		errors.Is(err, service.ErrInTimeOff),
+		errors.Is(err, service.ErrBookingNotCancellable),
+		errors.Is(err, service.ErrBookingNotPending),
+		errors.Is(err, service.ErrDepositOutstanding),
+		errors.Is(err, service.ErrNoShowTooEarly),
+		errors.Is(err, service.ErrNoShowNotConfirmed),
+		errors.Is(err, service.ErrNoShowVisited),
+		errors.Is(err, service.ErrBookingNotEditable),
+		errors.Is(err, service.ErrBookingNotReschedulable),
+		errors.Is(err, service.ErrReschedulePending),
+		errors.Is(err, service.ErrNoRescheduleProposal):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrOwnOffer),
+		errors.Is(err, service.ErrNotBookingParty),
+		errors.Is(err, service.ErrNotOfferFreelancer),
+		errors.Is(err, service.ErrNotBookingOwner),
+		errors.Is(err, service.ErrPrivateNotesFreelancer):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrSlotOfferMismatch),
+		errors.Is(err, service.ErrStayOffer),
+		errors.Is(err, service.ErrOfferInactive),
+		errors.Is(err, service.ErrSlotInPast),
+		errors.Is(err, service.ErrRescheduleSameSlot),
+		errors.Is(err, service.ErrUnknownAddOn),
+		errors.Is(err, service.ErrSlotTooShort),
+		errors.Is(err, service.ErrCreditPackUnusable),
+		errors.Is(err, service.ErrCouponUnusable),
+		errors.Is(err, service.ErrCouponNotApplicable),
+		errors.Is(err, service.ErrCouponMinSpend):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type cancelBookingReq struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+// Cancel handles POST /bookings/:id/cancel
+func (h *BookingHandler) Cancel(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req cancelBookingReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	booking, err := h.svc.CancelBooking(c.Request.Context(), id, userID, req.Reason)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// NoShow handles POST /bookings/:id/no-show
+func (h *BookingHandler) NoShow(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	booking, err := h.svc.MarkNoShow(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+type rescheduleBookingReq struct {
+	SlotID string `json:"slot_id" binding:"required,uuid"`
+}
+
+// Reschedule handles POST /bookings/:id/reschedule
+func (h *BookingHandler) Reschedule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req rescheduleBookingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slotID, err := uuid.Parse(req.SlotID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slot_id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	booking, err := h.svc.Reschedule(c.Request.Context(), id, userID, slotID)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if booking.ProposedSlotID != nil {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, booking)
+}
+
+// ApproveReschedule handles POST /bookings/:id/reschedule/approve
+func (h *BookingHandler) ApproveReschedule(c *gin.Context) {
+	h.decideReschedule(c, true)
+}
+
+// RejectReschedule handles POST /bookings/:id/reschedule/reject
+func (h *BookingHandler) RejectReschedule(c *gin.Context) {
+	h.decideReschedule(c, false)
+}
+
+func (h *BookingHandler) decideReschedule(c *gin.Context, approve bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	booking, err := h.svc.DecideReschedule(c.Request.Context(), id, userID, approve)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// Reschedules handles GET /bookings/:id/reschedules
+func (h *BookingHandler) Reschedules(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.ListReschedules(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Get handles GET /bookings/:id. Access details and private notes are
+// included as far as the caller may see them.
 func (h *BookingHandler) Get(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -72,19 +282,119 @@ func (h *BookingHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
 		return
 	}
-	b, err := h.svc.GetBooking(c.Request.Context(), id)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	b, err := h.notes.Get(c.Request.Context(), id, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "booking not found"})
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, b)
 }
 
+type instructionsReq struct {
+	Feeding    string `json:"feeding"    binding:"max=2000"`
+	Medication string `json:"medication" binding:"max=2000"`
+	Parking    string `json:"parking"    binding:"max=2000"`
+	Other      string `json:"other"      binding:"max=2000"`
+}
+
+type accessDetailsReq struct {
+	DoorCode     string `json:"door_code"     binding:"max=100"`
+	AlarmCode    string `json:"alarm_code"    binding:"max=100"`
+	KeyLocation  string `json:"key_location"  binding:"max=500"`
+	WifiPassword string `json:"wifi_password" binding:"max=200"`
+	Other        string `json:"other"         binding:"max=2000"`
+}
+
+type updateBookingReq struct {
+	Instructions    *instructionsReq  `json:"instructions"`
+	AccessDetails   *accessDetailsReq `json:"access_details"`
+	FreelancerNotes *string           `json:"freelancer_notes" binding:"omitempty,max=5000"`
+}
+
+// Update handles PATCH /bookings/:id. Owners set instructions and access
+// details, freelancers their private notes; omitted fields are kept.
+func (h *BookingHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req updateBookingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	upd := service.NotesUpdate{FreelancerNotes: req.FreelancerNotes}
+	if i := req.Instructions; i != nil {
+		upd.Instructions = &models.Instructions{
+			Feeding:    i.Feeding,
+			Medication: i.Medication,
+			Parking:    i.Parking,
+			Other:      i.Other,
+		}
+	}
+	if a := req.AccessDetails; a != nil {
+		upd.AccessDetails = &models.AccessDetails{
+			DoorCode:     a.DoorCode,
+			AlarmCode:    a.AlarmCode,
+			KeyLocation:  a.KeyLocation,
+			WifiPassword: a.WifiPassword,
+			Other:        a.Other,
+		}
+	}
+	b, err := h.notes.Update(c.Request.Context(), id, userID, upd)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// Confirm handles POST /bookings/:id/confirm
+func (h *BookingHandler) Confirm(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	booking, err := h.svc.Confirm(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(bookingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// List handles GET /bookings; ?group=series nests recurring bookings under
+// their series.
 func (h *BookingHandler) List(c *gin.Context) {
 	// List bookings for the logged-in user
 	uid, _ := c.Get("uid")
 	ownerID, _ := uuid.Parse(uid.(string))
 
+	if c.Query("group") == "series" {
+		grouped, err := h.series.ListGrouped(c.Request.Context(), ownerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, grouped)
+		return
+	}
+
 	list, err := h.svc.ListByOwner(c.Request.Context(), ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
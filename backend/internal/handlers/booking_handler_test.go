@@ -0,0 +1,1012 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/handlers"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/payments"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/secret"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBookingRouter(t *testing.T, uid uuid.UUID) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.ServiceOffer{},
+		&models.AddOn{},
+		&models.PricingRule{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Package{},
+		&models.CreditPack{},
+		&models.FreelancerSettings{},
+		&models.TimeOff{},
+		&models.AvailabilitySlot{},
+		&models.Booking{},
+		&models.BookingReschedule{},
+		&models.BookingSeries{},
+		&models.StayNight{},
+		&models.WaitlistEntry{},
+		&models.VisitReport{},
+		&models.VisitPhoto{},
+		&models.ReliabilityEvent{},
+		&models.ReliabilityStats{},
+		&models.Payment{},
+		&models.PaymentEvent{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.JournalLine{},
+		&models.Earning{},
+		&models.Payout{},
+		&models.Invoice{},
+		&models.InvoiceCounter{},
+		&models.User{},
+		&models.Service{},
+		&models.Activity{},
+	)
+	assert.NoError(t, err)
+
+	bookingSvc := newBookingService(db)
+	seriesSvc := service.NewBookingSeriesService(bookingSvc, repository.NewBookingSeriesRepository(db))
+	notesSvc := service.NewBookingNotesService(bookingSvc, secret.NewBox("test key"))
+	h := handlers.NewBookingHandler(bookingSvc, seriesSvc, notesSvc)
+	stayH := handlers.NewStayHandler(service.NewStayService(bookingSvc))
+	visitH := handlers.NewVisitHandler(service.NewVisitService(bookingSvc, repository.NewVisitReportRepository(db), t.TempDir()))
+	addOnRepo := repository.NewAddOnRepository(db)
+	creditPackRepo := repository.NewCreditPackRepository(db)
+	fakePayments := payments.NewFakeProvider("test secret")
+	paymentRepo := repository.NewPaymentRepository(db)
+	ledgerSvc := service.NewLedgerService(repository.NewLedgerRepository(db), paymentRepo)
+	paymentH := handlers.NewPaymentHandler(
+		service.NewPaymentService(bookingSvc, paymentRepo, creditPackRepo, ledgerSvc, fakePayments, time.Hour), fakePayments)
+	ledgerH := handlers.NewLedgerHandler(ledgerSvc)
+	payoutSvc := service.NewPayoutService(bookingSvc, repository.NewPayoutRepository(db), paymentRepo,
+		repository.NewServiceRepository(db), repository.NewFreelancerSettingsRepository(db),
+		ledgerSvc, fakePayments, 1500, time.Hour)
+	payoutH := handlers.NewPayoutHandler(payoutSvc)
+	invoiceH := handlers.NewInvoiceHandler(service.NewInvoiceService(bookingSvc, repository.NewInvoiceRepository(db),
+		repository.NewUserRepository(db), repository.NewFreelancerSettingsRepository(db), payoutSvc))
+	waitlistH := handlers.NewWaitlistHandler(service.NewWaitlistService(bookingSvc, repository.NewWaitlistRepository(db), time.Hour))
+	pricingRuleRepo := repository.NewPricingRuleRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
+	quoteH := handlers.NewQuoteHandler(service.NewQuoteService(bookingSvc, addOnRepo, creditPackRepo, pricingRuleRepo, couponRepo, 0, 0))
+	couponH := handlers.NewCouponHandler(service.NewCouponService(couponRepo))
+	serviceH := handlers.NewServiceHandler(service.NewServiceService(repository.NewServiceRepository(db)))
+	settingsH := handlers.NewFreelancerSettingsHandler(repository.NewFreelancerSettingsRepository(db))
+	pricingRuleH := handlers.NewPricingRuleHandler(service.NewPricingRuleService(
+		repository.NewServiceOfferRepository(db), pricingRuleRepo))
+	extrasH := handlers.NewOfferExtrasHandler(service.NewOfferExtrasService(
+		repository.NewServiceOfferRepository(db), addOnRepo, creditPackRepo))
+
+	// Requests act as uid unless they name another user in X-User-ID.
+	r.Use(func(c *gin.Context) {
+		if as := c.GetHeader("X-User-ID"); as != "" {
+			c.Set("uid", as)
+		} else {
+			c.Set("uid", uid.String())
+		}
+		c.Next()
+	})
+	r.POST("/bookings", h.Create)
+	r.GET("/bookings/:id", h.Get)
+	r.PATCH("/bookings/:id", h.Update)
+	r.POST("/bookings/:id/confirm", h.Confirm)
+	r.POST("/bookings/:id/cancel", h.Cancel)
+	r.POST("/bookings/:id/no-show", h.NoShow)
+	r.POST("/bookings/:id/payment", paymentH.Start)
+	r.GET("/bookings/:id/payments", paymentH.List)
+	r.GET("/bookings/:id/invoice", invoiceH.Invoice)
+	r.GET("/bookings/:id/credit-notes", invoiceH.CreditNotes)
+	r.GET("/bookings/:id/credit-notes/:note_id", invoiceH.CreditNote)
+	r.POST("/bookings/:id/refund", invoiceH.Refund)
+	r.POST("/payments/webhook", paymentH.Webhook)
+	r.POST("/payments/fake/:intent_id/authorize", paymentH.FakeAuthorize)
+	r.GET("/ledger/balances", ledgerH.Balances)
+	r.POST("/offers/:offer_id/quote", quoteH.Quote)
+	r.POST("/offers/:offer_id/add-ons", extrasH.CreateAddOn)
+	r.GET("/offers/:offer_id/add-ons", extrasH.ListAddOns)
+	r.POST("/offers/:offer_id/packages", extrasH.CreatePackage)
+	r.POST("/offers/:offer_id/pricing-rules", pricingRuleH.Create)
+	r.DELETE("/offers/:offer_id/pricing-rules/:rule_id", pricingRuleH.Delete)
+	r.POST("/admin/coupons", couponH.Create)
+	r.GET("/admin/coupons/:id", couponH.Get)
+	r.PATCH("/admin/coupons/:id", couponH.Update)
+	r.PUT("/admin/services/:id/commission", serviceH.SetCommission)
+	r.PUT("/admin/freelancers/:id/commission", settingsH.SetCommission)
+	r.PUT("/freelancer/settings", settingsH.Update)
+	r.POST("/packages/:id/purchase", paymentH.BuyPack)
+	r.GET("/profile/credit-packs", extrasH.CreditPacks)
+	r.GET("/freelancer/earnings", payoutH.Earnings)
+	// Payouts run on a schedule; here they are run by hand as if the hold had passed
+	r.POST("/payouts/run", func(c *gin.Context) {
+		if err := payoutSvc.PayAvailable(c.Request.Context(), time.Now().Add(2*time.Hour)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	r.POST("/bookings/:id/reschedule", h.Reschedule)
+	r.POST("/bookings/:id/reschedule/approve", h.ApproveReschedule)
+	r.GET("/bookings/:id/reschedules", h.Reschedules)
+	r.POST("/bookings/:id/check-in", visitH.CheckIn)
+	r.POST("/bookings/:id/check-out", visitH.CheckOut)
+	r.GET("/bookings/:id/report", visitH.Get)
+	r.POST("/bookings/:id/report/photos", visitH.AddPhoto)
+	r.GET("/bookings/:id/report/photos/:photo_id", visitH.Photo)
+	r.POST("/bookings/series", h.CreateSeries)
+	r.POST("/bookings/stays", stayH.BookStay)
+	r.PUT("/offers/:offer_id/nights", stayH.SetNights)
+	r.POST("/slots/:slot_id/waitlist", waitlistH.Join)
+	r.GET("/slots/:slot_id/waitlist", waitlistH.Get)
+	r.POST("/slots/:slot_id/waitlist/claim", waitlistH.Claim)
+
+	return r, db
+}
+
+// newBookingService wires a BookingService to db the way setupBookingRouter
+// does, for tests that build the services around it themselves.
+func newBookingService(db *gorm.DB) *service.BookingService {
+	return service.NewBookingService(
+		repository.NewBookingRepository(db),
+		repository.NewAvailabilitySlotRepository(db),
+		repository.NewServiceOfferRepository(db),
+		repository.NewBookingRescheduleRepository(db),
+		repository.NewStayNightRepository(db),
+		repository.NewVisitReportRepository(db),
+		repository.NewPaymentRepository(db),
+		repository.NewFreelancerSettingsRepository(db),
+		repository.NewTimeOffRepository(db),
+		repository.NewReliabilityRepository(db),
+		service.NewActivityService(repository.NewActivityRepository(db)),
+		db,
+	)
+}
+
+func seedOffer(t *testing.T, db *gorm.DB, freelancerID uuid.UUID, active bool) *models.ServiceOffer {
+	offer := &models.ServiceOffer{
+		FreelancerID: freelancerID,
+		ServiceID:    uuid.New(),
+		Title:        "Dog walk",
+		Description:  "30 minute walk",
+		Price:        money.New(1500, "EUR"),
+		Currency:     "EUR",
+		PriceType:    "fixed",
+		IsActive:     true,
+	}
+	assert.NoError(t, db.Create(offer).Error)
+	if !active {
+		assert.NoError(t, db.Model(offer).Update("is_active", false).Error)
+	}
+	return offer
+}
+
+func seedSlot(t *testing.T, db *gorm.DB, offerID uuid.UUID, start time.Time) *models.AvailabilitySlot {
+	slot := &models.AvailabilitySlot{
+		OfferID:   offerID,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+	}
+	assert.NoError(t, db.Create(slot).Error)
+	return slot
+}
+
+func postBooking(router *gin.Engine, offerID, slotID uuid.UUID) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{
+		"offer_id": offerID.String(),
+		"slot_id":  slotID.String(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// sendAs sends body as JSON on behalf of the given user.
+func sendAs(router *gin.Engine, method, path string, as uuid.UUID, body any) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", as.String())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateBooking(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, ownerID, booking.OwnerID)
+	assert.Equal(t, slot.ID, booking.SlotID)
+
+	var reloaded models.AvailabilitySlot
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.True(t, reloaded.IsBooked)
+
+	w = postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateBookingValidation(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	otherOffer := seedOffer(t, db, uuid.New(), true)
+	inactiveOffer := seedOffer(t, db, uuid.New(), false)
+	ownOffer := seedOffer(t, db, ownerID, true)
+	future := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		offerID uuid.UUID
+		slotID  uuid.UUID
+		status  int
+	}{
+		{"unknown offer", uuid.New(), seedSlot(t, db, offer.ID, future).ID, http.StatusNotFound},
+		{"unknown slot", offer.ID, uuid.New(), http.StatusNotFound},
+		{"slot of another offer", offer.ID, seedSlot(t, db, otherOffer.ID, future).ID, http.StatusUnprocessableEntity},
+		{"inactive offer", inactiveOffer.ID, seedSlot(t, db, inactiveOffer.ID, future).ID, http.StatusUnprocessableEntity},
+		{"slot in the past", offer.ID, seedSlot(t, db, offer.ID, time.Now().Add(-time.Hour)).ID, http.StatusUnprocessableEntity},
+		{"own offer", ownOffer.ID, seedSlot(t, db, ownOffer.ID, future).ID, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postBooking(router, tt.offerID, tt.slotID)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestCancelBookingAppliesPolicy(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	assert.NoError(t, db.Model(offer).Update("cancellation_policy", models.CancellationModerate).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Hour))
+
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+
+	req := httptest.NewRequest(http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cancelled models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cancelled))
+	assert.Equal(t, models.BookingStatusCancelled, cancelled.Status)
+	assert.Equal(t, 50, cancelled.RefundPercent)
+	// nothing was paid, so nothing is refunded
+	assert.Equal(t, int64(0), cancelled.RefundAmount.Amount)
+
+	var reloaded models.AvailabilitySlot
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.False(t, reloaded.IsBooked)
+
+	var activities int64
+	db.Model(&models.Activity{}).Where("type = ?", "cancellation").Count(&activities)
+	assert.Equal(t, int64(2), activities)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestRescheduleNeedingApproval(t *testing.T) {
+	ownerID := uuid.New()
+	freelancerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("reschedule_approval", true).Error)
+	oldSlot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	newSlot := seedSlot(t, db, offer.ID, time.Now().Add(48*time.Hour))
+
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(postBooking(router, offer.ID, oldSlot.ID).Body.Bytes(), &booking))
+	base := "/bookings/" + booking.ID.String()
+
+	body, _ := json.Marshal(map[string]string{"slot_id": newSlot.ID.String()})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, base+"/reschedule", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// the owner cannot approve their own proposal
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, base+"/reschedule/approve", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, base+"/reschedule/approve", nil)
+	req.Header.Set("X-User-ID", freelancerID.String())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var moved models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &moved))
+	assert.Equal(t, newSlot.ID, moved.SlotID)
+	assert.Nil(t, moved.ProposedSlotID)
+
+	var slots []models.AvailabilitySlot
+	assert.NoError(t, db.Order("start_time").Find(&slots).Error)
+	assert.False(t, slots[0].IsBooked)
+	assert.True(t, slots[1].IsBooked)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+"/reschedules", nil))
+	var history []models.BookingReschedule
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history, 1)
+	assert.Equal(t, models.RescheduleApplied, history[0].Status)
+	assert.Equal(t, oldSlot.ID, history[0].FromSlotID)
+}
+
+func TestCreateSeries(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	// Mondays and Wednesdays at 08:00 UTC for two weeks, minus one Wednesday.
+	monday := time.Now().UTC().AddDate(0, 0, 7)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	monday = time.Date(monday.Year(), monday.Month(), monday.Day(), 8, 0, 0, 0, time.UTC)
+	for _, offset := range []int{0, 2, 7} {
+		seedSlot(t, db, offer.ID, monday.AddDate(0, 0, offset))
+	}
+
+	post := func(mode string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{
+			"offer_id":    offer.ID.String(),
+			"weekdays":    []int{1, 3},
+			"time_of_day": "08:00",
+			"start_date":  monday.Format("2006-01-02"),
+			"weeks":       2,
+			"mode":        mode,
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/series", bytes.NewBuffer(body)))
+		return w
+	}
+
+	w := post("all_or_nothing")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var count int64
+	db.Model(&models.Booking{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = post("best_effort")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result service.SeriesResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Bookings, 3)
+	assert.Len(t, result.Conflicts, 1)
+	for _, b := range result.Bookings {
+		assert.Equal(t, result.Series.ID, *b.SeriesID)
+	}
+}
+
+func TestBookStayChecksEveryNight(t *testing.T) {
+	ownerID := uuid.New()
+	freelancerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("price_type", models.PriceTypeNightly).Error)
+	day := func(n int) string { return time.Now().UTC().AddDate(0, 0, n).Format("2006-01-02") }
+
+	body, _ := json.Marshal(map[string]any{"from": day(10), "to": day(13), "capacity": 1})
+	req := httptest.NewRequest(http.MethodPut, "/offers/"+offer.ID.String()+"/nights", bytes.NewBuffer(body))
+	req.Header.Set("X-User-ID", freelancerID.String())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	book := func(as uuid.UUID, in, out int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"offer_id": offer.ID.String(), "check_in": day(in), "check_out": day(out)})
+		req := httptest.NewRequest(http.MethodPost, "/bookings/stays", bytes.NewBuffer(body))
+		req.Header.Set("X-User-ID", as.String())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = book(ownerID, 10, 12)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result service.StayResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Nights)
+	assert.Equal(t, int64(3000), result.Total.Amount)
+
+	// night 11 is full, and night 13 was never opened
+	assert.Equal(t, http.StatusConflict, book(uuid.New(), 11, 13).Code)
+	assert.Equal(t, http.StatusConflict, book(uuid.New(), 12, 14).Code)
+	assert.Equal(t, http.StatusCreated, book(uuid.New(), 12, 13).Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/"+result.Booking.ID.String()+"/cancel", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusCreated, book(uuid.New(), 10, 12).Code)
+}
+
+func TestBookStayRespectsTimeOff(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("price_type", models.PriceTypeNightly).Error)
+	date := func(n int) time.Time { return time.Now().UTC().AddDate(0, 0, n).Truncate(24 * time.Hour) }
+	day := func(n int) string { return date(n).Format("2006-01-02") }
+	w := sendAs(router, http.MethodPut, "/offers/"+offer.ID.String()+"/nights", freelancerID,
+		map[string]any{"from": day(10), "to": day(16), "capacity": 1})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, db.Create(&models.TimeOff{
+		FreelancerID: freelancerID, StartsAt: date(12), EndsAt: date(13)}).Error)
+
+	book := func(in, out int) int {
+		return sendAs(router, http.MethodPost, "/bookings/stays", ownerID,
+			map[string]any{"offer_id": offer.ID.String(), "check_in": day(in), "check_out": day(out)}).Code
+	}
+	assert.Equal(t, http.StatusConflict, book(11, 14))
+	assert.Equal(t, http.StatusCreated, book(13, 15))
+}
+
+func TestStayTimesFollowFreelancerTimezone(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+	assert.NoError(t, db.Create(&models.FreelancerSettings{UserID: freelancerID, Timezone: "Pacific/Auckland"}).Error)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Updates(map[string]any{
+		"price_type": models.PriceTypeNightly, "check_in_time": "09:00", "check_out_time": "10:30"}).Error)
+	in, out := time.Now().UTC().AddDate(0, 0, 10), time.Now().UTC().AddDate(0, 0, 12)
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+	w := sendAs(router, http.MethodPut, "/offers/"+offer.ID.String()+"/nights", freelancerID,
+		map[string]any{"from": day(in), "to": day(out), "capacity": 1})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	book := func() *httptest.ResponseRecorder {
+		return sendAs(router, http.MethodPost, "/bookings/stays", ownerID,
+			map[string]any{"offer_id": offer.ID.String(), "check_in": day(in), "check_out": day(out)})
+	}
+	w = book()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result service.StayResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(in.Year(), in.Month(), in.Day(), 9, 0, 0, 0, auckland).Equal(*result.Booking.CheckIn))
+	assert.True(t, time.Date(out.Year(), out.Month(), out.Day(), 10, 30, 0, 0, auckland).Equal(*result.Booking.CheckOut))
+
+	// cancelling gives back the nights booked, not the day before
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, "/bookings/"+result.Booking.ID.String()+"/cancel", ownerID, nil).Code)
+	assert.Equal(t, http.StatusCreated, book().Code)
+}
+
+func TestGroupSlotSharedUntilFull(t *testing.T) {
+	router, db := setupBookingRouter(t, uuid.New())
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	assert.NoError(t, db.Model(slot).Update("capacity", 2).Error)
+
+	book := func() int {
+		body, _ := json.Marshal(map[string]string{"offer_id": offer.ID.String(), "slot_id": slot.ID.String()})
+		req := httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body))
+		req.Header.Set("X-User-ID", uuid.New().String())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, book())
+	var reloaded models.AvailabilitySlot
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.False(t, reloaded.IsBooked)
+	assert.Equal(t, 1, reloaded.RemainingSeats)
+
+	assert.Equal(t, http.StatusCreated, book())
+	assert.Equal(t, http.StatusConflict, book())
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.True(t, reloaded.IsBooked)
+	assert.Equal(t, 0, reloaded.RemainingSeats)
+}
+
+func TestWaitlistOffersFreedSeat(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	base := "/slots/" + slot.ID.String() + "/waitlist"
+
+	as := func(method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User-ID", userID.String())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// a slot with free seats is booked directly
+	first, second := uuid.New(), uuid.New()
+	assert.Equal(t, http.StatusConflict, as(http.MethodPost, base, first).Code)
+
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(postBooking(router, offer.ID, slot.ID).Body.Bytes(), &booking))
+	assert.Equal(t, http.StatusCreated, as(http.MethodPost, base, first).Code)
+	assert.Equal(t, http.StatusConflict, as(http.MethodPost, base, first).Code)
+	w := as(http.MethodPost, base, second)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var entry models.WaitlistEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, 2, entry.Position)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the freed seat is held for the first in line only
+	assert.Equal(t, http.StatusConflict, postBooking(router, offer.ID, slot.ID).Code)
+	assert.Equal(t, http.StatusConflict, as(http.MethodPost, base+"/claim", second).Code)
+	assert.NoError(t, json.Unmarshal(as(http.MethodGet, base, second).Body.Bytes(), &entry))
+	assert.Equal(t, 1, entry.Position)
+	assert.NoError(t, json.Unmarshal(as(http.MethodGet, base, first).Body.Bytes(), &entry))
+	assert.Equal(t, models.WaitlistOffered, entry.Status)
+
+	assert.Equal(t, http.StatusCreated, as(http.MethodPost, base+"/claim", first).Code)
+	var reloaded models.AvailabilitySlot
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.True(t, reloaded.IsBooked)
+	assert.Equal(t, 0, reloaded.HeldCount)
+
+	var offered int64
+	db.Model(&models.Activity{}).Where("type = ? AND user_id = ?", "waitlist", first).Count(&offered)
+	assert.Equal(t, int64(2), offered)
+}
+
+func TestWaitlistClaimRespectsTimeOff(t *testing.T) {
+	ownerID, freelancerID, waiting := uuid.New(), uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	base := "/slots/" + slot.ID.String() + "/waitlist"
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(postBooking(router, offer.ID, slot.ID).Body.Bytes(), &booking))
+	assert.Equal(t, http.StatusCreated, sendAs(router, http.MethodPost, base, waiting, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", ownerID, nil).Code)
+
+	// the freelancer took the day off after the seat was offered
+	assert.NoError(t, db.Create(&models.TimeOff{
+		FreelancerID: freelancerID, StartsAt: slot.StartTime.Add(-time.Hour), EndsAt: slot.EndTime.Add(time.Hour)}).Error)
+	assert.Equal(t, http.StatusConflict, sendAs(router, http.MethodPost, base+"/claim", waiting, nil).Code)
+
+	var reloaded models.AvailabilitySlot
+	assert.NoError(t, db.First(&reloaded, "id = ?", slot.ID).Error)
+	assert.Equal(t, 0, reloaded.BookedCount)
+}
+
+func TestBookingRespectsBuffersAcrossOffers(t *testing.T) {
+	router, db := setupBookingRouter(t, uuid.New())
+
+	freelancerID := uuid.New()
+	walk := seedOffer(t, db, freelancerID, true)
+	groom := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(walk).Update("buffer_after_min", 30).Error)
+	assert.NoError(t, db.Create(&models.FreelancerSettings{UserID: freelancerID, TravelTimeMin: 15}).Error)
+
+	day := time.Now().UTC().AddDate(0, 0, 2).Truncate(24 * time.Hour)
+	walkSlot := seedSlot(t, db, walk.ID, day.Add(10*time.Hour))
+	tooSoon := seedSlot(t, db, groom.ID, day.Add(11*time.Hour+30*time.Minute))
+	later := seedSlot(t, db, groom.ID, day.Add(12*time.Hour))
+
+	assert.Equal(t, http.StatusCreated, postBooking(router, walk.ID, walkSlot.ID).Code)
+	// the walk ends at 11:00, plus 30 minutes buffer and 15 minutes travel
+	assert.Equal(t, http.StatusConflict, postBooking(router, groom.ID, tooSoon.ID).Code)
+	assert.Equal(t, http.StatusCreated, postBooking(router, groom.ID, later.ID).Code)
+}
+
+func TestAccessDetailsRevealedAfterConfirmation(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	send := func(method, path string, as uuid.UUID, body any) *httptest.ResponseRecorder {
+		return sendAs(router, method, path, as, body)
+	}
+	details := func(w *httptest.ResponseRecorder) service.BookingDetails {
+		var d service.BookingDetails
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+		return d
+	}
+
+	w = send(http.MethodPatch, path, ownerID, map[string]any{
+		"instructions":   map[string]string{"feeding": "Half a cup at 6pm", "parking": "Visitor spot 4"},
+		"access_details": map[string]string{"door_code": "4711", "key_location": "Under the mat"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4711", details(w).AccessDetails.DoorCode)
+
+	var stored models.Booking
+	assert.NoError(t, db.First(&stored, "id = ?", booking.ID).Error)
+	assert.NotEmpty(t, stored.SealedAccess)
+	assert.False(t, bytes.Contains(stored.SealedAccess, []byte("4711")))
+
+	// the freelancer reads the instructions but not the door code yet
+	w = send(http.MethodGet, path, freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	d := details(w)
+	assert.Equal(t, "Half a cup at 6pm", d.Instructions.Feeding)
+	assert.Nil(t, d.AccessDetails)
+
+	// anyone else sees nothing of the booking
+	w = send(http.MethodGet, path, uuid.New(), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "Half a cup")
+
+	w = send(http.MethodPatch, path, freelancerID, map[string]any{"instructions": map[string]string{"other": "x"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send(http.MethodPatch, path, ownerID, map[string]any{"freelancer_notes": "x"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send(http.MethodPatch, path, freelancerID, map[string]any{"freelancer_notes": "Dog pulls on the lead"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send(http.MethodPost, path+"/confirm", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(http.MethodGet, path, freelancerID, nil)
+	d = details(w)
+	assert.Equal(t, "4711", d.AccessDetails.DoorCode)
+	assert.Equal(t, "Dog pulls on the lead", *d.FreelancerNotes)
+
+	// the owner never sees the freelancer's notes
+	w = send(http.MethodGet, path, ownerID, nil)
+	assert.Nil(t, details(w).FreelancerNotes)
+
+	// a day after completion the door code is hidden again
+	completed := time.Now().Add(-25 * time.Hour)
+	assert.NoError(t, db.Model(&models.Booking{}).Where("id = ?", booking.ID).
+		Updates(map[string]any{"status": models.BookingStatusCompleted, "completed_at": completed}).Error)
+	w = send(http.MethodGet, path, freelancerID, nil)
+	assert.Nil(t, details(w).AccessDetails)
+}
+
+func TestNoShowsRequireDeposit(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Updates(map[string]any{"deposit_percent": 20, "deposit_after_no_shows": 1}).Error)
+
+	// the first booking needs no deposit; the owner then fails to show up
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(time.Hour))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Zero(t, booking.DepositAmount.Amount)
+	path := "/bookings/" + booking.ID.String()
+
+	assert.NoError(t, db.Model(slot).Update("start_time", time.Now().Add(-10*time.Minute)).Error)
+	// only a booking the freelancer confirmed can be missed
+	w = sendAs(router, http.MethodPost, path+"/no-show", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, db.Model(slot).Update("start_time", time.Now().Add(time.Hour)).Error)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	w = sendAs(router, http.MethodPost, path+"/no-show", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, db.Model(slot).Update("start_time", time.Now().Add(-10*time.Minute)).Error)
+	w = sendAs(router, http.MethodPost, path+"/no-show", ownerID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendAs(router, http.MethodPost, path+"/no-show", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// a late cancellation is counted as well
+	late := seedSlot(t, db, offer.ID, time.Now().Add(3*time.Hour))
+	w = postBooking(router, offer.ID, late.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, int64(300), booking.DepositAmount.Amount)
+	w = sendAs(router, http.MethodPost, "/bookings/"+booking.ID.String()+"/confirm", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	// the deposit is paid; cancelling an unpaid hold would not count
+	w = sendAs(router, http.MethodPost, "/bookings/"+booking.ID.String()+"/payment", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	w = sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = sendAs(router, http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// once the deposit is paid, the freelancer can confirm
+	later := seedSlot(t, db, offer.ID, time.Now().Add(5*time.Hour))
+	w = postBooking(router, offer.ID, later.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, models.BookingStatusAwaitingPayment, booking.Status)
+	path = "/bookings/" + booking.ID.String()
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+
+	var stats models.ReliabilityStats
+	assert.NoError(t, db.First(&stats, "user_id = ?", ownerID).Error)
+	assert.Equal(t, 1, stats.NoShows)
+	assert.Equal(t, 1, stats.LateCancellations)
+	assert.Equal(t, 0, *stats.Score)
+
+	var events int64
+	db.Model(&models.ReliabilityEvent{}).Where("user_id = ?", ownerID).Count(&events)
+	assert.Equal(t, int64(2), events)
+}
+
+func TestPrepaidBookingAwaitsPayment(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("prepaid", true).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(72*time.Hour))
+
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, models.BookingStatusAwaitingPayment, booking.Status)
+	assert.Equal(t, int64(1500), booking.DepositAmount.Amount)
+	path := "/bookings/" + booking.ID.String()
+
+	// the slot is held while the owner pays
+	var held models.AvailabilitySlot
+	assert.NoError(t, db.First(&held, "id = ?", slot.ID).Error)
+	assert.True(t, held.IsBooked)
+	w = sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = sendAs(router, http.MethodPost, path+"/payment", freelancerID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, int64(1500), payment.Amount.Amount)
+	assert.Contains(t, w.Body.String(), `"amount":"15.00","refunded":"0.00"`)
+	assert.Equal(t, models.PaymentRequiresPayment, payment.Status)
+
+	// asking again returns the same open payment
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	var again models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Equal(t, payment.ID, again.ID)
+
+	forged := `{"id":"evt_1","type":"intent.authorized","intentId":"` + payment.IntentID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewBufferString(forged))
+	req.Header.Set(payments.SignatureHeader, "00")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var paid models.Booking
+	assert.NoError(t, db.First(&paid, "id = ?", booking.ID).Error)
+	assert.Equal(t, models.BookingStatusPending, paid.Status)
+	assert.NotNil(t, paid.DepositPaidAt)
+	w = sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// cancelling refunds the captured payment under the policy
+	w = sendAs(router, http.MethodPost, path+"/cancel", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cancelled models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cancelled))
+	assert.Equal(t, int64(1500), cancelled.RefundAmount.Amount)
+	w = sendAs(router, http.MethodGet, path+"/payments", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+	assert.Equal(t, models.PaymentRefunded, list[0].Status)
+	assert.Equal(t, int64(1500), list[0].Refunded.Amount)
+}
+
+func TestWebhookCapturesOnlyWhatWasBooked(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("prepaid", true).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(72*time.Hour))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+
+	fake := payments.NewFakeProvider("test secret")
+	paymentRepo := repository.NewPaymentRepository(db)
+	ledgerSvc := service.NewLedgerService(repository.NewLedgerRepository(db), paymentRepo)
+	svc := service.NewPaymentService(newBookingService(db), paymentRepo,
+		repository.NewCreditPackRepository(db), ledgerSvc, fake, time.Hour)
+	ctx := context.Background()
+	payment, err := svc.Start(ctx, booking.ID, ownerID)
+	assert.NoError(t, err)
+	payload, header, err := fake.Authorize(payment.IntentID)
+	assert.NoError(t, err)
+
+	// the ledger cannot be written, so nothing of the webhook is kept and
+	// the provider is not asked to capture
+	assert.NoError(t, db.Migrator().DropTable(&models.JournalLine{}))
+	assert.Error(t, svc.HandleWebhook(ctx, payload, header))
+	var stored models.Payment
+	assert.NoError(t, db.First(&stored, "id = ?", payment.ID).Error)
+	assert.Equal(t, models.PaymentRequiresPayment, stored.Status)
+	var events int64
+	assert.NoError(t, db.Model(&models.PaymentEvent{}).Count(&events).Error)
+	assert.Zero(t, events)
+	intent, err := fake.Intent(payment.IntentID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentAuthorized, intent.Status)
+
+	// the redelivered webhook goes through once the ledger is back
+	assert.NoError(t, db.AutoMigrate(&models.JournalLine{}))
+	assert.NoError(t, svc.HandleWebhook(ctx, payload, header))
+	assert.NoError(t, db.First(&stored, "id = ?", payment.ID).Error)
+	assert.Equal(t, models.PaymentCaptured, stored.Status)
+	assert.Empty(t, stored.ProviderAction)
+	var paid models.Booking
+	assert.NoError(t, db.First(&paid, "id = ?", booking.ID).Error)
+	assert.Equal(t, models.BookingStatusPending, paid.Status)
+	intent, err = fake.Intent(payment.IntentID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentCaptured, intent.Status)
+}
+
+func TestFailedCancellationRefundIsNotEarned(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Updates(map[string]any{
+		"prepaid": true, "cancellation_policy": models.CancellationModerate}).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Hour))
+
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil).Code)
+
+	// the provider no longer knows the intent, so the refund fails
+	assert.NoError(t, db.Model(&models.Payment{}).Where("id = ?", payment.ID).Update("intent_id", "pi_gone").Error)
+	w = sendAs(router, http.MethodPost, path+"/cancel", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cancelled models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cancelled))
+	assert.Equal(t, int64(750), cancelled.RefundAmount.Amount)
+
+	var stored models.Payment
+	assert.NoError(t, db.First(&stored, "id = ?", payment.ID).Error)
+	assert.Equal(t, models.PaymentCaptured, stored.Status)
+	assert.Equal(t, int64(0), stored.Refunded.Amount)
+
+	// the half still owed to the owner is not earned
+	var earning models.Earning
+	assert.NoError(t, db.First(&earning, "booking_id = ?", booking.ID).Error)
+	assert.Equal(t, int64(750), earning.Gross.Amount)
+}
+
+func TestLedgerBooksChargesAndRefunds(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Updates(map[string]any{
+		"prepaid": true, "cancellation_policy": models.CancellationModerate}).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Hour))
+
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	w = sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// half is refunded, the rest is earned by the freelancer
+	w = sendAs(router, http.MethodPost, path+"/cancel", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendAs(router, http.MethodGet, "/ledger/balances", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var balances []service.AccountBalance
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balances))
+	assert.Len(t, balances, 1)
+	assert.Equal(t, models.LedgerFreelancerPayable, balances[0].Kind)
+	assert.Equal(t, "EUR", balances[0].Currency)
+	assert.Equal(t, int64(750-113), balances[0].Balance.Amount)
+	w = sendAs(router, http.MethodGet, "/ledger/balances", ownerID, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balances))
+	assert.Equal(t, int64(0), balances[0].Balance.Amount)
+
+	var entries []models.JournalEntry
+	assert.NoError(t, db.Preload("Lines").Where("booking_id = ?", booking.ID).Order("created_at").Find(&entries).Error)
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []string{models.JournalCharge, models.JournalRefund, models.JournalEarning, models.JournalFee}, kinds)
+
+	// posted entries cannot be changed
+	assert.ErrorIs(t, db.Model(&entries[0]).Update("memo", "edited").Error, models.ErrJournalImmutable)
+	assert.ErrorIs(t, db.Delete(&entries[0].Lines[0]).Error, models.ErrJournalImmutable)
+
+	ledger := service.NewLedgerService(repository.NewLedgerRepository(db), repository.NewPaymentRepository(db))
+	problems, err := ledger.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	// a one-sided entry and cash without a payment behind it are reported
+	var cash models.LedgerAccount
+	assert.NoError(t, db.First(&cash, "kind = ?", models.LedgerCash).Error)
+	stray := uuid.New()
+	assert.NoError(t, db.Create(&models.JournalEntry{
+		Kind: models.JournalCharge, BookingID: &stray, Currency: "EUR",
+		Lines: []models.JournalLine{{AccountID: cash.ID, Amount: money.New(100, "EUR")}},
+	}).Error)
+	problems, err = ledger.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, problems, 2)
+}
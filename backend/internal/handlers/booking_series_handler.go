@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type createSeriesReq struct {
+	OfferID   string `json:"offer_id" binding:"required,uuid"`
+	Weekdays  []int  `json:"weekdays" binding:"required,min=1,dive,min=0,max=6"`
+	TimeOfDay string `json:"time_of_day" binding:"required,datetime=15:04"`
+	Timezone  string `json:"timezone"`
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	Weeks     int    `json:"weeks" binding:"required,min=1,max=52"`
+	Mode      string `json:"mode" binding:"omitempty,oneof=all_or_nothing best_effort"`
+}
+
+// CreateSeries handles POST /bookings/series
+func (h *BookingHandler) CreateSeries(c *gin.Context) {
+	var req createSeriesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ownerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	offerID, _ := uuid.Parse(req.OfferID)
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+	weekdays := make([]time.Weekday, len(req.Weekdays))
+	for i, d := range req.Weekdays {
+		weekdays[i] = time.Weekday(d)
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = models.SeriesModeAllOrNothing
+	}
+
+	result, err := h.series.BookSeries(c.Request.Context(), ownerID, service.SeriesRequest{
+		OfferID:   offerID,
+		Weekdays:  weekdays,
+		TimeOfDay: req.TimeOfDay,
+		Timezone:  req.Timezone,
+		StartDate: startDate,
+		Weeks:     req.Weeks,
+		Mode:      mode,
+	})
+	if err != nil {
+		var conflictErr *service.SeriesConflictError
+		if errors.As(err, &conflictErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": conflictErr.Conflicts})
+			return
+		}
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetSeries handles GET /bookings/series/:id
+func (h *BookingHandler) GetSeries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid series id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	result, err := h.series.GetSeries(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type cancelSeriesReq struct {
+	From   string `json:"from" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+// CancelSeries handles POST /bookings/series/:id/cancel. Without "from" every
+// upcoming occurrence is cancelled.
+func (h *BookingHandler) CancelSeries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid series id"})
+		return
+	}
+	var req cancelSeriesReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	from := time.Now()
+	if req.From != "" {
+		from, _ = time.Parse(time.RFC3339, req.From)
+	}
+
+	result, err := h.series.CancelSeries(c.Request.Context(), id, userID, from, req.Reason)
+	if err != nil {
+		c.JSON(seriesErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func seriesErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrSeriesNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidSeries):
+		return http.StatusBadRequest
+	default:
+		return bookingErrorStatus(err)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+const calendarContentType = "text/calendar; charset=utf-8"
+
+type CalendarHandler struct {
+	svc *service.CalendarService
+}
+
+func NewCalendarHandler(s *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{svc: s}
+}
+
+// Feed handles GET /profile/calendar and returns the caller's feed URLs.
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	h.feed(c, false)
+}
+
+// Rotate handles POST /profile/calendar/rotate, revoking the old feed URLs.
+func (h *CalendarHandler) Rotate(c *gin.Context) {
+	h.feed(c, true)
+}
+
+func (h *CalendarHandler) feed(c *gin.Context, rotate bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	feed, err := h.svc.Feed(c.Request.Context(), userID, rotate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":           feed.Token,
+		"bookingsUrl":     "/api/calendar/" + feed.Token + "/bookings.ics",
+		"availabilityUrl": "/api/calendar/" + feed.Token + "/offers/{offer_id}/availability.ics",
+	})
+}
+
+// Bookings handles GET /calendar/:token/bookings.ics?tz=Europe/Berlin
+func (h *CalendarHandler) Bookings(c *gin.Context) {
+	body, err := h.svc.BookingsFeed(c.Request.Context(), c.Param("token"), c.Query("tz"))
+	if err != nil {
+		c.JSON(calendarErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, calendarContentType, body)
+}
+
+// Availability handles GET /calendar/:token/offers/:offer_id/availability.ics
+func (h *CalendarHandler) Availability(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	body, err := h.svc.AvailabilityFeed(c.Request.Context(), c.Param("token"), offerID, c.Query("tz"))
+	if err != nil {
+		c.JSON(calendarErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, calendarContentType, body)
+}
+
+func calendarErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrFeedNotFound),
+		errors.Is(err, service.ErrOfferNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrUnknownTimezone):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
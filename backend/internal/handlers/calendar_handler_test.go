@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/handlers"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingsFeedTracksCancellation(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+	assert.NoError(t, db.AutoMigrate(&models.CalendarFeed{}))
+
+	slotRepo := repository.NewAvailabilitySlotRepository(db)
+	offerRepo := repository.NewServiceOfferRepository(db)
+	settingsRepo := repository.NewFreelancerSettingsRepository(db)
+	calendarH := handlers.NewCalendarHandler(service.NewCalendarService(
+		repository.NewCalendarFeedRepository(db),
+		repository.NewBookingRepository(db),
+		repository.NewBookingRescheduleRepository(db),
+		offerRepo,
+		settingsRepo,
+		slotRepo,
+		service.NewAvailabilitySlotService(slotRepo, offerRepo, settingsRepo, repository.NewTimeOffRepository(db), db),
+	))
+	router.GET("/profile/calendar", calendarH.Feed)
+	router.GET("/calendar/:token/bookings.ics", calendarH.Bookings)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(48*time.Hour))
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(postBooking(router, offer.ID, slot.ID).Body.Bytes(), &booking))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile/calendar", nil))
+	var feed struct {
+		BookingsURL string `json:"bookingsUrl"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &feed))
+	url := strings.TrimPrefix(feed.BookingsURL, "/api")
+
+	fetch := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?tz=America/New_York", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+		return w.Body.String()
+	}
+
+	uid := "UID:booking-" + booking.ID.String() + "@pet-freelance\r\n"
+	ics := fetch()
+	assert.Contains(t, ics, uid)
+	assert.Contains(t, ics, "STATUS:TENTATIVE\r\nTRANSP:OPAQUE")
+	assert.Contains(t, ics, "TZID:America/New_York\r\n")
+	assert.Contains(t, ics, "DTSTART;TZID=America/New_York:"+slot.StartTime.In(mustLoad(t, "America/New_York")).Format("20060102T150405"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	ics = fetch()
+	assert.Contains(t, ics, uid)
+	assert.Contains(t, ics, "SEQUENCE:1\r\n")
+	assert.Contains(t, ics, "STATUS:CANCELLED\r\n")
+
+	// a no-show is no appointment either
+	var missed models.Booking
+	later := seedSlot(t, db, offer.ID, time.Now().Add(72*time.Hour))
+	assert.NoError(t, json.Unmarshal(postBooking(router, offer.ID, later.ID).Body.Bytes(), &missed))
+	assert.NoError(t, db.Model(&missed).Update("status", models.BookingStatusNoShow).Error)
+	missedUID := "UID:booking-" + missed.ID.String() + "@pet-freelance\r\n"
+	ics = fetch()
+	event := ics[strings.Index(ics, missedUID):]
+	event = event[:strings.Index(event, "END:VEVENT")]
+	assert.Contains(t, event, "STATUS:CANCELLED\r\n")
+
+	// a week after they last changed, cancelled bookings leave the feed
+	assert.NoError(t, db.Model(&models.Booking{}).Where("id IN ?", []uuid.UUID{booking.ID, missed.ID}).
+		UpdateColumn("updated_at", time.Now().Add(-8*24*time.Hour)).Error)
+	ics = fetch()
+	assert.NotContains(t, ics, uid)
+	assert.NotContains(t, ics, missedUID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calendar/not-a-token/bookings.ics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	assert.NoError(t, err)
+	return loc
+}
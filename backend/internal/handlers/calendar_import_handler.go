@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type CalendarImportHandler struct {
+	svc *service.CalendarImportService
+}
+
+func NewCalendarImportHandler(s *service.CalendarImportService) *CalendarImportHandler {
+	return &CalendarImportHandler{svc: s}
+}
+
+type addCalendarImportReq struct {
+	URL string `json:"url" binding:"required,max=2048"`
+}
+
+// Create handles POST /freelancer/calendar-imports. It takes either JSON
+// {"url": ...} or a multipart upload in the "file" field, and answers 202
+// because the calendar is read in the background.
+func (h *CalendarImportHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var (
+		imp *models.CalendarImport
+		err error
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, ferr := c.FormFile("file")
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		f, ferr := file.Open()
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ferr.Error()})
+			return
+		}
+		defer f.Close()
+		content, ferr := io.ReadAll(io.LimitReader(f, 5<<20+1))
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ferr.Error()})
+			return
+		}
+		imp, err = h.svc.AddUpload(c.Request.Context(), userID, content)
+	} else {
+		var req addCalendarImportReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		imp, err = h.svc.AddURL(c.Request.Context(), userID, req.URL)
+	}
+	if err != nil {
+		c.JSON(calendarImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, imp)
+}
+
+// List handles GET /freelancer/calendar-imports
+func (h *CalendarImportHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Get handles GET /freelancer/calendar-imports/:id, including parse errors
+// from the last sync.
+func (h *CalendarImportHandler) Get(c *gin.Context) {
+	id, userID, ok := calendarImportParams(c)
+	if !ok {
+		return
+	}
+	imp, err := h.svc.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(calendarImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, imp)
+}
+
+// Sync handles POST /freelancer/calendar-imports/:id/sync
+func (h *CalendarImportHandler) Sync(c *gin.Context) {
+	id, userID, ok := calendarImportParams(c)
+	if !ok {
+		return
+	}
+	imp, err := h.svc.Resync(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(calendarImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, imp)
+}
+
+// Delete handles DELETE /freelancer/calendar-imports/:id
+func (h *CalendarImportHandler) Delete(c *gin.Context) {
+	id, userID, ok := calendarImportParams(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id, userID); err != nil {
+		c.JSON(calendarImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func calendarImportParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, ok := currentUserID(c)
+	return id, userID, ok
+}
+
+func calendarImportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCalendarURL),
+		errors.Is(err, service.ErrEmptyCalendar):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCalendarImportMissing):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
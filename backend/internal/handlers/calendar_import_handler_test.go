@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/handlers"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCalendarImportBlocksBusySlots(t *testing.T) {
+	calendars := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer calendars.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(
+		&models.ServiceOffer{},
+		&models.FreelancerSettings{},
+		&models.AvailabilitySlot{},
+		&models.CalendarImport{},
+	))
+	freelancerID := uuid.New()
+	svc := service.NewCalendarImportService(
+		repository.NewCalendarImportRepository(db),
+		repository.NewAvailabilitySlotRepository(db),
+		repository.NewFreelancerSettingsRepository(db),
+		db,
+		time.Hour,
+	)
+	// the test server listens on loopback, which imports may not fetch from
+	svc.UseClient(calendars.Client())
+	h := handlers.NewCalendarImportHandler(svc)
+	r.Use(func(c *gin.Context) {
+		c.Set("uid", freelancerID.String())
+		c.Next()
+	})
+	r.POST("/freelancer/calendar-imports", h.Create)
+	r.GET("/freelancer/calendar-imports/:id", h.Get)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	monday := time.Now().UTC().Truncate(24 * time.Hour)
+	for monday.Weekday() != time.Monday || !monday.After(time.Now()) {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	busy := seedSlot(t, db, offer.ID, monday.Add(10*time.Hour))
+	free := seedSlot(t, db, offer.ID, monday.AddDate(0, 0, 1).Add(10*time.Hour))
+
+	body, _ := json.Marshal(map[string]string{"url": calendars.URL + "/busy.ics"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/freelancer/calendar-imports", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var imp models.CalendarImport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &imp))
+	assert.Equal(t, models.CalendarImportPending, imp.Status)
+
+	// stands in for the background worker
+	assert.NoError(t, svc.SyncDue(context.Background(), time.Now()))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/freelancer/calendar-imports/"+imp.ID.String(), nil))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &imp))
+	assert.Equal(t, models.CalendarImportSynced, imp.Status)
+	assert.Equal(t, 1, imp.EventCount)
+	assert.Equal(t, 1, imp.BlockedSlots)
+	assert.Len(t, imp.ParseErrors, 1)
+
+	var blocked, open models.AvailabilitySlot
+	assert.NoError(t, db.First(&blocked, "id = ?", busy.ID).Error)
+	assert.Equal(t, &imp.ID, blocked.BlockedBy)
+	assert.Equal(t, 0, blocked.RemainingSeats)
+	assert.NoError(t, db.First(&open, "id = ?", free.ID).Error)
+	assert.Nil(t, open.BlockedBy)
+
+	// a calendar that cannot be fetched reports the error and keeps its blocks
+	assert.NoError(t, db.Model(&imp).Updates(map[string]any{
+		"source_url":   calendars.URL + "/missing.ics",
+		"next_sync_at": time.Now().Add(-time.Minute),
+	}).Error)
+	assert.NoError(t, svc.SyncDue(context.Background(), time.Now()))
+	assert.NoError(t, db.First(&imp, "id = ?", imp.ID).Error)
+	assert.Equal(t, models.CalendarImportFailed, imp.Status)
+	assert.Contains(t, imp.LastError, "404")
+	var stillBlocked models.AvailabilitySlot
+	assert.NoError(t, db.First(&stillBlocked, "id = ?", busy.ID).Error)
+	assert.NotNil(t, stillBlocked.BlockedBy)
+}
+
+func TestCalendarImportRefusesPrivateHosts(t *testing.T) {
+	calendars := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer calendars.Close()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(
+		&models.ServiceOffer{},
+		&models.FreelancerSettings{},
+		&models.AvailabilitySlot{},
+		&models.CalendarImport{},
+	))
+	svc := service.NewCalendarImportService(
+		repository.NewCalendarImportRepository(db),
+		repository.NewAvailabilitySlotRepository(db),
+		repository.NewFreelancerSettingsRepository(db),
+		db,
+		time.Hour,
+	)
+
+	ctx := context.Background()
+	for _, url := range []string{
+		calendars.URL + "/busy.ics",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/busy.ics",
+	} {
+		imp, err := svc.AddURL(ctx, uuid.New(), url)
+		assert.NoError(t, err)
+		assert.NoError(t, svc.Sync(ctx, imp.ID))
+		assert.NoError(t, db.First(imp, "id = ?", imp.ID).Error)
+		assert.Equal(t, models.CalendarImportFailed, imp.Status, url)
+		assert.Contains(t, imp.LastError, service.ErrPrivateCalendarHost.Error(), url)
+	}
+}
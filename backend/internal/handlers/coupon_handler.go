@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type CouponHandler struct {
+	svc *service.CouponService
+}
+
+func NewCouponHandler(s *service.CouponService) *CouponHandler {
+	return &CouponHandler{svc: s}
+}
+
+type createCouponReq struct {
+	Code             string        `json:"code"                binding:"required,max=40"`
+	Description      string        `json:"description"         binding:"max=1000"`
+	Kind             string        `json:"kind"                binding:"required,oneof=percent fixed"`
+	Percent          int           `json:"percent"             binding:"min=0,max=100"`
+	Amount           money.Decimal `json:"amount"`
+	Currency         string        `json:"currency"            binding:"omitempty,len=3"`
+	MinSpend         money.Decimal `json:"min_spend"`
+	ValidFrom        string        `json:"valid_from"          binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ValidUntil       string        `json:"valid_until"         binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxUses          int           `json:"max_uses"            binding:"min=0"`
+	MaxUsesPerUser   int           `json:"max_uses_per_user"   binding:"min=0"`
+	ServiceIDs       []uuid.UUID   `json:"service_ids"         binding:"max=100"`
+	OfferIDs         []uuid.UUID   `json:"offer_ids"           binding:"max=100"`
+	FreelancerIDs    []uuid.UUID   `json:"freelancer_ids"      binding:"max=100"`
+	FirstBookingOnly bool          `json:"first_booking_only"`
+}
+
+type updateCouponReq struct {
+	Description    *string `json:"description"       binding:"omitempty,max=1000"`
+	IsActive       *bool   `json:"is_active"`
+	ValidFrom      string  `json:"valid_from"        binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ValidUntil     string  `json:"valid_until"       binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxUses        *int    `json:"max_uses"          binding:"omitempty,min=0"`
+	MaxUsesPerUser *int    `json:"max_uses_per_user" binding:"omitempty,min=0"`
+}
+
+// Create handles POST /admin/coupons
+func (h *CouponHandler) Create(c *gin.Context) {
+	var req createCouponReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	coupon, err := h.svc.Create(c.Request.Context(), service.CouponInput{
+		Code:             req.Code,
+		Description:      req.Description,
+		Kind:             req.Kind,
+		Percent:          req.Percent,
+		Amount:           req.Amount,
+		Currency:         strings.ToUpper(req.Currency),
+		MinSpend:         req.MinSpend,
+		ValidFrom:        optionalTime(req.ValidFrom),
+		ValidUntil:       optionalTime(req.ValidUntil),
+		MaxUses:          req.MaxUses,
+		MaxUsesPerUser:   req.MaxUsesPerUser,
+		ServiceIDs:       req.ServiceIDs,
+		OfferIDs:         req.OfferIDs,
+		FreelancerIDs:    req.FreelancerIDs,
+		FirstBookingOnly: req.FirstBookingOnly,
+	})
+	if err != nil {
+		c.JSON(couponErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// List handles GET /admin/coupons
+func (h *CouponHandler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Get handles GET /admin/coupons/:id including its redemptions.
+func (h *CouponHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	detail, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(couponErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// Update handles PATCH /admin/coupons/:id
+func (h *CouponHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req updateCouponReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	coupon, err := h.svc.Update(c.Request.Context(), id, service.CouponUpdate{
+		Description:    req.Description,
+		IsActive:       req.IsActive,
+		ValidFrom:      optionalTime(req.ValidFrom),
+		ValidUntil:     optionalTime(req.ValidUntil),
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+	})
+	if err != nil {
+		c.JSON(couponErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
+
+// optionalTime parses an RFC 3339 time that binding already checked, nil
+// when empty.
+func optionalTime(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, _ := time.Parse(time.RFC3339, s)
+	return &t
+}
+
+func couponErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCoupon):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCouponCodeTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
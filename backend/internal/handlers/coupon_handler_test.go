@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCouponIsRedeemedWithBooking(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	welcome := map[string]any{
+		"code": "welcome10", "kind": "percent", "percent": 10, "currency": "eur", "min_spend": "10",
+		"max_uses_per_user": 1, "first_booking_only": true,
+	}
+	w := sendAs(router, http.MethodPost, "/admin/coupons", ownerID, map[string]any{"code": "X", "kind": "fixed", "amount": "5"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "fixed coupons need a currency")
+	w = sendAs(router, http.MethodPost, "/admin/coupons", ownerID, welcome)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var coupon models.Coupon
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &coupon))
+	assert.Equal(t, "WELCOME10", coupon.Code)
+	assert.Equal(t, http.StatusConflict, sendAs(router, http.MethodPost, "/admin/coupons", ownerID, welcome).Code)
+
+	w = sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/quote", ownerID, map[string]any{"coupon_code": "Welcome10"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quote models.PriceBreakdown
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, int64(1350), quote.Total.Amount)
+	if assert.Len(t, quote.Lines, 2) {
+		assert.Equal(t, models.PriceLineDiscount, quote.Lines[1].Kind)
+		assert.Equal(t, "Coupon WELCOME10", quote.Lines[1].Label)
+	}
+
+	days := 2
+	book := func(code string) *http.Response {
+		days++
+		slot := seedSlot(t, db, offer.ID, time.Now().AddDate(0, 0, days))
+		return sendAs(router, http.MethodPost, "/bookings", ownerID, map[string]any{
+			"offer_id": offer.ID, "slot_id": slot.ID, "coupon_code": code,
+		}).Result()
+	}
+	assert.Equal(t, http.StatusNotFound, book("NOPE").StatusCode)
+	res := book("WELCOME10")
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	var booking models.Booking
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&booking))
+	assert.Equal(t, int64(1350), booking.Total.Amount)
+	assert.Equal(t, &coupon.ID, booking.CouponID)
+
+	detail := func() service.CouponDetail {
+		w := sendAs(router, http.MethodGet, "/admin/coupons/"+coupon.ID.String(), ownerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var d service.CouponDetail
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+		return d
+	}
+	d := detail()
+	assert.Equal(t, 1, d.Coupon.Uses)
+	if assert.Len(t, d.Redemptions, 1) {
+		assert.Equal(t, booking.ID, d.Redemptions[0].BookingID)
+		assert.Equal(t, int64(150), d.Redemptions[0].Discount.Amount)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, book("WELCOME10").StatusCode, "used once already")
+
+	// a fully refunded cancellation gives the use back
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, "/bookings/"+booking.ID.String()+"/cancel", freelancerID, nil).Code)
+	d = detail()
+	assert.Equal(t, 0, d.Coupon.Uses)
+	assert.Empty(t, d.Redemptions)
+
+	// the owner has kept a booking since, so it is no longer their first
+	assert.Equal(t, http.StatusCreated, book("").StatusCode)
+	assert.Equal(t, http.StatusUnprocessableEntity, book("WELCOME10").StatusCode)
+
+	w = sendAs(router, http.MethodPatch, "/admin/coupons/"+coupon.ID.String(), ownerID, map[string]any{"is_active": false})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"isActive":false`)
+}
+
+func TestCouponRestrictions(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	other := seedOffer(t, db, uuid.New(), true)
+	for _, c := range []map[string]any{
+		{"code": "FIVE", "kind": "fixed", "amount": "5", "currency": "EUR", "offer_ids": []uuid.UUID{offer.ID}, "max_uses": 1},
+		{"code": "BIGSPENDER", "kind": "fixed", "amount": "5", "currency": "EUR", "min_spend": "20"},
+		{"code": "DOLLARS", "kind": "percent", "percent": 50, "currency": "USD"},
+		{"code": "LATER", "kind": "percent", "percent": 50, "valid_from": time.Now().Add(24 * time.Hour).Format(time.RFC3339)},
+	} {
+		assert.Equal(t, http.StatusCreated, sendAs(router, http.MethodPost, "/admin/coupons", ownerID, c).Code)
+	}
+
+	quote := func(offer *models.ServiceOffer, code string) int {
+		return sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/quote", ownerID, map[string]any{"coupon_code": code}).Code
+	}
+	assert.Equal(t, http.StatusOK, quote(offer, "FIVE"))
+	assert.Equal(t, http.StatusUnprocessableEntity, quote(other, "FIVE"))
+	assert.Equal(t, http.StatusUnprocessableEntity, quote(offer, "BIGSPENDER"))
+	assert.Equal(t, http.StatusUnprocessableEntity, quote(offer, "DOLLARS"))
+	assert.Equal(t, http.StatusUnprocessableEntity, quote(offer, "LATER"))
+
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(72*time.Hour))
+	w := sendAs(router, http.MethodPost, "/bookings", ownerID, map[string]any{
+		"offer_id": offer.ID, "slot_id": slot.ID, "coupon_code": "five",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":"10.00"`)
+	assert.Equal(t, http.StatusUnprocessableEntity, quote(offer, "FIVE"), "its only use is taken")
+}
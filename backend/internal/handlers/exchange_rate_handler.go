@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type ExchangeRateHandler struct {
+	svc *service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(s *service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{svc: s}
+}
+
+type replaceRatesReq struct {
+	Base  string            `json:"base"  binding:"required,len=3"`
+	Rates map[string]string `json:"rates" binding:"required,min=1,max=200"`
+}
+
+// List handles GET /exchange-rates
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Replace handles PUT /admin/exchange-rates. The rates sent replace the
+// whole table.
+func (h *ExchangeRateHandler) Replace(c *gin.Context) {
+	var req replaceRatesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t := service.RateTable{Base: strings.ToUpper(req.Base), Rates: make(map[string]string, len(req.Rates))}
+	for currency, rate := range req.Rates {
+		t.Rates[strings.ToUpper(currency)] = rate
+	}
+	list, err := h.svc.Replace(c.Request.Context(), t)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRates) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/handlers"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOfferListRouter(t *testing.T) (*gin.Engine, *gorm.DB, *service.ExchangeRateService) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.ServiceOffer{}, &models.ExchangeRate{}))
+
+	ratesSvc := service.NewExchangeRateService(repository.NewExchangeRateRepository(db))
+	offerRepo := repository.NewServiceOfferRepository(db)
+	offerH := handlers.NewServiceOfferHandler(offerRepo, service.NewServiceOfferService(offerRepo, ratesSvc))
+	ratesH := handlers.NewExchangeRateHandler(ratesSvc)
+
+	r.GET("/offers", offerH.List)
+	r.GET("/exchange-rates", ratesH.List)
+	r.PUT("/admin/exchange-rates", ratesH.Replace)
+	return r, db, ratesSvc
+}
+
+func TestOffersSortedInRequestedCurrency(t *testing.T) {
+	router, db, _ := setupOfferListRouter(t)
+
+	serviceID := uuid.New()
+	for _, p := range []money.Money{money.New(2000, "EUR"), money.New(2000, "USD"), money.New(1500, "GBP"), money.New(900, "CHF")} {
+		assert.NoError(t, db.Create(&models.ServiceOffer{
+			FreelancerID: uuid.New(), ServiceID: serviceID, Title: "Walk in " + p.Currency, Description: "walk",
+			Price: p, Currency: p.Currency, PriceType: models.PriceTypeFixed, IsActive: true,
+		}).Error)
+	}
+
+	asUser := uuid.New()
+	w := sendAs(router, http.MethodPut, "/admin/exchange-rates", asUser, map[string]any{
+		"base": "eur", "rates": map[string]string{"USD": "1.0825", "gbp": "0.8561", "JPY": "-3"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendAs(router, http.MethodPut, "/admin/exchange-rates", asUser, map[string]any{
+		"base": "eur", "rates": map[string]string{"USD": "1.0825", "gbp": "0.8561"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rates []models.ExchangeRate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rates))
+	assert.Len(t, rates, 3)
+
+	list := func(query string) (int, []service.DisplayOffer) {
+		w := sendAs(router, http.MethodGet, "/offers"+query, asUser, nil)
+		var offers []service.DisplayOffer
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &offers))
+		}
+		return w.Code, offers
+	}
+
+	code, offers := list("?currency=usd&sort=price_asc")
+	assert.Equal(t, http.StatusOK, code)
+	var titles []string
+	for _, o := range offers {
+		titles = append(titles, o.Title)
+	}
+	// GBP 15.00 is USD 18.97 and EUR 20.00 is USD 21.65; CHF has no rate
+	assert.Equal(t, []string{"Walk in GBP", "Walk in USD", "Walk in EUR", "Walk in CHF"}, titles)
+	if assert.NotNil(t, offers[0].DisplayPrice) {
+		assert.Equal(t, int64(1897), offers[0].DisplayPrice.Amount)
+		assert.Equal(t, "USD", offers[0].DisplayCurrency)
+		assert.Equal(t, int64(1500), offers[0].Price.Amount, "the original price is kept")
+	}
+	assert.Nil(t, offers[3].DisplayPrice)
+
+	code, offers = list("?currency=EUR&sort=price_desc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Walk in EUR", offers[0].Title)
+
+	code, offers = list("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, offers, 4)
+	assert.Nil(t, offers[0].DisplayPrice)
+
+	code, _ = list("?sort=price_asc")
+	assert.Equal(t, http.StatusBadRequest, code, "sorting needs a currency")
+	code, _ = list("?currency=CHF")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestExchangeRatesLoadFromFile(t *testing.T) {
+	router, _, ratesSvc := setupOfferListRouter(t)
+
+	path := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": "0.9238", "JPY": "150.12"}}`), 0o600))
+	seeded, err := ratesSvc.LoadFile(context.Background(), path)
+	assert.NoError(t, err)
+	assert.True(t, seeded)
+
+	w := sendAs(router, http.MethodGet, "/exchange-rates", uuid.New(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rates []models.ExchangeRate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rates))
+	if assert.Len(t, rates, 3) {
+		assert.Equal(t, "EUR", rates[0].Currency)
+		assert.Equal(t, "1", rates[2].Rate)
+	}
+
+	// rates set since are kept on the next start
+	w = sendAs(router, http.MethodPut, "/admin/exchange-rates", uuid.New(), map[string]any{"base": "EUR", "rates": map[string]string{"USD": "1.08"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	seeded, err = ratesSvc.LoadFile(context.Background(), path)
+	assert.NoError(t, err)
+	assert.False(t, seeded)
+	list, err := ratesSvc.List(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "1.08", list[1].Rate)
+	}
+}
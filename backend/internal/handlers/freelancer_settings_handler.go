@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+)
+
+type FreelancerSettingsHandler struct {
+	repo *repository.FreelancerSettingsRepository
+}
+
+func NewFreelancerSettingsHandler(r *repository.FreelancerSettingsRepository) *FreelancerSettingsHandler {
+	return &FreelancerSettingsHandler{repo: r}
+}
+
+// Get handles GET /freelancer/settings
+func (h *FreelancerSettingsHandler) Get(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	settings, err := h.repo.Find(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+type updateFreelancerSettingsReq struct {
+	// minutes kept free between two appointments for getting from one to the next
+	TravelTimeMin *int `json:"travel_time_min" binding:"omitempty,min=0,max=720"`
+	// IANA zone the calendar feeds are written in, e.g. "Europe/Berlin"
+	Timezone string `json:"timezone" binding:"omitempty,max=64"`
+	// seller details printed on invoices
+	InvoiceName    *string `json:"invoice_name"    binding:"omitempty,max=200"`
+	InvoiceAddress *string `json:"invoice_address" binding:"omitempty,max=1000"`
+	TaxID          *string `json:"tax_id"          binding:"omitempty,max=50"`
+}
+
+// Update handles PUT /freelancer/settings
+func (h *FreelancerSettingsHandler) Update(c *gin.Context) {
+	var req updateFreelancerSettingsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone"})
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	settings, err := h.repo.Find(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Timezone != "" {
+		settings.Timezone = req.Timezone
+	}
+	if req.TravelTimeMin != nil {
+		settings.TravelTimeMin = *req.TravelTimeMin
+	}
+	if req.InvoiceName != nil {
+		settings.InvoiceName = *req.InvoiceName
+	}
+	if req.InvoiceAddress != nil {
+		settings.InvoiceAddress = *req.InvoiceAddress
+	}
+	if req.TaxID != nil {
+		settings.TaxID = *req.TaxID
+	}
+	if err := h.repo.Save(c.Request.Context(), settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetCommission handles PUT /admin/freelancers/:id/commission
+func (h *FreelancerSettingsHandler) SetCommission(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid freelancer id"})
+		return
+	}
+	var req setCommissionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.repo.SetCommission(c.Request.Context(), userID, req.CommissionBps); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	settings, err := h.repo.Find(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type InvoiceHandler struct {
+	svc *service.InvoiceService
+}
+
+func NewInvoiceHandler(s *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{svc: s}
+}
+
+type refundBookingReq struct {
+	Amount money.Decimal `json:"amount" binding:"required"`
+	Reason string        `json:"reason" binding:"omitempty,max=500"`
+}
+
+// Invoice handles GET /bookings/:id/invoice?format=pdf|html|json
+func (h *InvoiceHandler) Invoice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	invoice, err := h.svc.Invoice(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	writeInvoice(c, invoice)
+}
+
+// CreditNotes handles GET /bookings/:id/credit-notes
+func (h *InvoiceHandler) CreditNotes(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.CreditNotes(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreditNote handles GET /bookings/:id/credit-notes/:note_id?format=pdf|html|json
+func (h *InvoiceHandler) CreditNote(c *gin.Context) {
+	id, err1 := uuid.Parse(c.Param("id"))
+	noteID, err2 := uuid.Parse(c.Param("note_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id or note_id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	note, err := h.svc.CreditNote(c.Request.Context(), id, noteID, userID)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	writeInvoice(c, note)
+}
+
+// Refund handles POST /bookings/:id/refund
+func (h *InvoiceHandler) Refund(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req refundBookingReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	note, err := h.svc.Refund(c.Request.Context(), id, userID, req.Amount, req.Reason)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
+
+// writeInvoice sends the document in the requested format, PDF unless
+// another one is asked for.
+func writeInvoice(c *gin.Context, i *models.Invoice) {
+	switch c.DefaultQuery("format", "pdf") {
+	case "pdf":
+		c.Header("Content-Disposition", `inline; filename="`+i.Number+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", i.PDF)
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(i.HTML))
+	case "json":
+		c.JSON(http.StatusOK, i)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf, html or json"})
+	}
+}
+
+func invoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRefund):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvoiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrBookingNotCompleted),
+		errors.Is(err, service.ErrEarningPaidOut):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNothingToRefund),
+		errors.Is(err, service.ErrRefundTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrRefundFailed):
+		return http.StatusBadGateway
+	default:
+		return bookingErrorStatus(err)
+	}
+}
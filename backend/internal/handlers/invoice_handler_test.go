@@ -0,0 +1,202 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// completePaidBooking books the slot, pays for it and completes the visit.
+func completePaidBooking(t *testing.T, router *gin.Engine, offerID, slotID, ownerID, freelancerID uuid.UUID) string {
+	w := postBooking(router, offerID, slotID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-out", freelancerID, nil).Code)
+	return path
+}
+
+func seedInvoiceParties(t *testing.T, db *gorm.DB, ownerID, freelancerID uuid.UUID) {
+	assert.NoError(t, db.Create(&models.User{ID: ownerID, Email: "owner@example.com", PasswordHash: "x", Role: "owner"}).Error)
+	assert.NoError(t, db.Create(&models.User{ID: freelancerID, Email: "sam@example.com", PasswordHash: "x", Role: "freelancer"}).Error)
+	assert.NoError(t, db.Create(&models.FreelancerSettings{
+		UserID:         freelancerID,
+		Timezone:       "UTC",
+		InvoiceName:    "Sam Walks",
+		InvoiceAddress: "1 Park Lane\nBerlin",
+		TaxID:          "DE123456789",
+	}).Error)
+}
+
+func TestCompletedBookingIsInvoiced(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+	seedInvoiceParties(t, db, ownerID, freelancerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("prepaid", true).Error)
+	first := seedSlot(t, db, offer.ID, time.Now().Add(10*time.Minute))
+	second := seedSlot(t, db, offer.ID, time.Now().Add(75*time.Minute))
+
+	path := completePaidBooking(t, router, offer.ID, first.ID, ownerID, freelancerID)
+
+	w := sendAs(router, http.MethodGet, path+"/invoice", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, w.Body.String(), "INV-000001")
+
+	w = sendAs(router, http.MethodGet, path+"/invoice?format=html", freelancerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Invoice INV-000001")
+	assert.Contains(t, w.Body.String(), "Sam Walks")
+	assert.Contains(t, w.Body.String(), "owner@example.com")
+
+	w = sendAs(router, http.MethodGet, path+"/invoice?format=json", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var invoice models.Invoice
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &invoice))
+	assert.Equal(t, "INV-000001", invoice.Number)
+	assert.Equal(t, models.InvoiceKindInvoice, invoice.Kind)
+	assert.Equal(t, "EUR", invoice.Currency)
+	assert.Equal(t, int64(1500), invoice.Total.Amount)
+	assert.Equal(t, "Sam Walks", invoice.Seller.Name)
+	assert.Equal(t, "DE123456789", invoice.Seller.TaxID)
+	assert.Equal(t, "owner@example.com", invoice.Buyer.Email)
+	if assert.NotNil(t, invoice.Price) {
+		assert.Equal(t, int64(1500), invoice.Price.Subtotal.Amount)
+	}
+
+	// only the parties see it, and it is issued only once
+	assert.Equal(t, http.StatusForbidden, sendAs(router, http.MethodGet, path+"/invoice", uuid.New(), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, sendAs(router, http.MethodGet, path+"/invoice?format=xml", ownerID, nil).Code)
+	var count int64
+	assert.NoError(t, db.Model(&models.Invoice{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// issued invoices cannot be changed
+	var stored models.Invoice
+	assert.NoError(t, db.First(&stored, "id = ?", invoice.ID).Error)
+	stored.Reason = "edited"
+	assert.ErrorIs(t, db.Save(&stored).Error, models.ErrInvoiceImmutable)
+	assert.ErrorIs(t, db.Delete(&stored).Error, models.ErrInvoiceImmutable)
+
+	// the next booking is numbered on, once it is completed
+	w = postBooking(router, offer.ID, second.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var pending models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.Equal(t, http.StatusConflict, sendAs(router, http.MethodGet, "/bookings/"+pending.ID.String()+"/invoice", ownerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, "/bookings/"+pending.ID.String()+"/cancel", ownerID, nil).Code)
+
+	third := seedSlot(t, db, offer.ID, time.Now().Add(100*time.Minute))
+	other := completePaidBooking(t, router, offer.ID, third.ID, ownerID, freelancerID)
+	w = sendAs(router, http.MethodGet, other+"/invoice?format=json", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var next models.Invoice
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &next))
+	assert.Equal(t, "INV-000002", next.Number)
+}
+
+func TestRefundIssuesCreditNote(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+	seedInvoiceParties(t, db, ownerID, freelancerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("prepaid", true).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(10*time.Minute))
+	path := completePaidBooking(t, router, offer.ID, slot.ID, ownerID, freelancerID)
+
+	refund := func(as uuid.UUID, amount string) *httptest.ResponseRecorder {
+		return sendAs(router, http.MethodPost, path+"/refund", as, map[string]string{"amount": amount, "reason": "walk cut short"})
+	}
+
+	// only the freelancer refunds, and no more than was paid
+	assert.Equal(t, http.StatusForbidden, refund(ownerID, "5").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, refund(freelancerID, "15.01").Code)
+	assert.Equal(t, http.StatusBadRequest, refund(freelancerID, "-1").Code)
+
+	// a refund the provider refuses issues no credit note
+	var payment models.Payment
+	assert.NoError(t, db.First(&payment).Error)
+	intentID := payment.IntentID
+	assert.NoError(t, db.Model(&payment).Update("intent_id", "pi_gone").Error)
+	assert.Equal(t, http.StatusBadGateway, refund(freelancerID, "5.00").Code)
+	var issued int64
+	assert.NoError(t, db.Model(&models.Invoice{}).Where("kind = ?", models.InvoiceKindCreditNote).Count(&issued).Error)
+	assert.Equal(t, int64(0), issued)
+	assert.NoError(t, db.Model(&payment).Update("intent_id", intentID).Error)
+
+	w := refund(freelancerID, "5.00")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var note models.Invoice
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &note))
+	assert.Equal(t, "CN-000001", note.Number)
+	assert.Equal(t, models.InvoiceKindCreditNote, note.Kind)
+	assert.Equal(t, int64(-500), note.Total.Amount)
+	assert.Equal(t, "walk cut short", note.Reason)
+	assert.NotNil(t, note.InvoiceID)
+
+	w = sendAs(router, http.MethodGet, path+"/credit-notes", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var notes []models.Invoice
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &notes))
+	assert.Len(t, notes, 1)
+	w = sendAs(router, http.MethodGet, path+"/credit-notes/"+note.ID.String()+"?format=html", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Credit note CN-000001")
+	assert.Contains(t, w.Body.String(), "corrects invoice INV-000001")
+	assert.Equal(t, http.StatusNotFound, sendAs(router, http.MethodGet, path+"/credit-notes/"+uuid.NewString(), ownerID, nil).Code)
+
+	// the payment is partly refunded and the earning shrinks with it
+	w = sendAs(router, http.MethodGet, path+"/payments", ownerID, nil)
+	var list []models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, models.PaymentPartlyRefunded, list[0].Status)
+		assert.Equal(t, int64(500), list[0].Refunded.Amount)
+	}
+	w = sendAs(router, http.MethodGet, "/freelancer/earnings", freelancerID, nil)
+	var summary service.EarningsSummary
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assertTotals(t, summary.Totals, 850, 0, 0)
+
+	// once paid out, the earning can no longer be refunded
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payouts/run", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusConflict, refund(freelancerID, "1").Code)
+
+	ledger := service.NewLedgerService(repository.NewLedgerRepository(db), repository.NewPaymentRepository(db))
+	balances, err := ledger.TrialBalance(context.Background())
+	assert.NoError(t, err)
+	held := map[string]int64{}
+	for _, b := range balances {
+		held[b.Kind] += b.Balance.Amount
+	}
+	assert.Equal(t, int64(0), held[models.LedgerOwnerWallet])
+	assert.Equal(t, int64(0), held[models.LedgerFreelancerPayable])
+	assert.Equal(t, int64(150), held[models.LedgerPlatformRevenue])
+	problems, err := ledger.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
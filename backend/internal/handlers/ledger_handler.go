@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type LedgerHandler struct {
+	svc *service.LedgerService
+}
+
+func NewLedgerHandler(s *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{svc: s}
+}
+
+// Balances handles GET /ledger/balances, the caller's wallet and payable
+// accounts with what they hold in minor units.
+func (h *LedgerHandler) Balances(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	balances, err := h.svc.Balances(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, balances)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type OfferExtrasHandler struct {
+	svc *service.OfferExtrasService
+}
+
+func NewOfferExtrasHandler(s *service.OfferExtrasService) *OfferExtrasHandler {
+	return &OfferExtrasHandler{svc: s}
+}
+
+type createAddOnReq struct {
+	Name        string        `json:"name"        binding:"required,max=100"`
+	Description string        `json:"description" binding:"max=1000"`
+	Price       money.Decimal `json:"price"       binding:"required"`
+	ExtraMin    int           `json:"extra_min"   binding:"min=0,max=480"`
+}
+
+type createPackageReq struct {
+	Name      string        `json:"name"       binding:"required,max=100"`
+	Credits   int           `json:"credits"    binding:"required,min=1,max=100"`
+	Price     money.Decimal `json:"price"      binding:"required"`
+	ValidDays int           `json:"valid_days" binding:"required,min=1,max=730"`
+}
+
+// CreateAddOn handles POST /offers/:offer_id/add-ons
+func (h *OfferExtrasHandler) CreateAddOn(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	var req createAddOnReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	a, err := h.svc.CreateAddOn(c.Request.Context(), offerID, userID, service.AddOnInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		ExtraMin:    req.ExtraMin,
+	})
+	if err != nil {
+		c.JSON(extrasErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, a)
+}
+
+// ListAddOns handles GET /offers/:offer_id/add-ons
+func (h *OfferExtrasHandler) ListAddOns(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	list, err := h.svc.ListAddOns(c.Request.Context(), offerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RemoveAddOn handles DELETE /offers/:offer_id/add-ons/:add_on_id
+func (h *OfferExtrasHandler) RemoveAddOn(c *gin.Context) {
+	offerID, err1 := uuid.Parse(c.Param("offer_id"))
+	addOnID, err2 := uuid.Parse(c.Param("add_on_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id or add_on_id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.RemoveAddOn(c.Request.Context(), offerID, addOnID, userID); err != nil {
+		c.JSON(extrasErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreatePackage handles POST /offers/:offer_id/packages
+func (h *OfferExtrasHandler) CreatePackage(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	var req createPackageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	p, err := h.svc.CreatePackage(c.Request.Context(), offerID, userID, service.PackageInput{
+		Name:      req.Name,
+		Credits:   req.Credits,
+		Price:     req.Price,
+		ValidDays: req.ValidDays,
+	})
+	if err != nil {
+		c.JSON(extrasErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// ListPackages handles GET /offers/:offer_id/packages
+func (h *OfferExtrasHandler) ListPackages(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	list, err := h.svc.ListPackages(c.Request.Context(), offerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RemovePackage handles DELETE /offers/:offer_id/packages/:package_id
+func (h *OfferExtrasHandler) RemovePackage(c *gin.Context) {
+	offerID, err1 := uuid.Parse(c.Param("offer_id"))
+	packageID, err2 := uuid.Parse(c.Param("package_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id or package_id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.RemovePackage(c.Request.Context(), offerID, packageID, userID); err != nil {
+		c.JSON(extrasErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreditPacks handles GET /profile/credit-packs
+func (h *OfferExtrasHandler) CreditPacks(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.CreditPacks(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func extrasErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidPrice):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAddOnNotFound),
+		errors.Is(err, service.ErrPackageNotFound):
+		return http.StatusNotFound
+	default:
+		return bookingErrorStatus(err)
+	}
+}
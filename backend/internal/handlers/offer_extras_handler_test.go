@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOnsExtendTheVisit(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	addOnsPath := "/offers/" + offer.ID.String() + "/add-ons"
+
+	body := map[string]any{"name": "Nail trim", "price": "5.50", "extra_min": 30}
+	assert.Equal(t, http.StatusForbidden, sendAs(router, http.MethodPost, addOnsPath, ownerID, body).Code)
+	w := sendAs(router, http.MethodPost, addOnsPath, freelancerID, map[string]any{"name": "Nail trim", "price": "5.555"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendAs(router, http.MethodPost, addOnsPath, freelancerID, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var addOn models.AddOn
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &addOn))
+
+	w = sendAs(router, http.MethodGet, addOnsPath, ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"price":"5.50"`)
+
+	book := func(slot *models.AvailabilitySlot, addOnIDs ...uuid.UUID) *http.Response {
+		w := sendAs(router, http.MethodPost, "/bookings", ownerID, map[string]any{
+			"offer_id": offer.ID, "slot_id": slot.ID, "add_on_ids": addOnIDs,
+		})
+		return w.Result()
+	}
+
+	// the hour-long slot leaves no room for the extra half hour
+	short := seedSlot(t, db, offer.ID, time.Now().Add(24*time.Hour))
+	assert.Equal(t, http.StatusUnprocessableEntity, book(short, addOn.ID).StatusCode)
+	assert.Equal(t, http.StatusUnprocessableEntity, book(short, uuid.New()).StatusCode)
+
+	long := &models.AvailabilitySlot{OfferID: offer.ID, StartTime: time.Now().Add(48 * time.Hour)}
+	long.EndTime = long.StartTime.Add(90 * time.Minute)
+	assert.NoError(t, db.Create(long).Error)
+	res := book(long, addOn.ID)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	var booking models.Booking
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&booking))
+	assert.Equal(t, int64(2050), booking.Total.Amount)
+	assert.Equal(t, []uuid.UUID{addOn.ID}, booking.AddOnIDs)
+	if assert.NotNil(t, booking.Price) && assert.Len(t, booking.Price.Lines, 2) {
+		assert.Equal(t, models.PriceLineAddOn, booking.Price.Lines[1].Kind)
+		assert.Equal(t, "Nail trim", booking.Price.Lines[1].Label)
+	}
+}
+
+func TestCreditPackPaysForBookings(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	w := sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/packages", freelancerID, map[string]any{
+		"name": "5 walks", "credits": 5, "price": "60", "valid_days": 90,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var pkg models.Package
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pkg))
+
+	assert.Equal(t, http.StatusForbidden,
+		sendAs(router, http.MethodPost, "/packages/"+pkg.ID.String()+"/purchase", freelancerID, nil).Code)
+	w = sendAs(router, http.MethodPost, "/packages/"+pkg.ID.String()+"/purchase", ownerID, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var purchase service.PackPurchase
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &purchase))
+	assert.Equal(t, models.CreditPackAwaitingPayment, purchase.Pack.Status)
+	assert.Equal(t, int64(6000), purchase.Payment.Amount.Amount)
+
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Minute))
+	bookWithPack := func() *http.Response {
+		return sendAs(router, http.MethodPost, "/bookings", ownerID, map[string]any{
+			"offer_id": offer.ID, "slot_id": slot.ID, "credit_pack_id": purchase.Pack.ID,
+		}).Result()
+	}
+	// the pack cannot be used before it is paid
+	assert.Equal(t, http.StatusUnprocessableEntity, bookWithPack().StatusCode)
+	assert.Equal(t, http.StatusNoContent,
+		sendAs(router, http.MethodPost, "/payments/fake/"+purchase.Payment.IntentID+"/authorize", ownerID, nil).Code)
+
+	remaining := func() int {
+		w := sendAs(router, http.MethodGet, "/profile/credit-packs", ownerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var packs []models.CreditPack
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &packs))
+		assert.Len(t, packs, 1)
+		assert.Equal(t, models.CreditPackActive, packs[0].Status)
+		return packs[0].Remaining
+	}
+	assert.Equal(t, 5, remaining())
+
+	res := bookWithPack()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	var booking models.Booking
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&booking))
+	assert.True(t, booking.Total.IsZero())
+	assert.Equal(t, models.BookingStatusPending, booking.Status)
+	assert.Equal(t, int64(1200), booking.CreditValue.Amount)
+	assert.Equal(t, 4, remaining())
+
+	// a fully refunded cancellation gives the credit back
+	path := "/bookings/" + booking.ID.String()
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/cancel", freelancerID, nil).Code)
+	assert.Equal(t, 5, remaining())
+
+	res = bookWithPack()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&booking))
+	path = "/bookings/" + booking.ID.String()
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-out", freelancerID, nil).Code)
+	assert.Equal(t, 4, remaining())
+
+	// the freelancer earns the credit's share of the pack, less 15%
+	w = sendAs(router, http.MethodGet, "/freelancer/earnings", freelancerID, nil)
+	var summary service.EarningsSummary
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assertTotals(t, summary.Totals, 1020, 0, 0)
+
+	ledger := service.NewLedgerService(repository.NewLedgerRepository(db), repository.NewPaymentRepository(db))
+	problems, err := ledger.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+	balances, err := ledger.Balances(context.Background(), ownerID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 1) {
+		assert.Equal(t, int64(4800), balances[0].Balance.Amount, "four credits are still held")
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/payments"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+// maxWebhookSize bounds the body of a provider webhook.
+const maxWebhookSize = 64 << 10
+
+type PaymentHandler struct {
+	svc  *service.PaymentService
+	fake *payments.FakeProvider
+}
+
+// NewPaymentHandler takes the fake provider, or nil, to serve the route that
+// completes fake payments during development.
+func NewPaymentHandler(s *service.PaymentService, fake *payments.FakeProvider) *PaymentHandler {
+	return &PaymentHandler{svc: s, fake: fake}
+}
+
+// paymentErrorStatus maps PaymentService errors onto HTTP status codes.
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNothingToPay):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNotPayer):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPackageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, payments.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, payments.ErrIntentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, payments.ErrInvalidState):
+		return http.StatusConflict
+	case errors.Is(err, payments.ErrDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return bookingErrorStatus(err)
+	}
+}
+
+// Start handles POST /bookings/:id/payment. It answers with the payment to
+// complete on the client, reusing an open one.
+func (h *PaymentHandler) Start(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	payment, err := h.svc.Start(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, payment)
+}
+
+// BuyPack handles POST /packages/:id/purchase. It answers with the credit
+// pack and the payment that activates it.
+func (h *PaymentHandler) BuyPack(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid package id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	purchase, err := h.svc.BuyPack(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, purchase)
+}
+
+// List handles GET /bookings/:id/payments
+func (h *PaymentHandler) List(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Webhook handles POST /payments/webhook, called by the provider.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.HandleWebhook(c.Request.Context(), payload, c.Request.Header); err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// FakeAuthorize handles POST /payments/fake/:intent_id/authorize, standing in
+// for the payer and the provider's webhook while developing.
+func (h *PaymentHandler) FakeAuthorize(c *gin.Context) {
+	if h.fake == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "fake payments are disabled"})
+		return
+	}
+	payload, header, err := h.fake.Authorize(c.Param("intent_id"))
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.HandleWebhook(c.Request.Context(), payload, header); err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type PayoutHandler struct {
+	svc *service.PayoutService
+}
+
+func NewPayoutHandler(s *service.PayoutService) *PayoutHandler {
+	return &PayoutHandler{svc: s}
+}
+
+// Earnings handles GET /freelancer/earnings: pending, available and paid
+// totals per currency in minor units, and the most recent payouts.
+func (h *PayoutHandler) Earnings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	summary, err := h.svc.Earnings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertTotals checks that the freelancer earned in EUR only, pending,
+// available and paid in minor units.
+func assertTotals(t *testing.T, totals []models.EarningTotals, pending, available, paid int64) {
+	t.Helper()
+	if assert.Len(t, totals, 1) {
+		assert.Equal(t, "EUR", totals[0].Currency)
+		assert.Equal(t, []int64{pending, available, paid},
+			[]int64{totals[0].Pending.Amount, totals[0].Available.Amount, totals[0].Paid.Amount})
+	}
+}
+
+func TestCompletedBookingIsPaidOut(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	// the commission agreed with the freelancer beats the service's
+	svc := &models.Service{Name: "Dog walking"}
+	assert.NoError(t, db.Create(svc).Error)
+	w := sendAs(router, http.MethodPut, "/admin/services/"+svc.ID.String()+"/commission", ownerID, map[string]any{"commission_bps": 0})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.Service
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	if assert.NotNil(t, updated.CommissionBps) {
+		assert.Equal(t, 0, *updated.CommissionBps)
+	}
+	assert.Equal(t, http.StatusNotFound, sendAs(router, http.MethodPut, "/admin/services/"+uuid.NewString()+"/commission", ownerID, map[string]any{"commission_bps": 0}).Code)
+	assert.Equal(t, http.StatusBadRequest, sendAs(router, http.MethodPut, "/admin/freelancers/"+freelancerID.String()+"/commission", ownerID, map[string]any{"commission_bps": 10001}).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPut, "/admin/freelancers/"+freelancerID.String()+"/commission", ownerID, map[string]any{"commission_bps": 1000}).Code)
+
+	// the freelancer saving their own settings leaves it alone
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPut, "/freelancer/settings", freelancerID, map[string]any{"travel_time_min": 0}).Code)
+	var settings models.FreelancerSettings
+	assert.NoError(t, db.First(&settings, "user_id = ?", freelancerID).Error)
+	if assert.NotNil(t, settings.CommissionBps) {
+		assert.Equal(t, 1000, *settings.CommissionBps)
+	}
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Updates(map[string]any{"prepaid": true, "service_id": svc.ID}).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Minute))
+	w = postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	w = sendAs(router, http.MethodPost, path+"/payment", ownerID, nil)
+	var payment models.Payment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payments/fake/"+payment.IntentID+"/authorize", ownerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-out", freelancerID, nil).Code)
+
+	earnings := func() service.EarningsSummary {
+		w := sendAs(router, http.MethodGet, "/freelancer/earnings", freelancerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var s service.EarningsSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+		return s
+	}
+
+	// the earning is on hold first
+	summary := earnings()
+	assertTotals(t, summary.Totals, 1350, 0, 0)
+	assert.Empty(t, summary.Payouts)
+
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payouts/run", freelancerID, nil).Code)
+	summary = earnings()
+	assertTotals(t, summary.Totals, 0, 0, 1350)
+	assert.Len(t, summary.Payouts, 1)
+	assert.Equal(t, models.PayoutPaid, summary.Payouts[0].Status)
+	assert.Equal(t, int64(1350), summary.Payouts[0].Amount.Amount)
+	assert.Len(t, summary.Payouts[0].Earnings, 1)
+	assert.Equal(t, int64(150), summary.Payouts[0].Earnings[0].Commission.Amount)
+
+	// nothing is left to pay, and the books still balance
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodPost, "/payouts/run", freelancerID, nil).Code)
+	assert.Len(t, earnings().Payouts, 1)
+
+	ledger := service.NewLedgerService(repository.NewLedgerRepository(db), repository.NewPaymentRepository(db))
+	balances, err := ledger.TrialBalance(context.Background())
+	assert.NoError(t, err)
+	held := map[string]int64{}
+	for _, b := range balances {
+		held[b.Kind] += b.Balance.Amount
+	}
+	assert.Equal(t, map[string]int64{
+		models.LedgerCash:              150,
+		models.LedgerOwnerWallet:       0,
+		models.LedgerFreelancerPayable: 0,
+		models.LedgerPlatformRevenue:   150,
+	}, held)
+	problems, err := ledger.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type PricingRuleHandler struct {
+	svc *service.PricingRuleService
+}
+
+func NewPricingRuleHandler(s *service.PricingRuleService) *PricingRuleHandler {
+	return &PricingRuleHandler{svc: s}
+}
+
+type createPricingRuleReq struct {
+	Kind      string   `json:"kind"       binding:"required"`
+	Label     string   `json:"label"      binding:"required,max=100"`
+	Percent   int      `json:"percent"    binding:"required"`
+	Weekdays  []int    `json:"weekdays"   binding:"max=7"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+	Dates     []string `json:"dates"      binding:"max=366"`
+	LeadHours int      `json:"lead_hours"`
+	MinPets   int      `json:"min_pets"`
+}
+
+// Create handles POST /offers/:offer_id/pricing-rules
+func (h *PricingRuleHandler) Create(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	var req createPricingRuleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	rule, err := h.svc.Create(c.Request.Context(), offerID, userID, &models.PricingRule{
+		Kind:      req.Kind,
+		Label:     req.Label,
+		Percent:   req.Percent,
+		Weekdays:  req.Weekdays,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Dates:     req.Dates,
+		LeadHours: req.LeadHours,
+		MinPets:   req.MinPets,
+	})
+	if err != nil {
+		c.JSON(pricingRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// List handles GET /offers/:offer_id/pricing-rules
+func (h *PricingRuleHandler) List(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), offerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Delete handles DELETE /offers/:offer_id/pricing-rules/:rule_id
+func (h *PricingRuleHandler) Delete(c *gin.Context) {
+	offerID, err1 := uuid.Parse(c.Param("offer_id"))
+	ruleID, err2 := uuid.Parse(c.Param("rule_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id or rule_id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), offerID, ruleID, userID); err != nil {
+		c.JSON(pricingRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func pricingRuleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidPricingRule):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrPricingRuleNotFound):
+		return http.StatusNotFound
+	default:
+		return bookingErrorStatus(err)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricingRulesAreItemised(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	rulesPath := "/offers/" + offer.ID.String() + "/pricing-rules"
+	soon := seedSlot(t, db, offer.ID, time.Now().Add(2*time.Hour))
+	later := seedSlot(t, db, offer.ID, time.Now().Add(100*time.Hour))
+
+	weekday := map[string]any{
+		"kind": "weekday", "label": "Busy day", "percent": 20,
+		"weekdays": []int{int(soon.StartTime.UTC().Weekday())},
+	}
+	assert.Equal(t, http.StatusForbidden, sendAs(router, http.MethodPost, rulesPath, ownerID, weekday).Code)
+	w := sendAs(router, http.MethodPost, rulesPath, freelancerID, map[string]any{
+		"kind": "last_minute", "label": "Last minute", "percent": 10,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "last-minute rules need lead hours")
+	for _, rule := range []map[string]any{
+		weekday,
+		{"kind": "last_minute", "label": "Last minute", "percent": 10, "lead_hours": 24},
+		{"kind": "early_bird", "label": "Early bird", "percent": -10, "lead_hours": 72},
+		{"kind": "multi_pet", "label": "Second pet", "percent": -25, "min_pets": 2},
+	} {
+		assert.Equal(t, http.StatusCreated, sendAs(router, http.MethodPost, rulesPath, freelancerID, rule).Code)
+	}
+
+	// two pets soon: EUR 30.00 +20% +10% -25%
+	w = sendAs(router, http.MethodPost, "/bookings", ownerID, map[string]any{
+		"offer_id": offer.ID, "slot_id": soon.ID, "pets": 2,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, 2, booking.Pets)
+	assert.Equal(t, int64(3150), booking.Total.Amount)
+	if assert.NotNil(t, booking.Price) && assert.Len(t, booking.Price.Lines, 4) {
+		assert.Equal(t, "Dog walk for 2 pets", booking.Price.Lines[0].Label)
+		assert.Equal(t, models.PriceLineSurcharge, booking.Price.Lines[1].Kind)
+		assert.Equal(t, "Last minute", booking.Price.Lines[2].Label)
+		assert.Equal(t, models.PriceLineDiscount, booking.Price.Lines[3].Kind)
+		assert.Equal(t, int64(-750), booking.Price.Lines[3].Amount.Amount)
+	}
+
+	// one pet, four days ahead, on another weekday
+	w = sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/quote", ownerID, map[string]any{"slot_id": later.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quote models.PriceBreakdown
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, int64(1350), quote.Total.Amount)
+	if assert.Len(t, quote.Lines, 2) {
+		assert.Equal(t, "Early bird", quote.Lines[1].Label)
+	}
+}
+
+func TestHolidaySurchargeAppliesPerNight(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("price_type", models.PriceTypeNightly).Error)
+	checkIn := time.Now().AddDate(0, 0, 10)
+	w := sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/pricing-rules", freelancerID, map[string]any{
+		"kind": "holiday", "label": "Public holiday", "percent": 50,
+		"dates": []string{checkIn.AddDate(0, 0, 1).Format("2006-01-02"), checkIn.AddDate(0, 0, 30).Format("2006-01-02")},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var rule models.PricingRule
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rule))
+
+	quote := func() models.PriceBreakdown {
+		w := sendAs(router, http.MethodPost, "/offers/"+offer.ID.String()+"/quote", ownerID, map[string]any{
+			"check_in": checkIn.Format("2006-01-02"), "check_out": checkIn.AddDate(0, 0, 3).Format("2006-01-02"),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var q models.PriceBreakdown
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &q))
+		return q
+	}
+	// three nights at EUR 15.00, one of them on the holiday
+	q := quote()
+	assert.Equal(t, int64(5250), q.Total.Amount)
+	if assert.Len(t, q.Lines, 2) {
+		assert.Equal(t, "Public holiday", q.Lines[1].Label)
+		assert.Equal(t, int64(750), q.Lines[1].Amount.Amount)
+	}
+
+	path := "/offers/" + offer.ID.String() + "/pricing-rules/" + rule.ID.String()
+	assert.Equal(t, http.StatusForbidden, sendAs(router, http.MethodDelete, path, ownerID, nil).Code)
+	assert.Equal(t, http.StatusNoContent, sendAs(router, http.MethodDelete, path, freelancerID, nil).Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(router, http.MethodDelete, path, freelancerID, nil).Code)
+	assert.Equal(t, int64(4500), quote().Total.Amount)
+}
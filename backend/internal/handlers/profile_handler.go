@@ -4,12 +4,18 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
 )
 
-type ProfileHandler struct{ users *repository.UserRepository }
+type ProfileHandler struct {
+	users       *repository.UserRepository
+	reliability *repository.ReliabilityRepository
+}
 
-func NewProfileHandler(u *repository.UserRepository) *ProfileHandler { return &ProfileHandler{u} }
+func NewProfileHandler(u *repository.UserRepository, r *repository.ReliabilityRepository) *ProfileHandler {
+	return &ProfileHandler{u, r}
+}
 
 func (h *ProfileHandler) Me(c *gin.Context) {
 	uid := c.GetString("uid")
@@ -18,9 +24,57 @@ func (h *ProfileHandler) Me(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
 	}
+	stats, err := h.reliability.Find(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":          user.ID,
+		"email":       user.Email,
+		"role":        user.Role,
+		"reliability": stats,
+	})
+}
+
+// Public handles GET /users/:id, the profile anyone may see: no contact
+// details, but how reliable the user has been.
+func (h *ProfileHandler) Public(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	user, err := h.users.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	stats, err := h.reliability.Find(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"id":    user.ID,
-		"email": user.Email,
-		"role":  user.Role,
+		"id":              user.ID,
+		"role":            user.Role,
+		"profilePhotoUrl": user.ProfilePhotoURL,
+		"memberSince":     user.CreatedAt,
+		"reliability":     stats,
 	})
 }
+
+// Reliability handles GET /profile/reliability, the caller's recent history
+// of completed bookings, no-shows and cancellations.
+func (h *ProfileHandler) Reliability(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	events, err := h.reliability.ListEvents(c.Request.Context(), userID, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
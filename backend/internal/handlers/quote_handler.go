@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type QuoteHandler struct {
+	svc *service.QuoteService
+}
+
+func NewQuoteHandler(s *service.QuoteService) *QuoteHandler {
+	return &QuoteHandler{svc: s}
+}
+
+type quoteReq struct {
+	SlotID      string `json:"slot_id"      binding:"omitempty,uuid"`
+	DurationMin int    `json:"duration_min" binding:"min=0,max=1440"`
+	CheckIn     string `json:"check_in"     binding:"omitempty,datetime=2006-01-02"`
+	CheckOut    string `json:"check_out"    binding:"omitempty,datetime=2006-01-02"`
+	extrasReq
+}
+
+// Quote handles POST /offers/:offer_id/quote. It prices a slot, a duration
+// or, for stays, the nights from check_in to check_out, with the chosen
+// add-ons and credit pack, without booking them.
+func (h *QuoteHandler) Quote(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	var req quoteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ownerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	q := service.QuoteRequest{DurationMin: req.DurationMin, Extras: req.extras()}
+	if req.SlotID != "" {
+		slotID, _ := uuid.Parse(req.SlotID)
+		q.SlotID = &slotID
+	}
+	q.CheckIn, _ = time.Parse("2006-01-02", req.CheckIn)
+	q.CheckOut, _ = time.Parse("2006-01-02", req.CheckOut)
+
+	quote, err := h.svc.Quote(c.Request.Context(), offerID, ownerID, q)
+	if err != nil {
+		c.JSON(quoteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+func quoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidQuote):
+		return http.StatusBadRequest
+	default:
+		return stayErrorStatus(err)
+	}
+}
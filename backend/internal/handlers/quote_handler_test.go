@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIsSnapshottedOnBooking(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	assert.NoError(t, db.Model(offer).Update("price_type", models.PriceTypeHourly).Error)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(72*time.Hour))
+	quotePath := "/offers/" + offer.ID.String() + "/quote"
+
+	// 90 minutes at EUR 15.00 per hour
+	w := sendAs(router, http.MethodPost, quotePath, ownerID, map[string]any{"duration_min": 90})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quote models.PriceBreakdown
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, int64(2250), quote.Total.Amount)
+	assert.Len(t, quote.Lines, 1)
+	assert.Equal(t, models.PriceLineBase, quote.Lines[0].Kind)
+	assert.Contains(t, w.Body.String(), `"total":"22.50"`)
+
+	// the slot is an hour long
+	w = sendAs(router, http.MethodPost, quotePath, ownerID, map[string]any{"slot_id": slot.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, int64(1500), quote.Total.Amount)
+
+	other := seedSlot(t, db, seedOffer(t, db, freelancerID, true).ID, time.Now().Add(72*time.Hour))
+	w = sendAs(router, http.MethodPost, quotePath, ownerID, map[string]any{"slot_id": other.ID})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, "EUR", booking.Currency)
+	assert.Equal(t, int64(1500), booking.Total.Amount)
+	if assert.NotNil(t, booking.Price) {
+		assert.Equal(t, quote.Lines, booking.Price.Lines)
+	}
+
+	// raising the price later does not change the booking
+	assert.NoError(t, db.Model(offer).Update("price_minor", 4000).Error)
+	w = sendAs(router, http.MethodGet, "/bookings/"+booking.ID.String(), ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	assert.Equal(t, int64(1500), booking.Price.Total.Amount)
+}
+
+func TestQuoteStayNights(t *testing.T) {
+	ownerID := uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, uuid.New(), true)
+	assert.NoError(t, db.Model(offer).Update("price_type", models.PriceTypeNightly).Error)
+	quotePath := "/offers/" + offer.ID.String() + "/quote"
+
+	checkIn := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	checkOut := time.Now().AddDate(0, 0, 13).Format("2006-01-02")
+	w := sendAs(router, http.MethodPost, quotePath, ownerID, map[string]any{"check_in": checkIn, "check_out": checkOut})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quote models.PriceBreakdown
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, int64(4500), quote.Total.Amount)
+	assert.Equal(t, "3 nights at EUR 15.00", quote.Lines[0].Label)
+
+	w = sendAs(router, http.MethodPost, quotePath, ownerID, map[string]any{"check_in": checkOut, "check_out": checkIn})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"github.com/shardy678/pet-freelance/backend/internal/service"
 )
 
@@ -18,10 +21,16 @@ func NewServiceHandler(s *service.ServiceService) *ServiceHandler {
 }
 
 type createServiceReq struct {
-	Name               string  `json:"name" binding:"required"`
-	Description        string  `json:"description"`
-	BasePrice          float64 `json:"base_price" binding:"gte=0"`
-	DefaultDurationMin int     `json:"default_duration_min" binding:"gte=1"`
+	Name               string        `json:"name" binding:"required"`
+	Description        string        `json:"description"`
+	BasePrice          money.Decimal `json:"base_price"`
+	Currency           string        `json:"currency" binding:"omitempty,len=3"`
+	DefaultDurationMin int           `json:"default_duration_min" binding:"gte=1"`
+}
+
+// setCommissionReq sets a commission in basis points; null clears it.
+type setCommissionReq struct {
+	CommissionBps *int `json:"commission_bps" binding:"omitempty,min=0,max=10000"`
 }
 
 // Create handles POST /services
@@ -32,10 +41,25 @@ func (h *ServiceHandler) Create(c *gin.Context) {
 		return
 	}
 
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = "EUR"
+	}
+	basePrice := money.New(0, currency)
+	if req.BasePrice != "" {
+		var err error
+		basePrice, err = money.Parse(string(req.BasePrice), currency)
+		if err != nil || basePrice.Amount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid base_price"})
+			return
+		}
+	}
+
 	svc := &models.Service{
 		Name:               req.Name,
 		Description:        req.Description,
-		BasePrice:          req.BasePrice,
+		BasePrice:          basePrice,
+		Currency:           currency,
 		DefaultDurationMin: req.DefaultDurationMin,
 	}
 
@@ -73,3 +97,27 @@ func (h *ServiceHandler) Get(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, svc)
 }
+
+// SetCommission handles PUT /admin/services/:id/commission
+func (h *ServiceHandler) SetCommission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service id"})
+		return
+	}
+	var req setCommissionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	svc, err := h.svc.SetCommission(c.Request.Context(), id, req.CommissionBps)
+	if err != nil {
+		if errors.Is(err, service.ErrServiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, svc)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/handlers"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
 	"github.com/shardy678/pet-freelance/backend/internal/service"
 	"github.com/stretchr/testify/assert"
@@ -59,7 +60,7 @@ func TestCreateAndGetService(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &created)
 	assert.NoError(t, err)
 	assert.Equal(t, "Grooming", created.Name)
-	assert.Equal(t, 30.5, created.BasePrice)
+	assert.Equal(t, int64(3050), created.BasePrice.Amount)
 	assert.Equal(t, 90, created.DefaultDurationMin)
 	assert.NotEqual(t, uuid.Nil, created.ID)
 
@@ -80,8 +81,8 @@ func TestListServices(t *testing.T) {
 	router, db := setupServiceRouter(t)
 
 	services := []models.Service{
-		{ID: uuid.New(), Name: "Walking", BasePrice: 10, DefaultDurationMin: 30},
-		{ID: uuid.New(), Name: "Training", BasePrice: 50, DefaultDurationMin: 60},
+		{ID: uuid.New(), Name: "Walking", BasePrice: money.New(1000, "EUR"), DefaultDurationMin: 30},
+		{ID: uuid.New(), Name: "Training", BasePrice: money.New(5000, "EUR"), DefaultDurationMin: 60},
 	}
 	err := db.Create(&services).Error
 	assert.NoError(t, err)
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
 	"github.com/shardy678/pet-freelance/backend/internal/service"
 )
@@ -25,13 +29,28 @@ func NewServiceOfferHandler(
 }
 
 type createServiceOfferReq struct {
-	ServiceID           string  `json:"service_id" binding:"required,uuid"`
-	Title               string  `json:"title" binding:"required"`
-	Description         string  `json:"description" binding:"required"`
-	Price               float32 `json:"price" binding:"required,gt=0"`
-	Currency            string  `json:"currency" binding:"required,len=3"`
-	PriceType           string  `json:"price_type" binding:"required,oneof=hourly fixed"`
-	DurationEstimateMin int     `json:"duration_estimate_min" binding:"omitempty,gt=0"`
+	ServiceID           string        `json:"service_id" binding:"required,uuid"`
+	Title               string        `json:"title" binding:"required"`
+	Description         string        `json:"description" binding:"required"`
+	Price               money.Decimal `json:"price" binding:"required"`
+	Currency            string        `json:"currency" binding:"required,len=3"`
+	PriceType           string        `json:"price_type" binding:"required,oneof=hourly fixed nightly"`
+	DurationEstimateMin int           `json:"duration_estimate_min" binding:"omitempty,gt=0"`
+	CancellationPolicy  string        `json:"cancellation_policy" binding:"omitempty,oneof=flexible moderate strict custom"`
+	CancellationTiers   []struct {
+		MinHoursBefore int `json:"min_hours_before"`
+		RefundPercent  int `json:"refund_percent"`
+	} `json:"cancellation_tiers" binding:"required_if=CancellationPolicy custom"`
+	RescheduleApproval bool   `json:"reschedule_approval"`
+	CheckInTime        string `json:"check_in_time" binding:"omitempty,datetime=15:04"`
+	CheckOutTime       string `json:"check_out_time" binding:"omitempty,datetime=15:04"`
+	BufferBeforeMin    int    `json:"buffer_before_min" binding:"min=0,max=720"`
+	BufferAfterMin     int    `json:"buffer_after_min" binding:"min=0,max=720"`
+	// prepaid offers are paid in full when booking; otherwise owners with at
+	// least DepositAfterNoShows no-shows pay DepositPercent up front
+	Prepaid             bool `json:"prepaid"`
+	DepositPercent      int  `json:"deposit_percent" binding:"min=0,max=100"`
+	DepositAfterNoShows int  `json:"deposit_after_no_shows" binding:"min=0"`
 }
 
 // Create handles POST /offers
@@ -61,16 +80,55 @@ func (h *ServiceOfferHandler) Create(c *gin.Context) {
 		return
 	}
 
+	currency := strings.ToUpper(req.Currency)
+	price, err := money.Parse(string(req.Price), currency)
+	if err != nil || !price.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be a positive amount with at most " +
+			strconv.Itoa(money.Exponent(currency)) + " decimals"})
+		return
+	}
+
+	policy := req.CancellationPolicy
+	if policy == "" {
+		policy = models.CancellationFlexible
+	}
+	var tiers []models.RefundTier
+	if policy == models.CancellationCustom {
+		for _, t := range req.CancellationTiers {
+			tiers = append(tiers, models.RefundTier{MinHoursBefore: t.MinHoursBefore, RefundPercent: t.RefundPercent})
+		}
+		if err := models.ValidateRefundTiers(tiers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	offer := &models.ServiceOffer{
 		ID:                  uuid.New(),
 		FreelancerID:        freelancerID,
 		ServiceID:           serviceID,
 		Title:               req.Title,
 		Description:         req.Description,
-		Price:               req.Price,
-		Currency:            req.Currency,
+		Price:               price,
+		Currency:            currency,
 		PriceType:           req.PriceType,
 		DurationEstimateMin: req.DurationEstimateMin,
+		CancellationPolicy:  policy,
+		CancellationTiers:   tiers,
+		RescheduleApproval:  req.RescheduleApproval,
+		CheckInTime:         req.CheckInTime,
+		CheckOutTime:        req.CheckOutTime,
+		BufferBeforeMin:     req.BufferBeforeMin,
+		BufferAfterMin:      req.BufferAfterMin,
+		Prepaid:             req.Prepaid,
+		DepositPercent:      req.DepositPercent,
+		DepositAfterNoShows: req.DepositAfterNoShows,
+	}
+	if offer.CheckInTime == "" {
+		offer.CheckInTime = "14:00"
+	}
+	if offer.CheckOutTime == "" {
+		offer.CheckOutTime = "11:00"
 	}
 
 	if err := h.repo.Create(c.Request.Context(), offer); err != nil {
@@ -80,7 +138,9 @@ func (h *ServiceOfferHandler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, offer)
 }
 
-// List handles GET /offers and supports optional ?service_id=…
+// List handles GET /offers and supports optional ?service_id=…, plus
+// ?currency=… to show prices converted into it and ?sort=price_asc or
+// price_desc to order by the converted price.
 func (h *ServiceOfferHandler) List(c *gin.Context) {
 	// Check for an optional service_id query parameter
 	svcParam := c.Query("service_id")
@@ -104,7 +164,7 @@ func (h *ServiceOfferHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, offers)
+	h.display(c, offers)
 }
 
 // Get handles GET /offers/:id
@@ -141,5 +201,21 @@ func (h *ServiceOfferHandler) ListByService(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, offers)
+	h.display(c, offers)
+}
+
+// display responds with the offers, their prices converted and sorted as
+// the currency and sort query parameters ask.
+func (h *ServiceOfferHandler) display(c *gin.Context, offers []models.ServiceOffer) {
+	list, err := h.svc.Display(c.Request.Context(), offers, strings.ToUpper(c.Query("currency")), c.Query("sort"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSort), errors.Is(err, service.ErrNoExchangeRate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, list)
 }
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type StayHandler struct {
+	svc *service.StayService
+}
+
+func NewStayHandler(s *service.StayService) *StayHandler {
+	return &StayHandler{svc: s}
+}
+
+type setNightsReq struct {
+	From     string `json:"from"     binding:"required,datetime=2006-01-02"`
+	To       string `json:"to"       binding:"required,datetime=2006-01-02"`
+	Capacity int    `json:"capacity" binding:"min=0,max=100"`
+}
+
+// SetNights handles PUT /offers/:offer_id/nights. "to" is exclusive.
+func (h *StayHandler) SetNights(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	var req setNightsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	from, _ := time.Parse("2006-01-02", req.From)
+	to, _ := time.Parse("2006-01-02", req.To)
+
+	nights, err := h.svc.SetCapacity(c.Request.Context(), offerID, userID, from, to, req.Capacity)
+	if err != nil {
+		c.JSON(stayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, nights)
+}
+
+// ListNights handles GET /offers/:offer_id/nights?from=2025-01-01&to=2025-02-01
+func (h *StayHandler) ListNights(c *gin.Context) {
+	offerID, err := uuid.Parse(c.Param("offer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer_id"})
+		return
+	}
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		from = time.Now()
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		to = from.AddDate(0, 1, 0)
+	}
+
+	nights, err := h.svc.ListNights(c.Request.Context(), offerID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, nights)
+}
+
+type bookStayReq struct {
+	OfferID  string `json:"offer_id"  binding:"required,uuid"`
+	CheckIn  string `json:"check_in"  binding:"required,datetime=2006-01-02"`
+	CheckOut string `json:"check_out" binding:"required,datetime=2006-01-02"`
+	extrasReq
+}
+
+// BookStay handles POST /bookings/stays
+func (h *StayHandler) BookStay(c *gin.Context) {
+	var req bookStayReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ownerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	offerID, _ := uuid.Parse(req.OfferID)
+	checkIn, _ := time.Parse("2006-01-02", req.CheckIn)
+	checkOut, _ := time.Parse("2006-01-02", req.CheckOut)
+
+	result, err := h.svc.BookStay(c.Request.Context(), offerID, ownerID, checkIn, checkOut, req.extras())
+	if err != nil {
+		var unavailable *service.StayUnavailableError
+		if errors.As(err, &unavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "nights": unavailable.Nights})
+			return
+		}
+		c.JSON(stayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func stayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidStayDates):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNotStayOffer):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrCapacityBelowBooked):
+		return http.StatusConflict
+	default:
+		return bookingErrorStatus(err)
+	}
+}
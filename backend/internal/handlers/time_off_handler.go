@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type TimeOffHandler struct {
+	svc *service.TimeOffService
+}
+
+func NewTimeOffHandler(s *service.TimeOffService) *TimeOffHandler {
+	return &TimeOffHandler{svc: s}
+}
+
+type createTimeOffReq struct {
+	StartsAt string `json:"starts_at" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	EndsAt   string `json:"ends_at"   binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	Reason   string `json:"reason"    binding:"omitempty,max=200"`
+}
+
+// Create handles POST /freelancer/time-off and returns the new period with
+// the bookings that conflict with it.
+func (h *TimeOffHandler) Create(c *gin.Context) {
+	var req createTimeOffReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	start, _ := time.Parse(time.RFC3339, req.StartsAt)
+	end, _ := time.Parse(time.RFC3339, req.EndsAt)
+
+	result, err := h.svc.Create(c.Request.Context(), userID, start, end, req.Reason)
+	if err != nil {
+		c.JSON(timeOffErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// List handles GET /freelancer/time-off
+func (h *TimeOffHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Get handles GET /freelancer/time-off/:id including the conflict report.
+func (h *TimeOffHandler) Get(c *gin.Context) {
+	id, userID, ok := timeOffParams(c)
+	if !ok {
+		return
+	}
+	result, err := h.svc.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(timeOffErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Delete handles DELETE /freelancer/time-off/:id
+func (h *TimeOffHandler) Delete(c *gin.Context) {
+	id, userID, ok := timeOffParams(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id, userID); err != nil {
+		c.JSON(timeOffErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func timeOffParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, ok := currentUserID(c)
+	return id, userID, ok
+}
+
+func timeOffErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidTimeOff):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTimeOffNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
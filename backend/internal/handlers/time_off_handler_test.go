@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/handlers"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTimeOffRouter(t *testing.T, freelancerID uuid.UUID) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(
+		&models.ServiceOffer{},
+		&models.FreelancerSettings{},
+		&models.TimeOff{},
+		&models.AvailabilitySlot{},
+		&models.Booking{},
+	))
+
+	slotRepo := repository.NewAvailabilitySlotRepository(db)
+	offerRepo := repository.NewServiceOfferRepository(db)
+	timeOffRepo := repository.NewTimeOffRepository(db)
+	slotH := handlers.NewAvailabilitySlotHandler(service.NewAvailabilitySlotService(
+		slotRepo, offerRepo, repository.NewFreelancerSettingsRepository(db), timeOffRepo, db))
+	timeOffH := handlers.NewTimeOffHandler(service.NewTimeOffService(
+		timeOffRepo, repository.NewBookingRepository(db), slotRepo, offerRepo))
+
+	r.Use(func(c *gin.Context) {
+		c.Set("uid", freelancerID.String())
+		c.Next()
+	})
+	r.GET("/offers/:offer_id/slots", slotH.List)
+	r.POST("/offers/:offer_id/slots", slotH.Create)
+	r.PUT("/slots/:slot_id", slotH.Update)
+	r.POST("/freelancer/time-off", timeOffH.Create)
+	return r, db
+}
+
+func TestTimeOffHidesSlotsAndReportsBookings(t *testing.T) {
+	freelancerID := uuid.New()
+	router, db := setupTimeOffRouter(t, freelancerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	day := time.Now().UTC().AddDate(0, 0, 3).Truncate(24 * time.Hour)
+	free := seedSlot(t, db, offer.ID, day.Add(9*time.Hour))
+	booked := seedSlot(t, db, offer.ID, day.Add(11*time.Hour))
+	after := seedSlot(t, db, offer.ID, day.AddDate(0, 0, 2).Add(9*time.Hour))
+	assert.NoError(t, db.Model(booked).Updates(map[string]any{"booked_count": 1, "is_booked": true}).Error)
+	booking := &models.Booking{OfferID: offer.ID, SlotID: booked.ID, OwnerID: uuid.New(), Status: models.BookingStatusPending}
+	assert.NoError(t, db.Create(booking).Error)
+
+	body, _ := json.Marshal(map[string]string{
+		"starts_at": day.Format(time.RFC3339),
+		"ends_at":   day.AddDate(0, 0, 1).Format(time.RFC3339),
+		"reason":    "holiday",
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/freelancer/time-off", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result service.TimeOffResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Conflicts, 1)
+	assert.Equal(t, booking.ID, result.Conflicts[0].Booking.ID)
+
+	query := "?available=false&from=" + day.Format(time.RFC3339) + "&to=" + day.AddDate(0, 0, 7).Format(time.RFC3339)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/offers/"+offer.ID.String()+"/slots"+query, nil))
+	var slots []models.AvailabilitySlot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &slots))
+	ids := []uuid.UUID{}
+	for _, s := range slots {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []uuid.UUID{booked.ID, after.ID}, ids)
+	assert.NotContains(t, ids, free.ID)
+
+	body, _ = json.Marshal(map[string]string{
+		"start_time": day.Add(15 * time.Hour).Format(time.RFC3339),
+		"end_time":   day.Add(16 * time.Hour).Format(time.RFC3339),
+	})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/offers/"+offer.ID.String()+"/slots", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
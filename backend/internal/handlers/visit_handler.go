@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type VisitHandler struct {
+	svc *service.VisitService
+}
+
+func NewVisitHandler(s *service.VisitService) *VisitHandler {
+	return &VisitHandler{svc: s}
+}
+
+type checklistReq struct {
+	Fed           bool `json:"fed"`
+	Watered       bool `json:"watered"`
+	Walked        bool `json:"walked"`
+	Medicated     bool `json:"medicated"`
+	LitterCleaned bool `json:"litter_cleaned"`
+	PlayedWith    bool `json:"played_with"`
+}
+
+type checkInReq struct {
+	Lat *float64 `json:"lat" binding:"required_with=Lng,omitempty,min=-90,max=90"`
+	Lng *float64 `json:"lng" binding:"required_with=Lat,omitempty,min=-180,max=180"`
+}
+
+type checkOutReq struct {
+	checkInReq
+	Notes     *string       `json:"notes"     binding:"omitempty,max=5000"`
+	Checklist *checklistReq `json:"checklist"`
+}
+
+type updateReportReq struct {
+	Notes     *string       `json:"notes"     binding:"omitempty,max=5000"`
+	Checklist *checklistReq `json:"checklist"`
+}
+
+// visitErrorStatus maps VisitService errors onto HTTP status codes.
+func visitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrVisitReportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrBookingNotConfirmed),
+		errors.Is(err, service.ErrTooEarlyToCheckIn),
+		errors.Is(err, service.ErrAlreadyCheckedIn),
+		errors.Is(err, service.ErrNotCheckedIn),
+		errors.Is(err, service.ErrAlreadyCheckedOut),
+		errors.Is(err, service.ErrReportClosed),
+		errors.Is(err, service.ErrTooManyPhotos):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidPhoto):
+		return http.StatusUnprocessableEntity
+	default:
+		return bookingErrorStatus(err)
+	}
+}
+
+// CheckIn handles POST /bookings/:id/check-in
+func (h *VisitHandler) CheckIn(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req checkInReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.CheckIn(c.Request.Context(), id, userID, req.coordinates())
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckOut handles POST /bookings/:id/check-out. It completes the booking.
+func (h *VisitHandler) CheckOut(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req checkOutReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	upd := service.ReportUpdate{Notes: req.Notes, Checklist: req.Checklist.toModel()}
+	report, err := h.svc.CheckOut(c.Request.Context(), id, userID, req.coordinates(), upd)
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Get handles GET /bookings/:id/report
+func (h *VisitHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	report, err := h.svc.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Update handles PUT /bookings/:id/report
+func (h *VisitHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	var req updateReportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	upd := service.ReportUpdate{Notes: req.Notes, Checklist: req.Checklist.toModel()}
+	report, err := h.svc.UpdateReport(c.Request.Context(), id, userID, upd)
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// AddPhoto handles POST /bookings/:id/report/photos with the image in the
+// multipart "photo" field. Bodies much larger than a photo may be are cut off
+// before they are buffered.
+func (h *VisitHandler) AddPhoto(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxVisitPhotoSize+1<<10)
+	file, err := c.FormFile("photo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo is required"})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, service.MaxVisitPhotoSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	photo, err := h.svc.AddPhoto(c.Request.Context(), id, userID, data)
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, photo)
+}
+
+// Photo handles GET /bookings/:id/report/photos/:photo_id
+func (h *VisitHandler) Photo(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+	photoID, err := uuid.Parse(c.Param("photo_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	photo, path, err := h.svc.Photo(c.Request.Context(), id, photoID, userID)
+	if err != nil {
+		c.JSON(visitErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", photo.ContentType)
+	c.File(path)
+}
+
+func (r checkInReq) coordinates() *service.Coordinates {
+	if r.Lat == nil || r.Lng == nil {
+		return nil
+	}
+	return &service.Coordinates{Lat: *r.Lat, Lng: *r.Lng}
+}
+
+func (r *checklistReq) toModel() *models.VisitChecklist {
+	if r == nil {
+		return nil
+	}
+	return &models.VisitChecklist{
+		Fed:           r.Fed,
+		Watered:       r.Watered,
+		Walked:        r.Walked,
+		Medicated:     r.Medicated,
+		LitterCleaned: r.LitterCleaned,
+		PlayedWith:    r.PlayedWith,
+	}
+}
@@ -0,0 +1,152 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisitCheckOutCompletesBooking(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Minute))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	// a pending booking cannot be visited yet
+	w = sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+
+	w = sendAs(router, http.MethodPost, path+"/check-in", ownerID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendAs(router, http.MethodPost, path+"/check-in", freelancerID, map[string]float64{"lat": 52.52})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendAs(router, http.MethodPost, path+"/check-in", freelancerID, map[string]float64{"lat": 52.52, "lng": 13.40})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// attach a photo
+	var img bytes.Buffer
+	assert.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("photo", "rex.png")
+	part.Write(img.Bytes())
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path+"/report/photos", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-User-ID", freelancerID.String())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var photo models.VisitPhoto
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &photo))
+	assert.Equal(t, "image/png", photo.ContentType)
+
+	// an upload past the size limit is refused before it is read in full
+	form.Reset()
+	mw = multipart.NewWriter(&form)
+	part, _ = mw.CreateFormFile("photo", "huge.png")
+	part.Write(img.Bytes())
+	part.Write(make([]byte, service.MaxVisitPhotoSize+2<<10))
+	mw.Close()
+	req = httptest.NewRequest(http.MethodPost, path+"/report/photos", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-User-ID", freelancerID.String())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = sendAs(router, http.MethodPost, path+"/check-out", freelancerID, map[string]any{
+		"notes":     "Rex was a good boy",
+		"checklist": map[string]bool{"fed": true, "walked": true},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report models.VisitReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.NotNil(t, report.CheckedOutAt)
+	assert.Equal(t, 52.52, *report.CheckInLat)
+	assert.Len(t, report.Photos, 1)
+
+	var completed models.Booking
+	assert.NoError(t, db.First(&completed, "id = ?", booking.ID).Error)
+	assert.Equal(t, models.BookingStatusCompleted, completed.Status)
+	assert.NotNil(t, completed.CompletedAt)
+
+	var activity models.Activity
+	assert.NoError(t, db.Where("user_id = ? AND title = ?", ownerID, "Visit completed").First(&activity).Error)
+	assert.Contains(t, activity.Message, "Done: fed, walked.")
+	assert.Contains(t, activity.Message, "1 photo(s) attached.")
+
+	// the owner sees the report and its photo
+	w = sendAs(router, http.MethodGet, path+"/report", ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendAs(router, http.MethodGet, path+"/report/photos/"+photo.ID.String(), ownerID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, img.Bytes(), w.Body.Bytes())
+	w = sendAs(router, http.MethodGet, path+"/report", uuid.New(), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCancelledVisitCannotBeCompleted(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Minute))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/cancel", ownerID, nil).Code)
+
+	assert.Equal(t, http.StatusConflict, sendAs(router, http.MethodPost, path+"/check-out", freelancerID, nil).Code)
+	var cancelled models.Booking
+	assert.NoError(t, db.First(&cancelled, "id = ?", booking.ID).Error)
+	assert.Equal(t, models.BookingStatusCancelled, cancelled.Status)
+	assert.Nil(t, cancelled.CompletedAt)
+}
+
+func TestCheckedInBookingIsNotANoShow(t *testing.T) {
+	ownerID, freelancerID := uuid.New(), uuid.New()
+	router, db := setupBookingRouter(t, ownerID)
+
+	offer := seedOffer(t, db, freelancerID, true)
+	slot := seedSlot(t, db, offer.ID, time.Now().Add(30*time.Minute))
+	w := postBooking(router, offer.ID, slot.ID)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var booking models.Booking
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &booking))
+	path := "/bookings/" + booking.ID.String()
+
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/confirm", freelancerID, nil).Code)
+	assert.Equal(t, http.StatusOK, sendAs(router, http.MethodPost, path+"/check-in", freelancerID, nil).Code)
+	assert.NoError(t, db.Model(slot).Update("start_time", time.Now().Add(-10*time.Minute)).Error)
+
+	assert.Equal(t, http.StatusConflict, sendAs(router, http.MethodPost, path+"/no-show", freelancerID, nil).Code)
+	var events int64
+	assert.NoError(t, db.Model(&models.ReliabilityEvent{}).Where("user_id = ?", ownerID).Count(&events).Error)
+	assert.Zero(t, events)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/service"
+)
+
+type WaitlistHandler struct {
+	svc *service.WaitlistService
+}
+
+func NewWaitlistHandler(s *service.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{svc: s}
+}
+
+// Join handles POST /slots/:slot_id/waitlist
+func (h *WaitlistHandler) Join(c *gin.Context) {
+	slotID, ownerID, ok := waitlistParams(c)
+	if !ok {
+		return
+	}
+	entry, err := h.svc.Join(c.Request.Context(), slotID, ownerID)
+	if err != nil {
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// Get handles GET /slots/:slot_id/waitlist and reports the caller's position.
+func (h *WaitlistHandler) Get(c *gin.Context) {
+	slotID, ownerID, ok := waitlistParams(c)
+	if !ok {
+		return
+	}
+	entry, err := h.svc.Get(c.Request.Context(), slotID, ownerID)
+	if err != nil {
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// Leave handles DELETE /slots/:slot_id/waitlist
+func (h *WaitlistHandler) Leave(c *gin.Context) {
+	slotID, ownerID, ok := waitlistParams(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Leave(c.Request.Context(), slotID, ownerID); err != nil {
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Claim handles POST /slots/:slot_id/waitlist/claim
+func (h *WaitlistHandler) Claim(c *gin.Context) {
+	slotID, ownerID, ok := waitlistParams(c)
+	if !ok {
+		return
+	}
+	booking, err := h.svc.Claim(c.Request.Context(), slotID, ownerID)
+	if err != nil {
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, booking)
+}
+
+func waitlistParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	slotID, err := uuid.Parse(c.Param("slot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slot_id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	ownerID, ok := currentUserID(c)
+	return slotID, ownerID, ok
+}
+
+func waitlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotWaitlisted):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrSlotHasSeats),
+		errors.Is(err, service.ErrAlreadyWaitlisted),
+		errors.Is(err, service.ErrNoWaitlistOffer):
+		return http.StatusConflict
+	default:
+		return bookingErrorStatus(err)
+	}
+}
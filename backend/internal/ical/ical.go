@@ -0,0 +1,162 @@
+// Package ical writes and reads the subset of iCalendar (RFC 5545) needed to
+// publish bookings and availability as calendar feeds.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	maxLineLen  = 75
+)
+
+// Event is a VEVENT. UID must stay the same for the lifetime of the thing it
+// describes; Sequence goes up whenever its time or status changes.
+type Event struct {
+	UID          string
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	Transparent  bool
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Calendar is a VCALENDAR whose events are written in Location, with the
+// matching VTIMEZONE. A nil or UTC Location writes UTC times.
+type Calendar struct {
+	ProdID   string
+	Name     string
+	Location *time.Location
+	Events   []Event
+}
+
+// Encode renders the calendar as a text/calendar document.
+func (c *Calendar) Encode() []byte {
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo writes the calendar to w with CRLF line endings and folded lines.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: w}
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + c.ProdID)
+	e.line("CALSCALE:GREGORIAN")
+	e.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if loc != time.UTC && len(c.Events) > 0 {
+		from, to := c.Events[0].Start, c.Events[0].End
+		for _, ev := range c.Events {
+			if ev.Start.Before(from) {
+				from = ev.Start
+			}
+			if ev.End.After(to) {
+				to = ev.End
+			}
+		}
+		writeTimezone(e, loc, from, to)
+		e.line("X-WR-TIMEZONE:" + loc.String())
+	}
+
+	for _, ev := range c.Events {
+		e.line("BEGIN:VEVENT")
+		e.line("UID:" + ev.UID)
+		e.line("DTSTAMP:" + ev.LastModified.UTC().Format(utcLayout))
+		e.line(timeProp("DTSTART", ev.Start, loc))
+		e.line(timeProp("DTEND", ev.End, loc))
+		e.line(fmt.Sprintf("SEQUENCE:%d", ev.Sequence))
+		e.line("SUMMARY:" + escape(ev.Summary))
+		if ev.Description != "" {
+			e.line("DESCRIPTION:" + escape(ev.Description))
+		}
+		if ev.Status != "" {
+			e.line("STATUS:" + ev.Status)
+		}
+		if ev.Transparent {
+			e.line("TRANSP:TRANSPARENT")
+		} else {
+			e.line("TRANSP:OPAQUE")
+		}
+		if !ev.Created.IsZero() {
+			e.line("CREATED:" + ev.Created.UTC().Format(utcLayout))
+		}
+		e.line("LAST-MODIFIED:" + ev.LastModified.UTC().Format(utcLayout))
+		e.line("END:VEVENT")
+	}
+	e.line("END:VCALENDAR")
+	return e.n, e.err
+}
+
+func timeProp(name string, t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+// escape quotes TEXT values as RFC 5545 section 3.3.11 requires.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+type encoder struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// line writes one content line, folding it after 75 octets without
+// splitting a UTF-8 sequence.
+func (e *encoder) line(s string) {
+	var out strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > maxLineLen {
+			out.WriteString("\r\n ")
+			width = 1
+		}
+		out.WriteRune(r)
+		width += size
+	}
+	out.WriteString("\r\n")
+	e.write(out.String())
+}
+
+func (e *encoder) write(s string) {
+	if e.err != nil {
+		return
+	}
+	n, err := io.WriteString(e.w, s)
+	e.n += int64(n)
+	e.err = err
+}
@@ -0,0 +1,69 @@
+package ical
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeFoldsEscapesAndAddsTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	start := time.Date(2030, 7, 1, 8, 0, 0, 0, time.UTC)
+
+	cal := &Calendar{
+		ProdID:   "-//test//EN",
+		Location: berlin,
+		Events: []Event{{
+			UID:          "booking-1@test",
+			Sequence:     2,
+			Start:        start,
+			End:          start.Add(time.Hour),
+			Summary:      "Walk; Rex, Bella",
+			Description:  strings.Repeat("long description ", 10),
+			Status:       StatusCancelled,
+			LastModified: start,
+		}},
+	}
+	out := string(cal.Encode())
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Contains(t, out, `SUMMARY:Walk\; Rex\, Bella`+"\r\n")
+	assert.Contains(t, out, "DTSTART;TZID=Europe/Berlin:20300701T100000\r\n")
+	assert.Contains(t, out, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, out, "SEQUENCE:2\r\n")
+	// the summer time switch of 2030 happens on March 31 at 02:00 local time
+	assert.Contains(t, out, "BEGIN:DAYLIGHT\r\nDTSTART:20300331T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n")
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+}
+
+func TestParseExpandsRecurringEvents(t *testing.T) {
+	f, err := os.Open("testdata/personal.ics")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	events, errs := Parse(f, time.UTC)
+	assert.Len(t, errs, 2) // the malformed DTSTART and the HOURLY rule
+	assert.Len(t, events, 5)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	starts := map[string][]string{}
+	for _, ev := range events {
+		for _, occ := range ev.Occurrences(from, to) {
+			starts[ev.Summary] = append(starts[ev.Summary], occ.Start.In(berlin).Format("Jan 2 15:04"))
+		}
+	}
+
+	assert.Equal(t, []string{"Jan 7 09:00", "Jan 16 09:00", "Jan 21 09:00", "Jan 23 09:00"}, starts["Yoga, weekly"])
+	assert.Equal(t, []string{"Jan 14 18:00"}, starts["Yoga moved"])
+	assert.Equal(t, []string{"Feb 1 01:00"}, starts["Public holiday"])
+	assert.Len(t, starts["Vet appointment with a description long enough to be folded over two lines"], 4)
+	assert.NotContains(t, starts, "Reminder")
+}
@@ -0,0 +1,359 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// VEvent is an event read from someone else's calendar. Only what is needed
+// to know when its owner is busy is kept.
+type VEvent struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+	Rule    *Rule
+	ExDates []time.Time
+}
+
+// ParseError points at the content line a problem was found on.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type contentLine struct {
+	num    int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the busy VEVENTs of an iCalendar document. Cancelled and
+// transparent (free) events are skipped. Floating times are read in loc.
+// Problems with single events are returned alongside the events that could
+// be read; the events concerned are dropped or, for unsupported recurrence
+// rules, kept as a single occurrence.
+func Parse(r io.Reader, loc *time.Location) ([]VEvent, []error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if len(lines) == 0 || lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR") {
+		return nil, []error{&ParseError{Line: 1, Msg: "not an iCalendar file"}}
+	}
+
+	var (
+		events    []VEvent
+		errs      []error
+		overrides = make(map[string][]time.Time)
+		cur       []contentLine
+		inEvent   bool
+		depth     int
+	)
+	for _, l := range lines {
+		switch {
+		case l.name == "BEGIN" && strings.EqualFold(l.value, "VEVENT") && !inEvent:
+			inEvent, depth, cur = true, 0, nil
+		case inEvent && l.name == "BEGIN":
+			depth++ // VALARM and friends
+		case inEvent && l.name == "END" && depth > 0:
+			depth--
+		case inEvent && l.name == "END" && strings.EqualFold(l.value, "VEVENT"):
+			inEvent = false
+			ev, recurrenceID, keep, evErrs := buildEvent(cur, loc)
+			errs = append(errs, evErrs...)
+			if !recurrenceID.IsZero() {
+				overrides[ev.UID] = append(overrides[ev.UID], recurrenceID)
+			}
+			if keep {
+				events = append(events, ev)
+			}
+		case inEvent && depth == 0:
+			cur = append(cur, l)
+		}
+	}
+
+	// a modified occurrence replaces the one the master rule would produce
+	for i := range events {
+		if events[i].Rule != nil {
+			events[i].ExDates = append(events[i].ExDates, overrides[events[i].UID]...)
+		}
+	}
+	return events, errs
+}
+
+// buildEvent turns the lines of one VEVENT into an event. keep is false for
+// events that are free, cancelled or unreadable.
+func buildEvent(lines []contentLine, loc *time.Location) (ev VEvent, recurrenceID time.Time, keep bool, errs []error) {
+	var (
+		duration time.Duration
+		hasEnd   bool
+		first    = 0
+	)
+	if len(lines) > 0 {
+		first = lines[0].num
+	}
+	fail := func(l contentLine, format string, args ...any) {
+		errs = append(errs, &ParseError{Line: l.num, Msg: fmt.Sprintf(format, args...)})
+	}
+	keep = true
+
+	for _, l := range lines {
+		switch l.name {
+		case "UID":
+			ev.UID = l.value
+		case "SUMMARY":
+			ev.Summary = unescape(l.value)
+		case "STATUS":
+			if strings.EqualFold(l.value, StatusCancelled) {
+				keep = false
+			}
+		case "TRANSP":
+			if strings.EqualFold(l.value, "TRANSPARENT") {
+				keep = false
+			}
+		case "DTSTART":
+			t, allDay, err := parseTime(l, loc)
+			if err != nil {
+				fail(l, "DTSTART: %v", err)
+				return ev, recurrenceID, false, errs
+			}
+			ev.Start, ev.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := parseTime(l, loc)
+			if err != nil {
+				fail(l, "DTEND: %v", err)
+				return ev, recurrenceID, false, errs
+			}
+			ev.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(l.value)
+			if err != nil {
+				fail(l, "DURATION: %v", err)
+				return ev, recurrenceID, false, errs
+			}
+			duration = d
+		case "RRULE":
+			rule, err := parseRule(l.value, loc)
+			if err != nil {
+				fail(l, "RRULE: %v; only the first occurrence is used", err)
+				continue
+			}
+			ev.Rule = rule
+		case "EXDATE":
+			for _, v := range strings.Split(l.value, ",") {
+				t, _, err := parseTime(contentLine{params: l.params, value: v}, loc)
+				if err != nil {
+					fail(l, "EXDATE: %v", err)
+					continue
+				}
+				ev.ExDates = append(ev.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			if t, _, err := parseTime(l, loc); err == nil {
+				recurrenceID = t
+			}
+		}
+	}
+
+	if ev.Start.IsZero() {
+		errs = append(errs, &ParseError{Line: first, Msg: "VEVENT without DTSTART"})
+		return ev, recurrenceID, false, errs
+	}
+	switch {
+	case hasEnd:
+	case duration > 0:
+		ev.End = ev.Start.Add(duration)
+	case ev.AllDay:
+		ev.End = ev.Start.AddDate(0, 0, 1)
+	default:
+		ev.End = ev.Start
+	}
+	if !ev.End.After(ev.Start) {
+		// zero-length events do not block anything
+		keep = false
+	}
+	return ev, recurrenceID, keep, errs
+}
+
+// unfold splits a document into content lines, joining folded ones.
+func unfold(r io.Reader) ([]contentLine, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		raw  []string
+		nums []int
+		n    int
+	)
+	for sc.Scan() {
+		n++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if len(text) > 0 && (text[0] == ' ' || text[0] == '\t') && len(raw) > 0 {
+			raw[len(raw)-1] += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		raw = append(raw, text)
+		nums = append(nums, n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]contentLine, 0, len(raw))
+	for i, text := range raw {
+		l, ok := splitLine(text)
+		if !ok {
+			continue
+		}
+		l.num = nums[i]
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// splitLine parses NAME;PARAM=VALUE:VALUE, honouring quoted parameters.
+func splitLine(text string) (contentLine, bool) {
+	inQuote := false
+	colon := -1
+	for i, r := range text {
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return contentLine{}, false
+	}
+	head := strings.Split(text[:colon], ";")
+	l := contentLine{
+		name:   strings.ToUpper(head[0]),
+		params: make(map[string]string, len(head)-1),
+		value:  text[colon+1:],
+	}
+	for _, p := range head[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			l.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return l, true
+}
+
+// parseTime reads a DATE or DATE-TIME value, in UTC, its TZID or loc.
+func parseTime(l contentLine, loc *time.Location) (time.Time, bool, error) {
+	v := strings.TrimSpace(l.value)
+	if l.params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(utcLayout, v)
+		return t, false, err
+	}
+	if tzid := l.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		} else if name, ok := windowsZones[tzid]; ok {
+			if tz, err := time.LoadLocation(name); err == nil {
+				loc = tz
+			}
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, v, loc)
+	return t, false, err
+}
+
+// windowsZones maps the zone names Outlook writes to IANA names.
+var windowsZones = map[string]string{
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Romance Standard Time":          "Europe/Paris",
+	"GMT Standard Time":              "Europe/London",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Russian Standard Time":          "Europe/Moscow",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"UTC":                            "UTC",
+	"Coordinated Universal Time":     "UTC",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Central European Standard Time": "Europe/Warsaw",
+}
+
+// parseDuration reads an RFC 5545 DURATION such as P1DT2H or -PT15M.
+func parseDuration(v string) (time.Duration, error) {
+	s := strings.TrimSpace(v)
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	s = s[1:]
+
+	var (
+		d      time.Duration
+		num    int
+		digits bool
+		inTime bool
+	)
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num = num*10 + int(r-'0')
+			digits = true
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		switch {
+		case r == 'W' && !inTime:
+			d += time.Duration(num) * 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			d += time.Duration(num) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(num) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(num) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(num) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		num, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return sign * d, nil
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,267 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods bounds how many days, weeks, months or years a rule is walked
+// through, so a daily rule from decades ago cannot stall a sync.
+const maxPeriods = 20000
+
+// Rule is the supported subset of an RRULE: DAILY, WEEKLY, MONTHLY and
+// YEARLY frequencies with INTERVAL, COUNT, UNTIL, BYDAY and BYMONTHDAY.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+}
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is 0 for every
+// such weekday in the period.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRule(v string, loc *time.Location) (*Rule, error) {
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		k, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("INTERVAL must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.Until, _, err = parseTime(contentLine{value: val}, loc)
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wn, perr := parseWeekdayNum(d)
+				if perr != nil {
+					return nil, perr
+				}
+				r.ByDay = append(r.ByDay, wn)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, perr := strconv.Atoi(d)
+				if perr != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			// weeks are taken to start on Monday
+		default:
+			return nil, fmt.Errorf("%s is not supported", strings.ToUpper(k))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", k, val)
+		}
+	}
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, fmt.Errorf("FREQ is missing")
+	default:
+		return nil, fmt.Errorf("FREQ=%s is not supported", r.Freq)
+	}
+	return r, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	n := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+	}
+	return WeekdayNum{N: n, Day: day}, nil
+}
+
+// Interval is one busy stretch of time.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Occurrences returns the event's instances overlapping [from, to).
+func (ev *VEvent) Occurrences(from, to time.Time) []Interval {
+	length := ev.End.Sub(ev.Start)
+	overlaps := func(start time.Time) bool {
+		return start.Before(to) && start.Add(length).After(from)
+	}
+	if ev.Rule == nil {
+		if overlaps(ev.Start) {
+			return []Interval{{ev.Start, ev.End}}
+		}
+		return nil
+	}
+
+	excluded := make(map[int64]bool, len(ev.ExDates))
+	for _, t := range ev.ExDates {
+		excluded[t.Unix()] = true
+	}
+
+	var (
+		out  []Interval
+		seen int
+		r    = ev.Rule
+	)
+	for period := 0; period < maxPeriods; period++ {
+		for _, start := range r.candidates(ev.Start, period) {
+			if start.Before(ev.Start) {
+				continue
+			}
+			if (!r.Until.IsZero() && start.After(r.Until)) || !start.Before(to) {
+				return out
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return out
+			}
+			if !excluded[start.Unix()] && overlaps(start) {
+				out = append(out, Interval{start, start.Add(length)})
+			}
+		}
+	}
+	return out
+}
+
+// candidates lists, in order, the instance starts the rule yields in the
+// given period (day, week, month or year) counted from dtstart.
+func (r *Rule) candidates(dtstart time.Time, period int) []time.Time {
+	y, m, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+	step := period * r.Interval
+
+	switch r.Freq {
+	case "DAILY":
+		t := at(y, m, d+step)
+		if len(r.ByDay) > 0 && !r.hasWeekday(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+
+	case "WEEKLY":
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(y, m, d+7*step)}
+		}
+		// the Monday of dtstart's week, moved on by the period
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := d - offset + 7*step
+		var out []time.Time
+		for i := 0; i < 7; i++ {
+			t := at(y, m, monday+i)
+			if r.hasWeekday(t.Weekday()) {
+				out = append(out, t)
+			}
+		}
+		return out
+
+	case "MONTHLY":
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		return r.monthDays(first, d, at)
+
+	case "YEARLY":
+		t := at(y+step, m, d)
+		if t.Day() != d {
+			return nil // Feb 29 in a common year
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
+
+// monthDays lists the matching days of the month starting at first.
+func (r *Rule) monthDays(first time.Time, dtDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	y, m := first.Year(), first.Month()
+	days := first.AddDate(0, 1, -1).Day()
+	var picked []int
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			if n < 0 {
+				n = days + n + 1
+			}
+			if n >= 1 && n <= days {
+				picked = append(picked, n)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wn := range r.ByDay {
+			var matches []int
+			for day := 1; day <= days; day++ {
+				if time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Weekday() == wn.Day {
+					matches = append(matches, day)
+				}
+			}
+			switch {
+			case wn.N == 0:
+				picked = append(picked, matches...)
+			case wn.N > 0 && wn.N <= len(matches):
+				picked = append(picked, matches[wn.N-1])
+			case wn.N < 0 && -wn.N <= len(matches):
+				picked = append(picked, matches[len(matches)+wn.N])
+			}
+		}
+	default:
+		if dtDay <= days {
+			picked = append(picked, dtDay)
+		}
+	}
+
+	sort.Ints(picked)
+	out := make([]time.Time, 0, len(picked))
+	for i, day := range picked {
+		if i > 0 && picked[i-1] == day {
+			continue
+		}
+		out = append(out, at(y, m, day))
+	}
+	return out
+}
+
+func (r *Rule) hasWeekday(day time.Weekday) bool {
+	for _, wn := range r.ByDay {
+		if wn.Day == day {
+			return true
+		}
+	}
+	return false
+}
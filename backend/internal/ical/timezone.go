@@ -0,0 +1,69 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// writeTimezone writes a VTIMEZONE for loc listing every offset change from
+// a year before from to a year after to, read from the Go zone database.
+func writeTimezone(e *encoder, loc *time.Location, from, to time.Time) {
+	from = from.AddDate(-1, 0, 0)
+	to = to.AddDate(1, 0, 0)
+
+	e.line("BEGIN:VTIMEZONE")
+	e.line("TZID:" + loc.String())
+
+	// the offset in force at the start of the window
+	first := from.In(loc)
+	_, offset := first.Zone()
+	writeObservance(e, first, offset, offset)
+
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, before := day.In(loc).Zone()
+		_, after := next.In(loc).Zone()
+		if before == after {
+			continue
+		}
+		// narrow the change down to the second
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		writeObservance(e, hi.In(loc), before, after)
+	}
+	e.line("END:VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT block for the offset that
+// starts at at. DTSTART is the local time under the previous offset.
+func writeObservance(e *encoder, at time.Time, from, to int) {
+	kind := "STANDARD"
+	if at.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, _ := at.Zone()
+	local := at.UTC().Add(time.Duration(from) * time.Second)
+
+	e.line("BEGIN:" + kind)
+	e.line("DTSTART:" + local.Format(localLayout))
+	e.line("TZOFFSETFROM:" + formatOffset(from))
+	e.line("TZOFFSETTO:" + formatOffset(to))
+	e.line("TZNAME:" + name)
+	e.line("END:" + kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds/60%60)
+}
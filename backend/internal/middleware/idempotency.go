@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+)
+
+const IdempotencyHeader = "Idempotency-Key"
+
+// Idempotency replays the stored response when a client repeats a request with
+// the same Idempotency-Key header. Keys are scoped to the authenticated user,
+// so it must run after JWT. Requests without the header pass straight through.
+// A key whose ttl has run out is used afresh; PurgeExpiredKeys clears the rest.
+func Idempotency(repo *repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "could not read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		userID := c.GetString("uid")
+		now := time.Now()
+		rec := &models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		existing, err := repo.Find(ctx, userID, key)
+		if err == nil && existing.ExpiresAt.Before(now) {
+			err = repo.Delete(ctx, existing.ID)
+			if err == nil {
+				err = gorm.ErrRecordNotFound
+			}
+		}
+		if err == nil {
+			replay(c, existing, rec.Fingerprint)
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		reserved, err := repo.Reserve(ctx, rec)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !reserved {
+			// Lost a race with a concurrent retry carrying the same key.
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is already in progress"})
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		// Server errors are not remembered so the client can retry them.
+		if w.Status() >= http.StatusInternalServerError {
+			if err := repo.Delete(ctx, rec.ID); err != nil {
+				log.Printf("idempotency: could not release key %q: %v", key, err)
+			}
+			return
+		}
+		rec.StatusCode = w.Status()
+		rec.ContentType = w.Header().Get("Content-Type")
+		rec.Response = w.body.Bytes()
+		if err := repo.Complete(ctx, rec); err != nil {
+			log.Printf("idempotency: could not store response for key %q: %v", key, err)
+		}
+	}
+}
+
+// PurgeExpiredKeys deletes the keys whose ttl has run out every tick until
+// ctx is done.
+func PurgeExpiredKeys(ctx context.Context, repo *repository.IdempotencyRepository, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := repo.DeleteExpired(ctx, now); err != nil {
+				log.Printf("idempotency: could not purge expired keys: %v", err)
+			}
+		}
+	}
+}
+
+// replay answers a repeated request from the stored record.
+func replay(c *gin.Context, rec *models.IdempotencyKey, fp string) {
+	if rec.Fingerprint != fp {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		return
+	}
+	if !rec.Completed {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is already in progress"})
+		return
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(rec.StatusCode, rec.ContentType, rec.Response)
+	c.Abort()
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capturingWriter tees the response body so it can be stored.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
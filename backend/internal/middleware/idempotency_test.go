@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/middleware"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIdempotentRouter(t *testing.T, status int, ttl time.Duration) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.IdempotencyKey{}))
+
+	calls := 0
+	r.Use(func(c *gin.Context) {
+		c.Set("uid", "user-1")
+		c.Next()
+	})
+	r.POST("/things", middleware.Idempotency(repository.NewIdempotencyRepository(db), ttl), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"call": calls})
+	})
+	return r, &calls
+}
+
+func post(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	r, calls := setupIdempotentRouter(t, http.StatusCreated, time.Hour)
+
+	first := post(r, "abc", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	second := post(r, "abc", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, *calls)
+
+	mismatch := post(r, "abc", `{"a":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotencyWithoutKeyOrOnServerError(t *testing.T) {
+	r, calls := setupIdempotentRouter(t, http.StatusCreated, time.Hour)
+	post(r, "", `{}`)
+	post(r, "", `{}`)
+	assert.Equal(t, 2, *calls)
+
+	r, calls = setupIdempotentRouter(t, http.StatusInternalServerError, time.Hour)
+	post(r, "abc", `{}`)
+	post(r, "abc", `{}`)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyKeyIsFreeOnceExpired(t *testing.T) {
+	r, calls := setupIdempotentRouter(t, http.StatusCreated, -time.Second)
+
+	assert.Equal(t, http.StatusCreated, post(r, "abc", `{"a":1}`).Code)
+	second := post(r, "abc", `{"a":2}`)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Empty(t, second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, *calls)
+}
@@ -34,3 +34,18 @@ func JWT(cfg *config.AppConfig) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireRole lets through only users whose token carries one of roles. It
+// must run after JWT.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	}
+}
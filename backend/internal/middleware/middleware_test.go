@@ -0,0 +1,31 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin", func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			c.Set("role", role)
+		}
+	}, middleware.RequireRole("admin"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for role, want := range map[string]int{"admin": http.StatusNoContent, "owner": http.StatusForbidden, "": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("X-Role", role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, role)
+	}
+}
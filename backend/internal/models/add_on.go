@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+// AddOn is an extra an owner can pick when booking an offer, such as a nail
+// trim. It costs Price on top of the offer and makes the visit ExtraMin
+// minutes longer, so the booked slot has to leave room for it.
+type AddOn struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Price       money.Money    `gorm:"column:price_minor;type:bigint;not null;default:0" json:"price"`
+	Currency    string         `gorm:"type:char(3);not null" json:"currency"`
+	ExtraMin    int            `gorm:"not null;default:0" json:"extraMin"`
+	IsActive    bool           `gorm:"not null;default:true" json:"isActive"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (a *AddOn) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the price the add-on's currency.
+func (a *AddOn) AfterFind(tx *gorm.DB) error {
+	a.Price.Currency = a.Currency
+	return nil
+}
@@ -4,18 +4,110 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"gorm.io/gorm"
 )
 
+const (
+	BookingStatusAwaitingPayment = "awaiting_payment"
+	BookingStatusPending         = "pending"
+	BookingStatusConfirmed       = "confirmed"
+	BookingStatusCancelled       = "cancelled"
+	BookingStatusCompleted       = "completed"
+	BookingStatusNoShow          = "no_show"
+)
+
+// ActiveBookingStatuses are the statuses in which a booking holds its slot.
+var ActiveBookingStatuses = []string{
+	BookingStatusAwaitingPayment,
+	BookingStatusPending,
+	BookingStatusConfirmed,
+}
+
+// Booking reserves either one AvailabilitySlot or, for stay-type offers, the
+// nights from CheckIn to CheckOut. Stay bookings hold no slot; their SlotID
+// is uuid.Nil.
+//
+// Price is the breakdown quoted when the booking was made and Total what it
+// came to. Bookings made before quotes existed have no Price. A booking paid
+// with a credit of CreditPackID is worth CreditValue to the freelancer on top
+// of what the owner paid. CouponID is the coupon redeemed on it. Pets is how
+// many pets the booking is for; slot capacity still counts bookings, not
+// pets.
 type Booking struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	OfferID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
-	SlotID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"slotId"`
-	OwnerID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"ownerId"`
-	Status    string         `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+	ID                 uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID            uuid.UUID       `gorm:"type:uuid;not null;index" json:"offerId"`
+	SlotID             uuid.UUID       `gorm:"type:uuid;not null;index" json:"slotId"`
+	CheckIn            *time.Time      `json:"checkIn,omitempty"`
+	CheckOut           *time.Time      `json:"checkOut,omitempty"`
+	Nights             int             `gorm:"not null;default:0" json:"nights,omitempty"`
+	OwnerID            uuid.UUID       `gorm:"type:uuid;not null;index" json:"ownerId"`
+	SeriesID           *uuid.UUID      `gorm:"type:uuid;index" json:"seriesId,omitempty"`
+	Status             string          `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	ProposedSlotID     *uuid.UUID      `gorm:"type:uuid" json:"proposedSlotId,omitempty"`
+	CancelledAt        *time.Time      `json:"cancelledAt,omitempty"`
+	CancelledBy        *uuid.UUID      `gorm:"type:uuid" json:"cancelledBy,omitempty"`
+	CancellationReason string          `gorm:"type:text" json:"cancellationReason,omitempty"`
+	RefundPercent      int             `gorm:"not null;default:0" json:"refundPercent"`
+	Currency           string          `gorm:"type:char(3);not null;default:''" json:"currency"`
+	Total              money.Money     `gorm:"column:total_minor;type:bigint;not null;default:0" json:"total"`
+	Price              *PriceBreakdown `gorm:"type:jsonb;serializer:json" json:"price,omitempty"`
+	AddOnIDs           []uuid.UUID     `gorm:"type:jsonb;serializer:json" json:"addOnIds,omitempty"`
+	Pets               int             `gorm:"not null;default:1" json:"pets"`
+	CreditPackID       *uuid.UUID      `gorm:"type:uuid;index" json:"creditPackId,omitempty"`
+	CreditValue        money.Money     `gorm:"column:credit_value_minor;type:bigint;not null;default:0" json:"creditValue"`
+	CouponID           *uuid.UUID      `gorm:"type:uuid;index" json:"couponId,omitempty"`
+	RefundAmount       money.Money     `gorm:"column:refund_amount_minor;type:bigint;not null;default:0" json:"refundAmount"`
+	DepositAmount      money.Money     `gorm:"column:deposit_amount_minor;type:bigint;not null;default:0" json:"depositAmount"`
+	DepositPaidAt      *time.Time      `json:"depositPaidAt,omitempty"`
+	ConfirmedAt        *time.Time      `json:"confirmedAt,omitempty"`
+	CompletedAt        *time.Time      `json:"completedAt,omitempty"`
+	Instructions       Instructions    `gorm:"type:jsonb;serializer:json" json:"instructions"`
+	SealedAccess       []byte          `json:"-"`
+	FreelancerNotes    string          `gorm:"type:text" json:"-"`
+	CreatedAt          time.Time       `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt          time.Time       `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt  `gorm:"index" json:"deletedAt,omitempty"`
+}
+
+// Instructions are the owner's notes for the visit, shown to the freelancer
+// as soon as the booking exists.
+type Instructions struct {
+	Feeding    string `json:"feeding,omitempty"`
+	Medication string `json:"medication,omitempty"`
+	Parking    string `json:"parking,omitempty"`
+	Other      string `json:"other,omitempty"`
+}
+
+// AccessDetails tell the freelancer how to get in. They are stored sealed in
+// Booking.SealedAccess and only revealed to the freelancer while the booking
+// is confirmed and for a day after it is completed.
+type AccessDetails struct {
+	DoorCode     string `json:"doorCode,omitempty"`
+	AlarmCode    string `json:"alarmCode,omitempty"`
+	KeyLocation  string `json:"keyLocation,omitempty"`
+	WifiPassword string `json:"wifiPassword,omitempty"`
+	Other        string `json:"other,omitempty"`
+}
+
+// IsStay reports whether the booking covers a range of nights rather than a slot.
+func (b *Booking) IsStay() bool {
+	return b.CheckIn != nil
+}
+
+// IsActive reports whether the booking still holds its slot.
+func (b *Booking) IsActive() bool {
+	for _, s := range ActiveBookingStatuses {
+		if b.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// DepositOutstanding reports whether a required deposit is still unpaid.
+func (b *Booking) DepositOutstanding() bool {
+	return b.DepositAmount.IsPositive() && b.DepositPaidAt == nil
 }
 
 func (b *Booking) BeforeCreate(tx *gorm.DB) error {
@@ -24,3 +116,12 @@ func (b *Booking) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// AfterFind gives the amounts the booking's currency, which is stored once.
+func (b *Booking) AfterFind(tx *gorm.DB) error {
+	b.Total.Currency = b.Currency
+	b.CreditValue.Currency = b.Currency
+	b.RefundAmount.Currency = b.Currency
+	b.DepositAmount.Currency = b.Currency
+	return nil
+}
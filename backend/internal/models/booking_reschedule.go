@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RescheduleProposed  = "proposed"
+	RescheduleApplied   = "applied"
+	RescheduleRejected  = "rejected"
+	RescheduleWithdrawn = "withdrawn"
+)
+
+// BookingReschedule records one move (or proposed move) of a booking from
+// one slot to another, forming the booking's slot history.
+type BookingReschedule struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	BookingID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"bookingId"`
+	FromSlotID  uuid.UUID  `gorm:"type:uuid;not null" json:"fromSlotId"`
+	ToSlotID    uuid.UUID  `gorm:"type:uuid;not null" json:"toSlotId"`
+	RequestedBy uuid.UUID  `gorm:"type:uuid;not null" json:"requestedBy"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	DecidedBy   *uuid.UUID `gorm:"type:uuid" json:"decidedBy,omitempty"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (r *BookingReschedule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
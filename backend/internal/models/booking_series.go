@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SeriesModeAllOrNothing = "all_or_nothing"
+	SeriesModeBestEffort   = "best_effort"
+
+	SeriesStatusActive    = "active"
+	SeriesStatusCancelled = "cancelled"
+)
+
+// BookingSeries groups the bookings created from one recurring request such
+// as "every Mon/Wed/Fri at 08:00 for 6 weeks".
+type BookingSeries struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
+	OwnerID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"ownerId"`
+	Weekdays  []int          `gorm:"type:jsonb;serializer:json;not null" json:"weekdays"`
+	TimeOfDay string         `gorm:"type:char(5);not null" json:"timeOfDay"`
+	Timezone  string         `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	StartDate time.Time      `gorm:"type:date;not null" json:"startDate"`
+	Weeks     int            `gorm:"not null" json:"weeks"`
+	Mode      string         `gorm:"type:varchar(20);not null" json:"mode"`
+	Status    string         `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+}
+
+func (s *BookingSeries) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarFeed holds the secret token that unlocks a user's .ics feeds.
+// Rotating the token revokes every subscription made with the old one.
+type CalendarFeed struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	Token     string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"token"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
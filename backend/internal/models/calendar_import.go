@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	CalendarImportPending = "pending"
+	CalendarImportSynced  = "synced"
+	CalendarImportFailed  = "failed"
+)
+
+// CalendarImport is an external calendar whose busy time blocks the
+// freelancer's open slots. It is either fetched from SourceURL on every sync
+// or was uploaded once and kept in Content.
+type CalendarImport struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	FreelancerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"freelancerId"`
+	SourceURL    string     `gorm:"type:varchar(2048)" json:"sourceUrl,omitempty"`
+	Content      string     `gorm:"type:text" json:"-"`
+	Status       string     `gorm:"type:varchar(20);not null" json:"status"`
+	LastError    string     `gorm:"type:text" json:"lastError,omitempty"`
+	ParseErrors  []string   `gorm:"type:jsonb;serializer:json" json:"parseErrors"`
+	EventCount   int        `gorm:"not null;default:0" json:"eventCount"`
+	BlockedSlots int        `gorm:"not null;default:0" json:"blockedSlots"`
+	LastSyncedAt *time.Time `json:"lastSyncedAt,omitempty"`
+	NextSyncAt   time.Time  `gorm:"not null;index" json:"nextSyncAt"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (c *CalendarImport) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUpload reports whether the calendar was uploaded rather than linked.
+func (c *CalendarImport) IsUpload() bool {
+	return c.SourceURL == ""
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"sort"
+)
+
+const (
+	CancellationFlexible = "flexible"
+	CancellationModerate = "moderate"
+	CancellationStrict   = "strict"
+	CancellationCustom   = "custom"
+)
+
+var ErrInvalidRefundTiers = errors.New("refund tiers need a non-negative notice and a refund between 0 and 100 percent")
+
+// RefundTier grants RefundPercent of the booking price when the booking is
+// cancelled at least MinHoursBefore hours before the slot starts.
+type RefundTier struct {
+	MinHoursBefore int `json:"minHoursBefore"`
+	RefundPercent  int `json:"refundPercent"`
+}
+
+var presetRefundTiers = map[string][]RefundTier{
+	CancellationFlexible: {{MinHoursBefore: 24, RefundPercent: 100}},
+	CancellationModerate: {{MinHoursBefore: 48, RefundPercent: 100}, {MinHoursBefore: 24, RefundPercent: 50}},
+	CancellationStrict:   {{MinHoursBefore: 168, RefundPercent: 50}},
+}
+
+// ValidateRefundTiers rejects tiers with negative notice or out of range refunds.
+func ValidateRefundTiers(tiers []RefundTier) error {
+	if len(tiers) == 0 {
+		return ErrInvalidRefundTiers
+	}
+	for _, t := range tiers {
+		if t.MinHoursBefore < 0 || t.RefundPercent < 0 || t.RefundPercent > 100 {
+			return ErrInvalidRefundTiers
+		}
+	}
+	return nil
+}
+
+// RefundTiers returns the tiers in effect for the offer, longest notice first.
+// Unknown policies fall back to flexible.
+func (o *ServiceOffer) RefundTiers() []RefundTier {
+	var tiers []RefundTier
+	if o.CancellationPolicy == CancellationCustom {
+		tiers = append(tiers, o.CancellationTiers...)
+	} else if preset, ok := presetRefundTiers[o.CancellationPolicy]; ok {
+		tiers = append(tiers, preset...)
+	} else {
+		tiers = append(tiers, presetRefundTiers[CancellationFlexible]...)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinHoursBefore > tiers[j].MinHoursBefore })
+	return tiers
+}
+
+// RefundPercent returns the share of the price refunded when cancelling
+// hoursBefore hours ahead of the start. Late cancellations get nothing.
+func (o *ServiceOffer) RefundPercent(hoursBefore float64) int {
+	for _, t := range o.RefundTiers() {
+		if hoursBefore >= float64(t.MinHoursBefore) {
+			return t.RefundPercent
+		}
+	}
+	return 0
+}
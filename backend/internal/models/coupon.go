@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	CouponPercent = "percent"
+	CouponFixed   = "fixed"
+)
+
+// Coupon is a promotional code owners enter when booking. It takes Percent
+// percent, or Amount, off the booking's subtotal, never more than the
+// subtotal itself.
+//
+// Currency, when set, limits the coupon to offers priced in it; fixed
+// coupons and coupons with a MinSpend always have one. ServiceIDs, OfferIDs
+// and FreelancerIDs, when not empty, limit it to those services, offers or
+// freelancers. MaxUses and MaxUsesPerUser of zero mean no limit; Uses counts
+// the bookings that hold a redemption. Coupons are deactivated rather than
+// deleted, so their codes are never reused.
+type Coupon struct {
+	ID               uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	Code             string      `gorm:"type:varchar(40);not null;uniqueIndex" json:"code"`
+	Description      string      `gorm:"type:text" json:"description,omitempty"`
+	Kind             string      `gorm:"type:varchar(10);not null" json:"kind"`
+	Percent          int         `gorm:"not null;default:0" json:"percent,omitempty"`
+	Amount           money.Money `gorm:"column:amount_minor;type:bigint;not null;default:0" json:"amount"`
+	Currency         string      `gorm:"type:char(3);not null;default:''" json:"currency,omitempty"`
+	MinSpend         money.Money `gorm:"column:min_spend_minor;type:bigint;not null;default:0" json:"minSpend"`
+	ValidFrom        *time.Time  `json:"validFrom,omitempty"`
+	ValidUntil       *time.Time  `json:"validUntil,omitempty"`
+	MaxUses          int         `gorm:"not null;default:0" json:"maxUses"`
+	MaxUsesPerUser   int         `gorm:"not null;default:0" json:"maxUsesPerUser"`
+	Uses             int         `gorm:"not null;default:0" json:"uses"`
+	ServiceIDs       []uuid.UUID `gorm:"type:jsonb;serializer:json" json:"serviceIds,omitempty"`
+	OfferIDs         []uuid.UUID `gorm:"type:jsonb;serializer:json" json:"offerIds,omitempty"`
+	FreelancerIDs    []uuid.UUID `gorm:"type:jsonb;serializer:json" json:"freelancerIds,omitempty"`
+	FirstBookingOnly bool        `gorm:"not null;default:false" json:"firstBookingOnly"`
+	IsActive         bool        `gorm:"not null;default:true" json:"isActive"`
+	CreatedAt        time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time   `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the amounts the coupon's currency.
+func (c *Coupon) AfterFind(tx *gorm.DB) error {
+	c.Amount.Currency = c.Currency
+	c.MinSpend.Currency = c.Currency
+	return nil
+}
+
+// Live reports whether the coupon can be redeemed at now, leaving aside who
+// uses it and on what.
+func (c *Coupon) Live(now time.Time) bool {
+	if !c.IsActive || c.MaxUses > 0 && c.Uses >= c.MaxUses {
+		return false
+	}
+	if c.ValidFrom != nil && now.Before(*c.ValidFrom) {
+		return false
+	}
+	return c.ValidUntil == nil || now.Before(*c.ValidUntil)
+}
+
+// Covers reports whether the coupon's restrictions allow it on the offer.
+func (c *Coupon) Covers(offer *ServiceOffer) bool {
+	if c.Currency != "" && c.Currency != offer.Currency {
+		return false
+	}
+	return containsID(c.ServiceIDs, offer.ServiceID) &&
+		containsID(c.OfferIDs, offer.ID) &&
+		containsID(c.FreelancerIDs, offer.FreelancerID)
+}
+
+// Discount is what the coupon takes off subtotal, as a positive amount.
+func (c *Coupon) Discount(subtotal money.Money) money.Money {
+	d := c.Amount
+	if c.Kind == CouponPercent {
+		d = subtotal.Percent(c.Percent)
+	}
+	if d.Amount > subtotal.Amount {
+		return subtotal
+	}
+	return d
+}
+
+// CouponRedemption records a booking made with a coupon. It is deleted when
+// the coupon is given back, on a fully refunded cancellation.
+type CouponRedemption struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	CouponID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"couponId"`
+	UserID    uuid.UUID   `gorm:"type:uuid;not null;index" json:"userId"`
+	BookingID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex" json:"bookingId"`
+	Discount  money.Money `gorm:"column:discount_minor;type:bigint;not null;default:0" json:"discount"`
+	Currency  string      `gorm:"type:char(3);not null" json:"currency"`
+	CreatedAt time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the discount the redemption's currency.
+func (r *CouponRedemption) AfterFind(tx *gorm.DB) error {
+	r.Discount.Currency = r.Currency
+	return nil
+}
+
+// containsID reports whether id is in list, treating an empty list as
+// containing everything.
+func containsID(list []uuid.UUID, id uuid.UUID) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, x := range list {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
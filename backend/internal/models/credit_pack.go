@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	CreditPackAwaitingPayment = "awaiting_payment"
+	CreditPackActive          = "active"
+)
+
+// Package is a bundle of bookings a freelancer sells for one of their offers,
+// such as 5 walks: Credits bookings for Price, to be used within ValidDays of
+// buying it.
+type Package struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
+	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
+	Credits   int            `gorm:"not null" json:"credits"`
+	Price     money.Money    `gorm:"column:price_minor;type:bigint;not null;default:0" json:"price"`
+	Currency  string         `gorm:"type:char(3);not null" json:"currency"`
+	ValidDays int            `gorm:"not null" json:"validDays"`
+	IsActive  bool           `gorm:"not null;default:true" json:"isActive"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (p *Package) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the price the package's currency.
+func (p *Package) AfterFind(tx *gorm.DB) error {
+	p.Price.Currency = p.Currency
+	return nil
+}
+
+// CreditPack is a Package an owner bought. Once paid, each booking of the
+// offer can redeem one credit in place of the offer's price until none
+// remain or the pack expires. A booking cancelled with a full refund gives
+// its credit back.
+type CreditPack struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	PackageID uuid.UUID   `gorm:"type:uuid;not null;index" json:"packageId"`
+	OfferID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"offerId"`
+	OwnerID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"ownerId"`
+	Name      string      `gorm:"type:varchar(100);not null" json:"name"`
+	Credits   int         `gorm:"not null" json:"credits"`
+	Remaining int         `gorm:"not null" json:"remaining"`
+	Price     money.Money `gorm:"column:price_minor;type:bigint;not null;default:0" json:"price"`
+	Currency  string      `gorm:"type:char(3);not null" json:"currency"`
+	ValidDays int         `gorm:"not null" json:"validDays"`
+	Status    string      `gorm:"type:varchar(20);not null;index" json:"status"`
+	PaidAt    *time.Time  `json:"paidAt,omitempty"`
+	ExpiresAt *time.Time  `json:"expiresAt,omitempty"`
+	CreatedAt time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time   `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (p *CreditPack) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the price the pack's currency.
+func (p *CreditPack) AfterFind(tx *gorm.DB) error {
+	p.Price.Currency = p.Currency
+	return nil
+}
+
+// Usable reports whether a credit of the pack can be redeemed at now.
+func (p *CreditPack) Usable(now time.Time) bool {
+	return p.Status == CreditPackActive && p.Remaining > 0 && p.ExpiresAt != nil && now.Before(*p.ExpiresAt)
+}
+
+// CreditValue is what one credit is worth: the price shared evenly between
+// the credits, rounded to the minor unit.
+func (p *CreditPack) CreditValue() money.Money {
+	return p.Price.Scale(1, int64(p.Credits))
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	PayoutPending = "pending"
+	PayoutPaid    = "paid"
+	PayoutFailed  = "failed"
+)
+
+// Earning is what a freelancer earns from a booking: the money collected for
+// it, net of refunds, minus the platform's commission. It can be paid out
+// from AvailableAt on and belongs to a payout once it has been batched.
+type Earning struct {
+	ID            uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	BookingID     uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex" json:"bookingId"`
+	FreelancerID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"freelancerId"`
+	Currency      string      `gorm:"type:char(3);not null" json:"currency"`
+	Gross         money.Money `gorm:"type:bigint;not null" json:"gross"`
+	CommissionBps int         `gorm:"not null" json:"commissionBps"`
+	Commission    money.Money `gorm:"type:bigint;not null" json:"commission"`
+	Net           money.Money `gorm:"type:bigint;not null" json:"net"`
+	AvailableAt   time.Time   `gorm:"not null;index" json:"availableAt"`
+	PayoutID      *uuid.UUID  `gorm:"type:uuid;index" json:"payoutId,omitempty"`
+	CreatedAt     time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (e *Earning) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the amounts the earning's currency.
+func (e *Earning) AfterFind(tx *gorm.DB) error {
+	e.Gross.Currency = e.Currency
+	e.Commission.Currency = e.Currency
+	e.Net.Currency = e.Currency
+	return nil
+}
+
+// Payout is one transfer of a freelancer's available earnings in a currency.
+type Payout struct {
+	ID            uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	FreelancerID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"freelancerId"`
+	Currency      string      `gorm:"type:char(3);not null" json:"currency"`
+	Amount        money.Money `gorm:"type:bigint;not null" json:"amount"`
+	Status        string      `gorm:"type:varchar(20);not null;index" json:"status"`
+	TransferID    string      `gorm:"type:varchar(100)" json:"transferId,omitempty"`
+	FailureReason string      `gorm:"type:text" json:"failureReason,omitempty"`
+	PaidAt        *time.Time  `json:"paidAt,omitempty"`
+	Earnings      []Earning   `gorm:"foreignKey:PayoutID" json:"earnings,omitempty"`
+	CreatedAt     time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt     time.Time   `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (p *Payout) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the amount the payout's currency.
+func (p *Payout) AfterFind(tx *gorm.DB) error {
+	p.Amount.Currency = p.Currency
+	return nil
+}
+
+// EarningTotals sums a freelancer's earnings in one currency: still on hold,
+// ready to be paid out, and already paid.
+type EarningTotals struct {
+	Currency  string      `json:"currency"`
+	Pending   money.Money `json:"pending"`
+	Available money.Money `json:"available"`
+	Paid      money.Money `json:"paid"`
+}
+
+// Commission returns bps basis points of amount, rounded half up.
+func Commission(amount money.Money, bps int) money.Money {
+	return amount.Scale(int64(bps), 10000)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"math/big"
+	"time"
+)
+
+// ExchangeRate is how many units of Currency one unit of the table's base
+// currency buys, as an exact decimal such as "1.0825". The base itself has
+// a rate of 1. Rates are replaced as a whole table, so any two of them can
+// be divided to convert between their currencies.
+type ExchangeRate struct {
+	Currency  string    `gorm:"type:char(3);primaryKey" json:"currency"`
+	Rate      string    `gorm:"type:varchar(40);not null" json:"rate"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// Rat returns the rate as a number, or false if it is not a positive one.
+func (r *ExchangeRate) Rat() (*big.Rat, bool) {
+	v, ok := new(big.Rat).SetString(r.Rate)
+	return v, ok && v.Sign() > 0
+}
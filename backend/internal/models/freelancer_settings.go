@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FreelancerSettings holds per-freelancer scheduling preferences. A missing
+// row means the defaults (no travel time, UTC). CommissionBps is a
+// commission agreed with the freelancer; it is not theirs to change and
+// takes precedence over the service's and the platform default.
+// InvoiceName, InvoiceAddress and TaxID are printed on the freelancer's
+// invoices as the seller.
+type FreelancerSettings struct {
+	UserID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	TravelTimeMin  int       `gorm:"not null;default:0" json:"travelTimeMin"`
+	Timezone       string    `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	CommissionBps  *int      `json:"commissionBps,omitempty"`
+	InvoiceName    string    `gorm:"type:varchar(200);not null;default:''" json:"invoiceName"`
+	InvoiceAddress string    `gorm:"type:text;not null;default:''" json:"invoiceAddress"`
+	TaxID          string    `gorm:"type:varchar(50);not null;default:''" json:"taxId"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
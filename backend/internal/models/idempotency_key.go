@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey remembers the outcome of a mutating request so that a client
+// retrying with the same Idempotency-Key header gets the original response.
+type IdempotencyKey struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_idempotency_scope,priority:1" json:"userId"`
+	Key         string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_scope,priority:2" json:"key"`
+	Method      string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path        string    `gorm:"type:text;not null" json:"path"`
+	Fingerprint string    `gorm:"type:char(64);not null" json:"fingerprint"`
+	Completed   bool      `gorm:"not null;default:false" json:"completed"`
+	StatusCode  int       `gorm:"not null;default:0" json:"statusCode"`
+	ContentType string    `gorm:"type:varchar(100)" json:"contentType"`
+	Response    []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+var ErrInvoiceImmutable = errors.New("invoices cannot be changed once issued")
+
+// Party is the seller or buyer named on an invoice.
+type Party struct {
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+	TaxID   string `json:"taxId,omitempty"`
+}
+
+// Invoice is an invoice the freelancer issues the owner for a completed
+// booking, or a credit note for money refunded on one. Each kind is numbered
+// from its own sequence per freelancer, without gaps. An invoice is never
+// changed once issued: its HTML and PDF renderings are stored with it, and
+// corrections are made with credit notes, which refer to the invoice in
+// InvoiceID and have negative amounts.
+type Invoice struct {
+	ID           uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	Kind         string          `gorm:"type:varchar(20);not null;uniqueIndex:idx_invoices_seq" json:"kind"`
+	FreelancerID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_invoices_seq" json:"freelancerId"`
+	Seq          int             `gorm:"not null;uniqueIndex:idx_invoices_seq" json:"seq"`
+	Number       string          `gorm:"type:varchar(30);not null" json:"number"`
+	BookingID    uuid.UUID       `gorm:"type:uuid;not null;index" json:"bookingId"`
+	OwnerID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"ownerId"`
+	InvoiceID    *uuid.UUID      `gorm:"type:uuid;index" json:"invoiceId,omitempty"`
+	Seller       Party           `gorm:"type:jsonb;serializer:json" json:"seller"`
+	Buyer        Party           `gorm:"type:jsonb;serializer:json" json:"buyer"`
+	Price        *PriceBreakdown `gorm:"type:jsonb;serializer:json" json:"price"`
+	Currency     string          `gorm:"type:char(3);not null" json:"currency"`
+	Total        money.Money     `gorm:"column:total_minor;type:bigint;not null" json:"total"`
+	Reason       string          `gorm:"type:text" json:"reason,omitempty"`
+	IssuedAt     time.Time       `gorm:"not null" json:"issuedAt"`
+	HTML         string          `gorm:"type:text;not null" json:"-"`
+	PDF          []byte          `gorm:"not null" json:"-"`
+	CreatedAt    time.Time       `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (i *Invoice) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *Invoice) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInvoiceImmutable
+}
+
+func (i *Invoice) BeforeDelete(tx *gorm.DB) error {
+	return ErrInvoiceImmutable
+}
+
+// AfterFind gives the total the invoice's currency.
+func (i *Invoice) AfterFind(tx *gorm.DB) error {
+	i.Total.Currency = i.Currency
+	return nil
+}
+
+// InvoiceNumber formats the seq-th document of a kind of a freelancer, e.g.
+// "INV-000042" or, for credit notes, "CN-000007".
+func InvoiceNumber(kind string, seq int) string {
+	prefix := "INV"
+	if kind == InvoiceKindCreditNote {
+		prefix = "CN"
+	}
+	return fmt.Sprintf("%s-%06d", prefix, seq)
+}
+
+// InvoiceCounter is the last number a freelancer used for a kind of
+// document.
+type InvoiceCounter struct {
+	FreelancerID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Kind         string    `gorm:"type:varchar(20);primaryKey"`
+	Last         int       `gorm:"not null;default:0"`
+}
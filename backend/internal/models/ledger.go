@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+// Ledger account kinds. Owner wallets and freelancer payables exist per user
+// and currency; the others belong to the platform and have a nil UserID.
+const (
+	LedgerCash              = "cash"
+	LedgerOwnerWallet       = "owner_wallet"
+	LedgerFreelancerPayable = "freelancer_payable"
+	LedgerPlatformRevenue   = "platform_revenue"
+	LedgerRefunds           = "refunds"
+)
+
+// Journal entry kinds.
+const (
+	JournalCharge   = "charge"
+	JournalEarning  = "earning"
+	JournalFee      = "fee"
+	JournalPayout   = "payout"
+	JournalRefund   = "refund"
+	JournalReversal = "reversal"
+)
+
+// ErrJournalImmutable is returned when a posted journal entry or line would
+// be changed. Mistakes are corrected by posting a reversing entry.
+var ErrJournalImmutable = errors.New("journal entries cannot be changed")
+
+// LedgerAccount holds money of one kind for one user in one currency.
+type LedgerAccount struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Kind      string    `gorm:"type:varchar(30);not null;uniqueIndex:idx_ledger_account" json:"kind"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_ledger_account" json:"userId"`
+	Currency  string    `gorm:"type:char(3);not null;uniqueIndex:idx_ledger_account" json:"currency"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (a *LedgerAccount) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// DebitNormal reports whether the account's balance grows with debits: the
+// cash the platform holds and the refunds clearing account. Wallets,
+// payables and revenue are owed to someone and grow with credits.
+func (a *LedgerAccount) DebitNormal() bool {
+	return a.Kind == LedgerCash || a.Kind == LedgerRefunds
+}
+
+// JournalEntry is one balanced money movement. Its lines always sum to zero;
+// once posted it is never updated or deleted.
+type JournalEntry struct {
+	ID        uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	Kind      string        `gorm:"type:varchar(20);not null;index" json:"kind"`
+	BookingID *uuid.UUID    `gorm:"type:uuid;index" json:"bookingId,omitempty"`
+	Reference string        `gorm:"type:varchar(100)" json:"reference,omitempty"`
+	Currency  string        `gorm:"type:char(3);not null" json:"currency"`
+	Memo      string        `gorm:"type:text" json:"memo,omitempty"`
+	Lines     []JournalLine `gorm:"foreignKey:EntryID" json:"lines"`
+	CreatedAt time.Time     `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (e *JournalEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *JournalEntry) BeforeUpdate(tx *gorm.DB) error { return ErrJournalImmutable }
+func (e *JournalEntry) BeforeDelete(tx *gorm.DB) error { return ErrJournalImmutable }
+
+// AfterFind gives preloaded lines the entry's currency.
+func (e *JournalEntry) AfterFind(tx *gorm.DB) error {
+	for i := range e.Lines {
+		e.Lines[i].Amount.Currency = e.Currency
+	}
+	return nil
+}
+
+// JournalLine moves Amount on one account: positive amounts are debits,
+// negative amounts credits. It is in its entry's currency.
+type JournalLine struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	EntryID   uuid.UUID   `gorm:"type:uuid;not null;index" json:"entryId"`
+	AccountID uuid.UUID   `gorm:"type:uuid;not null;index" json:"accountId"`
+	Amount    money.Money `gorm:"type:bigint;not null" json:"amount"`
+}
+
+func (l *JournalLine) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (l *JournalLine) BeforeUpdate(tx *gorm.DB) error { return ErrJournalImmutable }
+func (l *JournalLine) BeforeDelete(tx *gorm.DB) error { return ErrJournalImmutable }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentRequiresPayment = "requires_payment"
+	PaymentCaptured        = "captured"
+	PaymentFailed          = "failed"
+	PaymentRefunded        = "refunded"
+	PaymentPartlyRefunded  = "partly_refunded"
+)
+
+const (
+	PaymentActionCapture = "capture"
+	PaymentActionRelease = "release"
+)
+
+// Payment is what an owner pays up front for a booking through a payment
+// provider. Amounts are in Currency. Payments for a CreditPack have its
+// PackID and a nil BookingID. ProviderAction is the call still owed to the
+// provider to match Status, capturing or releasing the authorized amount; it
+// is empty once the provider has made it.
+type Payment struct {
+	ID             uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	BookingID      uuid.UUID   `gorm:"type:uuid;not null;index" json:"bookingId"`
+	PackID         *uuid.UUID  `gorm:"type:uuid;index" json:"packId,omitempty"`
+	Provider       string      `gorm:"type:varchar(20);not null" json:"provider"`
+	IntentID       string      `gorm:"type:varchar(100);not null;uniqueIndex" json:"intentId"`
+	ClientSecret   string      `gorm:"type:varchar(200)" json:"clientSecret,omitempty"`
+	Amount         money.Money `gorm:"type:bigint;not null" json:"amount"`
+	Refunded       money.Money `gorm:"type:bigint;not null;default:0" json:"refunded"`
+	Currency       string      `gorm:"type:char(3);not null" json:"currency"`
+	Status         string      `gorm:"type:varchar(20);not null;index" json:"status"`
+	ProviderAction string      `gorm:"type:varchar(20);not null;default:'';index" json:"-"`
+	CreatedAt      time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time   `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (p *Payment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind gives the amounts the payment's currency.
+func (p *Payment) AfterFind(tx *gorm.DB) error {
+	p.Amount.Currency = p.Currency
+	p.Refunded.Currency = p.Currency
+	return nil
+}
+
+// PaymentEvent remembers a handled provider webhook, so redelivered events
+// are only applied once.
+type PaymentEvent struct {
+	ID        string    `gorm:"type:varchar(100);primaryKey" json:"id"`
+	Provider  string    `gorm:"type:varchar(20);not null" json:"provider"`
+	Type      string    `gorm:"type:varchar(50);not null" json:"type"`
+	IntentID  string    `gorm:"type:varchar(100);not null;index" json:"intentId"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+)
+
+const (
+	PriceLineBase      = "base"
+	PriceLineAddOn     = "add_on"
+	PriceLineSurcharge = "surcharge"
+	PriceLineDiscount  = "discount"
+	PriceLineCredit    = "credit"
+	PriceLineTax       = "tax"
+	PriceLineFee       = "fee"
+)
+
+// PriceLine is one item of a PriceBreakdown. Discounts and redeemed credits
+// have negative amounts.
+type PriceLine struct {
+	Kind   string      `json:"kind"`
+	Label  string      `json:"label"`
+	Amount money.Money `json:"amount"`
+}
+
+// PriceBreakdown itemises what a booking costs. Subtotal sums the base price,
+// add-ons, surcharges, discounts and credits; tax and fees are charged on top
+// of it. A copy is stored on each booking, so later changes to the offer do
+// not change what the owner agreed to pay.
+type PriceBreakdown struct {
+	Currency string      `json:"currency"`
+	Lines    []PriceLine `json:"lines"`
+	Subtotal money.Money `json:"subtotal"`
+	Tax      money.Money `json:"tax"`
+	Fees     money.Money `json:"fees"`
+	Total    money.Money `json:"total"`
+}
+
+// NewPriceBreakdown starts an empty breakdown in the currency.
+func NewPriceBreakdown(currency string) *PriceBreakdown {
+	zero := money.New(0, currency)
+	return &PriceBreakdown{Currency: currency, Subtotal: zero, Tax: zero, Fees: zero, Total: zero}
+}
+
+// Add appends a line and updates the sums.
+func (p *PriceBreakdown) Add(kind, label string, amount money.Money) {
+	p.Lines = append(p.Lines, PriceLine{Kind: kind, Label: label, Amount: amount})
+	switch kind {
+	case PriceLineTax:
+		p.Tax = p.Tax.Add(amount)
+	case PriceLineFee:
+		p.Fees = p.Fees.Add(amount)
+	default:
+		p.Subtotal = p.Subtotal.Add(amount)
+	}
+	p.Total = p.Total.Add(amount)
+}
+
+// UnmarshalJSON reads the amounts in the breakdown's currency, so that
+// currencies without two decimals survive a round trip.
+func (p *PriceBreakdown) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Currency string `json:"currency"`
+		Lines    []struct {
+			Kind   string        `json:"kind"`
+			Label  string        `json:"label"`
+			Amount money.Decimal `json:"amount"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*p = *NewPriceBreakdown(raw.Currency)
+	for _, l := range raw.Lines {
+		amount, err := money.Parse(string(l.Amount), raw.Currency)
+		if err != nil {
+			return fmt.Errorf("price line %q: %w", l.Label, err)
+		}
+		p.Add(l.Kind, l.Label, amount)
+	}
+	return nil
+}
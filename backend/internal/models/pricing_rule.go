@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PricingRuleWeekday    = "weekday"
+	PricingRuleHoliday    = "holiday"
+	PricingRuleLastMinute = "last_minute"
+	PricingRuleEarlyBird  = "early_bird"
+	PricingRuleMultiPet   = "multi_pet"
+)
+
+var ErrInvalidPricingRule = errors.New("invalid pricing rule: check kind, percent (-90 to 300, not 0) and the fields the kind needs")
+
+// PricingRule changes an offer's price by Percent of it, a surcharge when
+// positive and a discount when negative. Depending on Kind it applies to:
+//
+//   - weekday: visits, or nights of a stay, on one of Weekdays (0 is Sunday,
+//     every day if empty) and, when StartTime and EndTime are set, starting
+//     between them, in the freelancer's time zone;
+//   - holiday: visits and nights on one of Dates;
+//   - last_minute: bookings made less than LeadHours before the start;
+//   - early_bird: bookings made at least LeadHours before the start;
+//   - multi_pet: bookings for at least MinPets pets.
+type PricingRule struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
+	Kind      string         `gorm:"type:varchar(20);not null" json:"kind"`
+	Label     string         `gorm:"type:varchar(100);not null" json:"label"`
+	Percent   int            `gorm:"not null" json:"percent"`
+	Weekdays  []int          `gorm:"type:jsonb;serializer:json" json:"weekdays,omitempty"`
+	StartTime string         `gorm:"type:varchar(5)" json:"startTime,omitempty"`
+	EndTime   string         `gorm:"type:varchar(5)" json:"endTime,omitempty"`
+	Dates     []string       `gorm:"type:jsonb;serializer:json" json:"dates,omitempty"`
+	LeadHours int            `gorm:"not null;default:0" json:"leadHours,omitempty"`
+	MinPets   int            `gorm:"not null;default:0" json:"minPets,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (r *PricingRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Validate checks the rule has what its kind needs.
+func (r *PricingRule) Validate() error {
+	if r.Percent == 0 || r.Percent < -90 || r.Percent > 300 {
+		return ErrInvalidPricingRule
+	}
+	switch r.Kind {
+	case PricingRuleWeekday:
+		for _, d := range r.Weekdays {
+			if d < 0 || d > 6 {
+				return ErrInvalidPricingRule
+			}
+		}
+		if (r.StartTime == "") != (r.EndTime == "") {
+			return ErrInvalidPricingRule
+		}
+		if r.StartTime != "" && (!validClock(r.StartTime) || !validClock(r.EndTime) || r.StartTime >= r.EndTime) {
+			return ErrInvalidPricingRule
+		}
+	case PricingRuleHoliday:
+		if len(r.Dates) == 0 {
+			return ErrInvalidPricingRule
+		}
+		for _, d := range r.Dates {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return ErrInvalidPricingRule
+			}
+		}
+	case PricingRuleLastMinute, PricingRuleEarlyBird:
+		if r.LeadHours <= 0 {
+			return ErrInvalidPricingRule
+		}
+	case PricingRuleMultiPet:
+		if r.MinPets < 2 {
+			return ErrInvalidPricingRule
+		}
+	default:
+		return ErrInvalidPricingRule
+	}
+	return nil
+}
+
+// AppliesOn reports whether a weekday or holiday rule covers a visit or
+// night starting at local time t.
+func (r *PricingRule) AppliesOn(t time.Time) bool {
+	switch r.Kind {
+	case PricingRuleWeekday:
+		if len(r.Weekdays) > 0 && !containsInt(r.Weekdays, int(t.Weekday())) {
+			return false
+		}
+		clock := t.Format("15:04")
+		return r.StartTime == "" || clock >= r.StartTime && clock < r.EndTime
+	case PricingRuleHoliday:
+		day := t.Format("2006-01-02")
+		for _, d := range r.Dates {
+			if d == day {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AppliesToBooking reports whether a lead time or multi-pet rule covers a
+// booking of pets pets made leadHours before its start.
+func (r *PricingRule) AppliesToBooking(leadHours float64, pets int) bool {
+	switch r.Kind {
+	case PricingRuleLastMinute:
+		return leadHours < float64(r.LeadHours)
+	case PricingRuleEarlyBird:
+		return leadHours >= float64(r.LeadHours)
+	case PricingRuleMultiPet:
+		return pets >= r.MinPets
+	}
+	return false
+}
+
+func validClock(s string) bool {
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
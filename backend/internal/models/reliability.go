@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ReliabilityCompleted              = "completed"
+	ReliabilityNoShow                 = "no_show"
+	ReliabilityLateCancellation       = "late_cancellation"
+	ReliabilityFreelancerCancellation = "freelancer_cancellation"
+)
+
+// ReliabilityEvent is one entry in a user's booking history: a completed
+// booking, an owner's no-show or late cancellation, or a cancellation by the
+// freelancer.
+type ReliabilityEvent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"userId"`
+	BookingID uuid.UUID `gorm:"type:uuid;not null;index" json:"bookingId"`
+	Kind      string    `gorm:"type:varchar(30);not null" json:"kind"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (e *ReliabilityEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReliabilityStats counts a user's ReliabilityEvents by kind. Score is the
+// share of their bookings, in percent, that went ahead; it is nil until
+// there is any history.
+type ReliabilityStats struct {
+	UserID                  uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	Completed               int       `gorm:"not null;default:0" json:"completed"`
+	NoShows                 int       `gorm:"not null;default:0" json:"noShows"`
+	LateCancellations       int       `gorm:"not null;default:0" json:"lateCancellations"`
+	FreelancerCancellations int       `gorm:"not null;default:0" json:"freelancerCancellations"`
+	Score                   *int      `gorm:"-" json:"score"`
+	UpdatedAt               time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (s *ReliabilityStats) AfterFind(tx *gorm.DB) error {
+	s.RefreshScore()
+	return nil
+}
+
+// RefreshScore recomputes Score from the counters.
+func (s *ReliabilityStats) RefreshScore() {
+	total := s.Completed + s.NoShows + s.LateCancellations + s.FreelancerCancellations
+	s.Score = nil
+	if total > 0 {
+		score := s.Completed * 100 / total
+		s.Score = &score
+	}
+}
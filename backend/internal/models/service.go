@@ -4,15 +4,20 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"gorm.io/gorm"
 )
 
+// Service is a kind of work offered on the platform. CommissionBps, when
+// set, replaces the default platform commission for its bookings.
 type Service struct {
 	ID                 uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
 	Name               string         `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
 	Description        string         `gorm:"type:text" json:"description,omitempty"`
-	BasePrice          float64        `gorm:"not null;default:0" json:"basePrice"`
+	BasePrice          money.Money    `gorm:"column:base_price_minor;type:bigint;not null;default:0" json:"basePrice"`
+	Currency           string         `gorm:"type:char(3);not null;default:'EUR'" json:"currency"`
 	DefaultDurationMin int            `gorm:"not null;default:60" json:"defaultDurationMin"`
+	CommissionBps      *int           `json:"commissionBps,omitempty"`
 	CreatedAt          time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt          time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt          gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
@@ -24,3 +29,9 @@ func (s *Service) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// AfterFind gives the base price the service's currency.
+func (s *Service) AfterFind(tx *gorm.DB) error {
+	s.BasePrice.Currency = s.Currency
+	return nil
+}
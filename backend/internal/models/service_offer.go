@@ -4,20 +4,37 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"gorm.io/gorm"
 )
 
+const (
+	PriceTypeHourly  = "hourly"
+	PriceTypeFixed   = "fixed"
+	PriceTypeNightly = "nightly"
+)
+
 type ServiceOffer struct {
 	ID                  uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
 	FreelancerID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"freelancerId"`
 	ServiceID           uuid.UUID      `gorm:"type:uuid;not null;index" json:"serviceId"`
 	Title               string         `gorm:"type:varchar(150);not null" json:"title"`
 	Description         string         `gorm:"type:text;not null" json:"description"`
-	Price               float32        `gorm:"not null" json:"price"`
+	Price               money.Money    `gorm:"column:price_minor;type:bigint;not null;default:0" json:"price"`
 	Currency            string         `gorm:"type:char(3);not null" json:"currency"`
 	PriceType           string         `gorm:"type:varchar(20);not null" json:"priceType"`
 	DurationEstimateMin int            `gorm:"not null;default:60" json:"durationEstimateMin"`
 	IsActive            bool           `gorm:"not null;default:true" json:"isActive"`
+	CancellationPolicy  string         `gorm:"type:varchar(20);not null;default:'flexible'" json:"cancellationPolicy"`
+	CancellationTiers   []RefundTier   `gorm:"type:jsonb;serializer:json" json:"cancellationTiers,omitempty"`
+	RescheduleApproval  bool           `gorm:"not null;default:false" json:"rescheduleApproval"`
+	CheckInTime         string         `gorm:"type:char(5);not null;default:'14:00'" json:"checkInTime"`
+	CheckOutTime        string         `gorm:"type:char(5);not null;default:'11:00'" json:"checkOutTime"`
+	BufferBeforeMin     int            `gorm:"not null;default:0" json:"bufferBeforeMin"`
+	BufferAfterMin      int            `gorm:"not null;default:0" json:"bufferAfterMin"`
+	Prepaid             bool           `gorm:"not null;default:false" json:"prepaid"`
+	DepositPercent      int            `gorm:"not null;default:0" json:"depositPercent"`
+	DepositAfterNoShows int            `gorm:"not null;default:0" json:"depositAfterNoShows"`
 	CreatedAt           time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt           time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt           gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
@@ -29,3 +46,21 @@ func (o *ServiceOffer) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// AfterFind gives the price the offer's currency, which is stored once.
+func (o *ServiceOffer) AfterFind(tx *gorm.DB) error {
+	o.Price.Currency = o.Currency
+	return nil
+}
+
+// RequiresDeposit reports whether an owner with the given number of no-shows
+// has to pay DepositPercent of the price up front. A DepositAfterNoShows of
+// zero never asks for a deposit.
+func (o *ServiceOffer) RequiresDeposit(noShows int) bool {
+	return o.DepositPercent > 0 && o.DepositAfterNoShows > 0 && noShows >= o.DepositAfterNoShows
+}
+
+// IsStay reports whether the offer is booked by nights instead of slots.
+func (o *ServiceOffer) IsStay() bool {
+	return o.PriceType == PriceTypeNightly
+}
@@ -7,15 +7,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// AvailabilitySlot is a bookable session of an offer. Group sessions take up
+// to Capacity bookings. HeldCount seats are reserved for waitlisted owners
+// who were offered them; IsBooked is set once no seat is left. A slot that
+// overlaps busy time in an imported calendar is blocked by that import.
 type AvailabilitySlot struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	OfferID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
-	StartTime time.Time      `gorm:"not null;index:idx_slot_time,priority:1" json:"startTime"`
-	EndTime   time.Time      `gorm:"not null" json:"endTime"`
-	IsBooked  bool           `gorm:"not null;default:false;index" json:"isBooked"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"offerId"`
+	StartTime      time.Time      `gorm:"not null;index:idx_slot_time,priority:1" json:"startTime"`
+	EndTime        time.Time      `gorm:"not null" json:"endTime"`
+	IsBooked       bool           `gorm:"not null;default:false;index" json:"isBooked"`
+	Capacity       int            `gorm:"not null;default:1" json:"capacity"`
+	BookedCount    int            `gorm:"not null;default:0" json:"bookedCount"`
+	HeldCount      int            `gorm:"not null;default:0" json:"heldCount"`
+	BlockedBy      *uuid.UUID     `gorm:"type:uuid;index" json:"blockedBy,omitempty"`
+	RemainingSeats int            `gorm:"-" json:"remainingSeats"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
 }
 
 func (s *AvailabilitySlot) BeforeCreate(tx *gorm.DB) error {
@@ -24,3 +33,58 @@ func (s *AvailabilitySlot) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func (s *AvailabilitySlot) AfterFind(tx *gorm.DB) error {
+	s.RefreshSeats()
+	return nil
+}
+
+// HasSeat reports whether another booking fits into the slot.
+func (s *AvailabilitySlot) HasSeat() bool {
+	return !s.IsBooked && s.BlockedBy == nil && s.taken() < s.Capacity
+}
+
+// Reserve takes one seat.
+func (s *AvailabilitySlot) Reserve() {
+	s.BookedCount++
+	s.settle()
+}
+
+// Release gives one booked seat back.
+func (s *AvailabilitySlot) Release() {
+	if s.BookedCount > 0 {
+		s.BookedCount--
+	}
+	s.settle()
+}
+
+// Hold sets a free seat aside for a waitlisted owner.
+func (s *AvailabilitySlot) Hold() {
+	s.HeldCount++
+	s.settle()
+}
+
+// Unhold returns a held seat, either to be booked or to be freed.
+func (s *AvailabilitySlot) Unhold() {
+	if s.HeldCount > 0 {
+		s.HeldCount--
+	}
+	s.settle()
+}
+
+// RefreshSeats recomputes RemainingSeats after the counters changed.
+func (s *AvailabilitySlot) RefreshSeats() {
+	s.RemainingSeats = 0
+	if !s.IsBooked && s.BlockedBy == nil && s.Capacity > s.taken() {
+		s.RemainingSeats = s.Capacity - s.taken()
+	}
+}
+
+func (s *AvailabilitySlot) taken() int {
+	return s.BookedCount + s.HeldCount
+}
+
+func (s *AvailabilitySlot) settle() {
+	s.IsBooked = s.taken() >= s.Capacity
+	s.RefreshSeats()
+}
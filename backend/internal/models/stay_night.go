@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StayNight is one night of a stay-type ("nightly") offer. Capacity is how
+// many pets the freelancer can board that night and Booked how many stays
+// already cover it.
+type StayNight struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	OfferID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_stay_night,priority:1" json:"offerId"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_stay_night,priority:2" json:"date"`
+	Capacity  int       `gorm:"not null" json:"capacity"`
+	Booked    int       `gorm:"not null;default:0" json:"booked"`
+	Remaining int       `gorm:"-" json:"remaining"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (n *StayNight) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+func (n *StayNight) AfterFind(tx *gorm.DB) error {
+	n.Remaining = n.Capacity - n.Booked
+	return nil
+}
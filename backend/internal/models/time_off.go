@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TimeOff is a period in which a freelancer takes no appointments. Unbooked
+// slots inside it are hidden and no new slots can be opened in it.
+type TimeOff struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	FreelancerID uuid.UUID `gorm:"type:uuid;not null;index" json:"freelancerId"`
+	StartsAt     time.Time `gorm:"not null;index" json:"startsAt"`
+	EndsAt       time.Time `gorm:"not null;index" json:"endsAt"`
+	Reason       string    `gorm:"type:varchar(200)" json:"reason,omitempty"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (t *TimeOff) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// Covers reports whether [start, end) overlaps the time off.
+func (t *TimeOff) Covers(start, end time.Time) bool {
+	return t.StartsAt.Before(end) && t.EndsAt.After(start)
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// VisitReport is the freelancer's record of one booking: when and where
+// they checked in and out, what they did and photos taken during the visit.
+type VisitReport struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	BookingID    uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"bookingId"`
+	FreelancerID uuid.UUID      `gorm:"type:uuid;not null;index" json:"freelancerId"`
+	CheckedInAt  *time.Time     `json:"checkedInAt,omitempty"`
+	CheckInLat   *float64       `json:"checkInLat,omitempty"`
+	CheckInLng   *float64       `json:"checkInLng,omitempty"`
+	CheckedOutAt *time.Time     `json:"checkedOutAt,omitempty"`
+	CheckOutLat  *float64       `json:"checkOutLat,omitempty"`
+	CheckOutLng  *float64       `json:"checkOutLng,omitempty"`
+	Notes        string         `gorm:"type:text" json:"notes"`
+	Checklist    VisitChecklist `gorm:"type:jsonb;serializer:json" json:"checklist"`
+	Photos       []VisitPhoto   `gorm:"foreignKey:ReportID" json:"photos"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// VisitChecklist ticks off the usual chores of a visit.
+type VisitChecklist struct {
+	Fed           bool `json:"fed"`
+	Watered       bool `json:"watered"`
+	Walked        bool `json:"walked"`
+	Medicated     bool `json:"medicated"`
+	LitterCleaned bool `json:"litterCleaned"`
+	PlayedWith    bool `json:"playedWith"`
+}
+
+// Editable reports whether the freelancer may still change the report: from
+// check-in until a day after check-out.
+func (r *VisitReport) Editable(now time.Time) bool {
+	if r.CheckedInAt == nil {
+		return false
+	}
+	return r.CheckedOutAt == nil || now.Before(r.CheckedOutAt.Add(24*time.Hour))
+}
+
+// Done lists the chores that were ticked off.
+func (c VisitChecklist) Done() []string {
+	var done []string
+	for _, item := range []struct {
+		ok   bool
+		name string
+	}{
+		{c.Fed, "fed"},
+		{c.Watered, "watered"},
+		{c.Walked, "walked"},
+		{c.Medicated, "medicated"},
+		{c.LitterCleaned, "litter cleaned"},
+		{c.PlayedWith, "played with"},
+	} {
+		if item.ok {
+			done = append(done, item.name)
+		}
+	}
+	return done
+}
+
+func (r *VisitReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// VisitPhoto is an image attached to a visit report. The file itself lives
+// in the upload directory under StoragePath.
+type VisitPhoto struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ReportID    uuid.UUID `gorm:"type:uuid;not null;index" json:"reportId"`
+	ContentType string    `gorm:"type:varchar(50);not null" json:"contentType"`
+	Size        int64     `gorm:"not null" json:"size"`
+	StoragePath string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (p *VisitPhoto) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
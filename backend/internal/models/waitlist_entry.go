@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistOffered = "offered"
+	WaitlistClaimed = "claimed"
+	WaitlistExpired = "expired"
+	WaitlistLeft    = "left"
+)
+
+// WaitlistEntry queues an owner for a fully booked slot. When a seat frees up
+// the oldest waiting entry is offered it exclusively until OfferExpiresAt.
+type WaitlistEntry struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SlotID         uuid.UUID  `gorm:"type:uuid;not null;index:idx_waitlist_slot,priority:1" json:"slotId"`
+	OwnerID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"ownerId"`
+	Status         string     `gorm:"type:varchar(20);not null;index:idx_waitlist_slot,priority:2" json:"status"`
+	OfferedAt      *time.Time `json:"offeredAt,omitempty"`
+	OfferExpiresAt *time.Time `gorm:"index" json:"offerExpiresAt,omitempty"`
+	BookingID      *uuid.UUID `gorm:"type:uuid" json:"bookingId,omitempty"`
+	Position       int        `gorm:"-" json:"position,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (w *WaitlistEntry) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsOpen reports whether the entry is still queued or holding an offer.
+func (w *WaitlistEntry) IsOpen() bool {
+	return w.Status == WaitlistWaiting || w.Status == WaitlistOffered
+}
@@ -0,0 +1,265 @@
+// Package money represents amounts of money exactly, as integer minor units
+// of an ISO 4217 currency.
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount of money")
+
+// exponents lists the currencies whose minor unit is not a hundredth.
+var exponents = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3,
+	"PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// Exponent returns the number of decimals of the currency's minor unit.
+func Exponent(currency string) int {
+	if e, ok := exponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// Exponents returns the currencies whose exponent is not 2.
+func Exponents() map[string]int {
+	out := make(map[string]int, len(exponents))
+	for c, e := range exponents {
+		out[c] = e
+	}
+	return out
+}
+
+// Money is Amount minor units of Currency, e.g. 1050 EUR is €10.50.
+//
+// In JSON it is a decimal string in major units ("10.50"); the currency is
+// carried next to it. In the database it is a bigint column of minor units,
+// and the owning model fills Currency in after loading.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a decimal amount in major units, such as "10.5", exactly.
+// Amounts with more decimals than the currency has are rejected.
+func Parse(s, currency string) (Money, error) {
+	exp := Exponent(currency)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > exp || !digits(whole) || !digits(frac) {
+		return Money{}, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if neg {
+		n = -n
+	}
+	return Money{Amount: n, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns m + o. Both must be in the same currency; a zero Money without
+// a currency adds to anything.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.common(o)}
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.common(o)}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m times n.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Scale returns m times num/den, rounded to the nearest minor unit with
+// halves rounded away from zero.
+func (m Money) Scale(num, den int64) Money {
+	p := m.Amount * num
+	q, r := p/den, p%den
+	if 2*abs(r) >= abs(den) {
+		if (p < 0) != (den < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money{Amount: q, Currency: m.Currency}
+}
+
+// Percent returns pct percent of m, rounded like Scale.
+func (m Money) Percent(pct int) Money {
+	return m.Scale(int64(pct), 100)
+}
+
+// Convert returns m in currency to at rate units of to per unit of m's
+// currency, rounded to the nearest minor unit with halves rounded away from
+// zero.
+func (m Money) Convert(to string, rate *big.Rat) Money {
+	r := new(big.Rat).SetInt64(m.Amount)
+	r.Mul(r, rate)
+	r.Mul(r, new(big.Rat).SetFrac(pow10(Exponent(to)), pow10(Exponent(m.Currency))))
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	return Money{Amount: q.Int64(), Currency: to}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// Decimal formats the amount in major units, e.g. "10.50".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	n := m.Amount
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := strconv.FormatInt(n, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+// String formats the amount with its currency, e.g. "EUR 10.50".
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Decimal()
+	}
+	return m.Currency + " " + m.Decimal()
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Decimal())
+}
+
+// UnmarshalJSON accepts a decimal string or, as older clients send, a JSON
+// number. Currency should be set beforehand; without it two decimals are
+// assumed.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var d Decimal
+	if err := d.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	v, err := Parse(string(d), m.Currency)
+	if err != nil {
+		return err
+	}
+	m.Amount = v.Amount
+	return nil
+}
+
+// Value stores the amount in minor units.
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		m.Amount = 0
+	case int64:
+		m.Amount = v
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("money: %w", err)
+	}
+	m.Amount = n
+	return nil
+}
+
+func (m Money) common(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, o.Currency))
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Decimal is an amount in major units as sent by clients: a JSON string such
+// as "10.50" or a JSON number such as 10.5. Parse turns it into Money once
+// the currency is known.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*d = Decimal(strings.TrimSpace(s))
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return ErrInvalidAmount
+	}
+	*d = Decimal(n.String())
+	return nil
+}
@@ -0,0 +1,70 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	m, err := Parse("10.5", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, New(1050, "EUR"), m)
+	assert.Equal(t, "10.50", m.Decimal())
+	assert.Equal(t, "EUR 10.50", m.String())
+
+	yen, err := Parse("1500", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), yen.Amount)
+	assert.Equal(t, "1500", yen.Decimal())
+	assert.Equal(t, "0.005", New(5, "KWD").Decimal())
+	assert.Equal(t, "-0.07", New(-7, "USD").Decimal())
+
+	for _, bad := range []string{"", ".", "1.234", "1e3", "abc", "1,50"} {
+		_, err := Parse(bad, "EUR")
+		assert.ErrorIs(t, err, ErrInvalidAmount, bad)
+	}
+	_, err = Parse("10.5", "JPY")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestArithmeticRoundsHalfAway(t *testing.T) {
+	price := New(1999, "EUR")
+	assert.Equal(t, New(1000, "EUR"), price.Percent(50))
+	assert.Equal(t, New(-1000, "EUR"), New(-1999, "EUR").Percent(50))
+	assert.Equal(t, New(2999, "EUR"), price.Scale(90, 60))
+	assert.Equal(t, New(3998, "EUR"), price.Add(price))
+	assert.Equal(t, New(1999, "EUR"), Money{}.Add(price))
+	assert.Panics(t, func() { price.Add(New(1, "USD")) })
+}
+
+func TestJSONAcceptsStringsAndNumbers(t *testing.T) {
+	b, err := json.Marshal(New(3050, "EUR"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"30.50"`, string(b))
+
+	for _, in := range []string{`"30.50"`, `30.5`, `"30.5"`} {
+		m := Money{Currency: "EUR"}
+		assert.NoError(t, json.Unmarshal([]byte(in), &m), in)
+		assert.Equal(t, int64(3050), m.Amount, in)
+	}
+
+	var d Decimal
+	assert.NoError(t, json.Unmarshal([]byte(`12.25`), &d))
+	m, err := Parse(string(d), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1225), m.Amount)
+	assert.Error(t, json.Unmarshal([]byte(`true`), &d))
+}
+
+func TestConvertBetweenExponents(t *testing.T) {
+	rate, _ := new(big.Rat).SetString("1.0825")
+	assert.Equal(t, New(1624, "USD"), New(1500, "EUR").Convert("USD", rate))
+	assert.Equal(t, New(-1624, "USD"), New(-1500, "EUR").Convert("USD", rate))
+
+	yen, _ := new(big.Rat).SetString("162.5")
+	assert.Equal(t, New(2438, "JPY"), New(1500, "EUR").Convert("JPY", yen))
+	assert.Equal(t, New(1500, "EUR"), New(2438, "JPY").Convert("EUR", new(big.Rat).Inv(yen)))
+}
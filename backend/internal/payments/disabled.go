@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+)
+
+// Disabled stands in for a provider while none is configured. Bookings that
+// need no payment work as usual; anything that would move money fails with
+// ErrDisabled.
+type Disabled struct{}
+
+func (Disabled) Name() string { return "disabled" }
+
+func (Disabled) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) Refund(ctx context.Context, intentID string, amount int64) (*Intent, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) Payout(ctx context.Context, req PayoutRequest) (string, error) {
+	return "", ErrDisabled
+}
@@ -0,0 +1,198 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// SignatureHeader carries the fake provider's webhook signature.
+const SignatureHeader = "X-Fake-Signature"
+
+// FakeProvider is an in-memory PaymentProvider for development and tests.
+// IDs are numbered in creation order after a prefix picked per process, so
+// they never repeat the IDs of payments stored by an earlier run; those
+// intents are gone, though, and capturing or refunding them fails with
+// ErrIntentNotFound. Intents are completed with Authorize or Decline, which
+// return the webhook the real provider would send.
+type FakeProvider struct {
+	secret []byte
+	run    string
+
+	mu      sync.Mutex
+	seq     int
+	intents map[string]*Intent
+	byKey   map[string]string
+	payouts map[string]string
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	run := make([]byte, 4)
+	rand.Read(run)
+	return &FakeProvider{
+		secret:  []byte(webhookSecret),
+		run:     hex.EncodeToString(run),
+		intents: make(map[string]*Intent),
+		byKey:   make(map[string]string),
+		payouts: make(map[string]string),
+	}
+}
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id, ok := p.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return p.copy(id), nil
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	p.seq++
+	id := fmt.Sprintf("pi_fake_%s_%06d", p.run, p.seq)
+	p.intents[id] = &Intent{
+		ID:           id,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		Status:       IntentRequiresPayment,
+		ClientSecret: id + "_secret",
+	}
+	if req.IdempotencyKey != "" {
+		p.byKey[req.IdempotencyKey] = id
+	}
+	return p.copy(id), nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	in, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	switch in.Status {
+	case IntentCaptured:
+	case IntentAuthorized:
+		in.Status = IntentCaptured
+	default:
+		return nil, ErrInvalidState
+	}
+	return p.copy(intentID), nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount int64) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	in, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	switch in.Status {
+	case IntentAuthorized:
+		in.Status = IntentRefunded
+	case IntentCaptured:
+		if amount <= 0 || in.Refunded+amount > in.Amount {
+			return nil, ErrRefundTooLarge
+		}
+		in.Refunded += amount
+		if in.Refunded == in.Amount {
+			in.Status = IntentRefunded
+		}
+	default:
+		return nil, ErrInvalidState
+	}
+	return p.copy(intentID), nil
+}
+
+func (p *FakeProvider) Payout(ctx context.Context, req PayoutRequest) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id, ok := p.payouts[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return id, nil
+	}
+	if req.Amount <= 0 {
+		return "", fmt.Errorf("amount must be positive")
+	}
+	p.seq++
+	id := fmt.Sprintf("po_fake_%s_%06d", p.run, p.seq)
+	if req.IdempotencyKey != "" {
+		p.payouts[req.IdempotencyKey] = id
+	}
+	return id, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	sig, err := hex.DecodeString(header.Get(SignatureHeader))
+	if err != nil || !hmac.Equal(sig, p.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// Intent returns the current state of an intent.
+func (p *FakeProvider) Intent(intentID string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.intents[intentID]; !ok {
+		return nil, ErrIntentNotFound
+	}
+	return p.copy(intentID), nil
+}
+
+// Authorize completes the payer's side of an intent and returns the signed
+// webhook announcing it.
+func (p *FakeProvider) Authorize(intentID string) ([]byte, http.Header, error) {
+	return p.settle(intentID, IntentAuthorized, EventIntentAuthorized)
+}
+
+// Decline fails an intent and returns the signed webhook announcing it.
+func (p *FakeProvider) Decline(intentID string) ([]byte, http.Header, error) {
+	return p.settle(intentID, IntentFailed, EventIntentFailed)
+}
+
+func (p *FakeProvider) settle(intentID, status, eventType string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	in, ok := p.intents[intentID]
+	if !ok {
+		return nil, nil, ErrIntentNotFound
+	}
+	if in.Status != IntentRequiresPayment {
+		return nil, nil, ErrInvalidState
+	}
+	in.Status = status
+	p.seq++
+	payload, err := json.Marshal(Event{
+		ID:       fmt.Sprintf("evt_fake_%s_%06d", p.run, p.seq),
+		Type:     eventType,
+		IntentID: intentID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(SignatureHeader, hex.EncodeToString(p.sign(payload)))
+	return payload, header, nil
+}
+
+func (p *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (p *FakeProvider) copy(id string) *Intent {
+	in := *p.intents[id]
+	return &in
+}
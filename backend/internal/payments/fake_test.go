@@ -0,0 +1,46 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeProviderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("whsec")
+
+	in, err := p.CreateIntent(ctx, IntentRequest{Amount: 1500, Currency: "EUR", IdempotencyKey: "booking-1"})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^pi_fake_[0-9a-f]{8}_000001$`, in.ID)
+	other, _ := NewFakeProvider("whsec").CreateIntent(ctx, IntentRequest{Amount: 1500, Currency: "EUR"})
+	assert.NotEqual(t, in.ID, other.ID, "a restarted provider must not reuse IDs")
+	again, _ := p.CreateIntent(ctx, IntentRequest{Amount: 1500, Currency: "EUR", IdempotencyKey: "booking-1"})
+	assert.Equal(t, in.ID, again.ID)
+
+	_, err = p.Capture(ctx, in.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	payload, header, err := p.Authorize(in.ID)
+	assert.NoError(t, err)
+	ev, err := p.VerifyWebhook(payload, header)
+	assert.NoError(t, err)
+	assert.Equal(t, EventIntentAuthorized, ev.Type)
+	assert.Equal(t, in.ID, ev.IntentID)
+
+	_, err = NewFakeProvider("other").VerifyWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	in, err = p.Capture(ctx, in.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentCaptured, in.Status)
+
+	in, err = p.Refund(ctx, in.ID, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), in.Refunded)
+	_, err = p.Refund(ctx, in.ID, 1001)
+	assert.ErrorIs(t, err, ErrRefundTooLarge)
+	in, _ = p.Refund(ctx, in.ID, 1000)
+	assert.Equal(t, IntentRefunded, in.Status)
+}
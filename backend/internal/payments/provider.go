@@ -0,0 +1,100 @@
+// Package payments talks to payment providers. Amounts are in minor units
+// (cents) of the given ISO 4217 currency.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	IntentRequiresPayment = "requires_payment"
+	IntentAuthorized      = "authorized"
+	IntentCaptured        = "captured"
+	IntentFailed          = "failed"
+	IntentRefunded        = "refunded"
+)
+
+const (
+	// EventIntentAuthorized is sent once the payer's funds are reserved and
+	// the intent can be captured.
+	EventIntentAuthorized = "intent.authorized"
+	// EventIntentFailed is sent when the payer's attempt was declined.
+	EventIntentFailed = "intent.failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrInvalidState     = errors.New("payment intent is not in a state that allows this")
+	ErrRefundTooLarge   = errors.New("refund exceeds the captured amount")
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrDisabled         = errors.New("payments are not configured")
+)
+
+// IntentRequest asks for a new payment intent. IdempotencyKey makes retries
+// return the intent created by the first request.
+type IntentRequest struct {
+	Amount         int64
+	Currency       string
+	Reference      string
+	IdempotencyKey string
+}
+
+// Intent is a payment the payer completes with the provider, using
+// ClientSecret on the client side.
+type Intent struct {
+	ID           string
+	Amount       int64
+	Currency     string
+	Status       string
+	ClientSecret string
+	Refunded     int64
+}
+
+// PayoutRequest sends Amount to Payee, the provider's reference for the
+// recipient's account. Retries with the same IdempotencyKey send it once.
+type PayoutRequest struct {
+	Amount         int64
+	Currency       string
+	Payee          string
+	IdempotencyKey string
+}
+
+// Event is a verified webhook notification about an intent.
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	IntentID string `json:"intentId"`
+}
+
+// PaymentProvider is implemented by every payment backend.
+type PaymentProvider interface {
+	// Name identifies the provider on stored payments.
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture collects an authorized intent.
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund pays amount of a captured intent back, or releases an
+	// authorized one in full.
+	Refund(ctx context.Context, intentID string, amount int64) (*Intent, error)
+	// VerifyWebhook checks that payload was sent by the provider and
+	// decodes the event it carries.
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+	// Payout transfers money to a payee and returns the transfer's ID.
+	Payout(ctx context.Context, req PayoutRequest) (string, error)
+}
+
+// New returns the provider called name. With no name, payments are disabled:
+// the provider refuses everything with ErrDisabled.
+func New(name, webhookSecret string) (PaymentProvider, error) {
+	switch name {
+	case "":
+		return Disabled{}, nil
+	case "fake":
+		return NewFakeProvider(webhookSecret), nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+}
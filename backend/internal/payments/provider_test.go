@@ -0,0 +1,23 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPicksProviderByName(t *testing.T) {
+	p, err := New("fake", "whsec")
+	assert.NoError(t, err)
+	assert.IsType(t, &FakeProvider{}, p)
+
+	_, err = New("acme", "whsec")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	// without a name nothing moves money
+	p, err = New("", "")
+	assert.NoError(t, err)
+	_, err = p.CreateIntent(context.Background(), IntentRequest{Amount: 1500, Currency: "EUR"})
+	assert.ErrorIs(t, err, ErrDisabled)
+}
@@ -0,0 +1,45 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentHasValidCrossReferences(t *testing.T) {
+	d := New()
+	p := d.AddPage()
+	p.Text(50, 800, Bold, 16, "Invoice (draft)")
+	p.TextRight(545, 780, 10, "€ 12.50")
+	p.Line(50, 770, 545, 770)
+	d.AddPage().Text(50, 800, Regular, 10, "Grüße 日本")
+	out := d.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), `(Invoice \(draft\)) Tj`)
+	assert.Contains(t, string(out), `(\200 12.50) Tj`)
+	assert.Contains(t, string(out), `(Gr\374\337e ??) Tj`)
+	assert.Contains(t, string(out), "/Count 2")
+	assert.Equal(t, out, d.Bytes(), "rendering is deterministic")
+
+	// every object sits where the cross-reference table says it does
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	xref, _ := strconv.Atoi(string(start[1]))
+	assert.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	assert.Len(t, entries, 9)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(out[off:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+
+	// stream lengths match their content
+	for _, m := range regexp.MustCompile(`(?s)/Length (\d+) >>\nstream\n(.*?)endstream`).FindAllSubmatch(out, -1) {
+		n, _ := strconv.Atoi(string(m[1]))
+		assert.Equal(t, n, len(m[2]))
+	}
+}
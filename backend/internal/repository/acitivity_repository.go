@@ -15,6 +15,11 @@ func NewActivityRepository(db *gorm.DB) *ActivityRepository {
 	return &ActivityRepository{db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *ActivityRepository) WithTx(tx *gorm.DB) *ActivityRepository {
+	return &ActivityRepository{tx}
+}
+
 func (r *ActivityRepository) Create(ctx context.Context, a *models.Activity) error {
 	return r.db.WithContext(ctx).Create(a).Error
 }
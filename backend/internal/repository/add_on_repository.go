@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type AddOnRepository struct {
+	db *gorm.DB
+}
+
+func NewAddOnRepository(db *gorm.DB) *AddOnRepository {
+	return &AddOnRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *AddOnRepository) WithTx(tx *gorm.DB) *AddOnRepository {
+	return &AddOnRepository{tx}
+}
+
+func (r *AddOnRepository) Create(ctx context.Context, a *models.AddOn) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+func (r *AddOnRepository) Update(ctx context.Context, a *models.AddOn) error {
+	return r.db.WithContext(ctx).Save(a).Error
+}
+
+func (r *AddOnRepository) FindByID(ctx context.Context, id any) (*models.AddOn, error) {
+	var a models.AddOn
+	if err := r.db.WithContext(ctx).First(&a, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListByOffer returns the offer's add-ons by name, only the active ones
+// unless all is set.
+func (r *AddOnRepository) ListByOffer(ctx context.Context, offerID any, all bool) ([]models.AddOn, error) {
+	var list []models.AddOn
+	q := r.db.WithContext(ctx).Where("offer_id = ?", offerID)
+	if !all {
+		q = q.Where("is_active = ?", true)
+	}
+	err := q.Order("name").Find(&list).Error
+	return list, err
+}
+
+// ListActive returns those of the given add-ons that are active and belong
+// to the offer.
+func (r *AddOnRepository) ListActive(ctx context.Context, offerID any, ids []uuid.UUID) ([]models.AddOn, error) {
+	var list []models.AddOn
+	err := r.db.WithContext(ctx).
+		Where("offer_id = ? AND id IN ? AND is_active = ?", offerID, ids, true).
+		Order("name").
+		Find(&list).Error
+	return list, err
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AvailabilitySlotRepository struct {
@@ -16,6 +18,11 @@ func NewAvailabilitySlotRepository(db *gorm.DB) *AvailabilitySlotRepository {
 	return &AvailabilitySlotRepository{db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *AvailabilitySlotRepository) WithTx(tx *gorm.DB) *AvailabilitySlotRepository {
+	return &AvailabilitySlotRepository{tx}
+}
+
 func (r *AvailabilitySlotRepository) Create(ctx context.Context, slot *models.AvailabilitySlot) error {
 	return r.db.WithContext(ctx).Create(slot).Error
 }
@@ -28,6 +35,26 @@ func (r *AvailabilitySlotRepository) FindByID(ctx context.Context, id any) (*mod
 	return &slot, nil
 }
 
+// ListByIDs returns the slots with the given IDs, deleted ones included.
+func (r *AvailabilitySlotRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]models.AvailabilitySlot, error) {
+	var slots []models.AvailabilitySlot
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&slots).Error
+	return slots, err
+}
+
+// FindByIDForUpdate loads a slot and locks its row until the surrounding
+// transaction ends.
+func (r *AvailabilitySlotRepository) FindByIDForUpdate(ctx context.Context, id any) (*models.AvailabilitySlot, error) {
+	var slot models.AvailabilitySlot
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&slot, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
 func (r *AvailabilitySlotRepository) ListByOffer(ctx context.Context, offerID any, onlyAvailable bool, from, to time.Time) ([]models.AvailabilitySlot, error) {
 	q := r.db.WithContext(ctx).
 		Where("offer_id = ?", offerID).
@@ -35,7 +62,7 @@ func (r *AvailabilitySlotRepository) ListByOffer(ctx context.Context, offerID an
 		Order("start_time ASC")
 
 	if onlyAvailable {
-		q = q.Where("is_booked = ?", false)
+		q = q.Where("is_booked = ? AND blocked_by IS NULL", false)
 	}
 
 	var slots []models.AvailabilitySlot
@@ -45,10 +72,82 @@ func (r *AvailabilitySlotRepository) ListByOffer(ctx context.Context, offerID an
 	return slots, nil
 }
 
+// OccupiedSlot is a slot with at least one seat taken or held, together with
+// the buffers of its offer.
+type OccupiedSlot struct {
+	ID              uuid.UUID
+	StartTime       time.Time
+	EndTime         time.Time
+	BufferBeforeMin int
+	BufferAfterMin  int
+}
+
+// ListOccupiedByFreelancer returns the occupied slots of all the freelancer's
+// offers that overlap [from, to).
+func (r *AvailabilitySlotRepository) ListOccupiedByFreelancer(ctx context.Context, freelancerID any, from, to time.Time) ([]OccupiedSlot, error) {
+	var out []OccupiedSlot
+	err := r.db.WithContext(ctx).
+		Model(&models.AvailabilitySlot{}).
+		Select("availability_slots.id, availability_slots.start_time, availability_slots.end_time, "+
+			"service_offers.buffer_before_min, service_offers.buffer_after_min").
+		Joins("JOIN service_offers ON service_offers.id = availability_slots.offer_id").
+		Where("service_offers.freelancer_id = ?", freelancerID).
+		Where("availability_slots.booked_count + availability_slots.held_count > 0").
+		Where("availability_slots.start_time < ? AND availability_slots.end_time > ?", to, from).
+		Scan(&out).Error
+	return out, err
+}
+
+// ListBlockable returns the freelancer's slots starting in [from, to) that
+// nobody booked or holds and that are free or already blocked by importID.
+func (r *AvailabilitySlotRepository) ListBlockable(ctx context.Context, freelancerID, importID any, from, to time.Time) ([]models.AvailabilitySlot, error) {
+	var slots []models.AvailabilitySlot
+	err := r.db.WithContext(ctx).
+		Joins("JOIN service_offers ON service_offers.id = availability_slots.offer_id").
+		Where("service_offers.freelancer_id = ?", freelancerID).
+		Where("availability_slots.booked_count + availability_slots.held_count = 0").
+		Where("availability_slots.blocked_by IS NULL OR availability_slots.blocked_by = ?", importID).
+		Where("availability_slots.start_time >= ? AND availability_slots.start_time < ?", from, to).
+		Find(&slots).Error
+	return slots, err
+}
+
+// Unblock frees every slot blocked by importID.
+func (r *AvailabilitySlotRepository) Unblock(ctx context.Context, importID any) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AvailabilitySlot{}).
+		Where("blocked_by = ?", importID).
+		Update("blocked_by", nil).Error
+}
+
+// SetBlockedBy blocks the slot for importID, or frees it when importID is
+// nil. Only blocked_by is written, and a slot is blocked only while nobody
+// booked or holds it, so a booking made since the slot was read wins. It
+// reports whether the slot changed.
+func (r *AvailabilitySlotRepository) SetBlockedBy(ctx context.Context, id any, importID *uuid.UUID) (bool, error) {
+	q := r.db.WithContext(ctx).Model(&models.AvailabilitySlot{}).Where("id = ?", id)
+	var value any
+	if importID != nil {
+		q = q.Where("booked_count + held_count = 0")
+		value = *importID
+	}
+	res := q.Update("blocked_by", value)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *AvailabilitySlotRepository) Update(ctx context.Context, slot *models.AvailabilitySlot) error {
 	return r.db.WithContext(ctx).Save(slot).Error
 }
 
+// UpdateSchedule writes the slot's times, capacity and whether it is closed,
+// leaving its seat counts alone.
+func (r *AvailabilitySlotRepository) UpdateSchedule(ctx context.Context, slot *models.AvailabilitySlot) error {
+	return r.db.WithContext(ctx).
+		Model(slot).
+		Select("start_time", "end_time", "capacity", "is_booked").
+		Updates(slot).Error
+}
+
 func (r *AvailabilitySlotRepository) Delete(ctx context.Context, id any) error {
 	return r.db.WithContext(ctx).Delete(&models.AvailabilitySlot{}, "id = ?", id).Error
 }
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/shardy678/pet-freelance/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingRepository struct {
@@ -15,6 +17,11 @@ func NewBookingRepository(db *gorm.DB) *BookingRepository {
 	return &BookingRepository{db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *BookingRepository) WithTx(tx *gorm.DB) *BookingRepository {
+	return &BookingRepository{tx}
+}
+
 func (r *BookingRepository) Create(ctx context.Context, b *models.Booking) error {
 	return r.db.WithContext(ctx).Create(b).Error
 }
@@ -27,6 +34,33 @@ func (r *BookingRepository) FindByID(ctx context.Context, id any) (*models.Booki
 	return &b, nil
 }
 
+// FindByIDForUpdate loads a booking and locks its row until the surrounding
+// transaction ends.
+func (r *BookingRepository) FindByIDForUpdate(ctx context.Context, id any) (*models.Booking, error) {
+	var b models.Booking
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&b, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *BookingRepository) Update(ctx context.Context, b *models.Booking) error {
+	return r.db.WithContext(ctx).Save(b).Error
+}
+
+// CountByOwner returns how many bookings the owner made that were not
+// cancelled.
+func (r *BookingRepository) CountByOwner(ctx context.Context, ownerID any) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.Booking{}).
+		Where("owner_id = ? AND status <> ?", ownerID, models.BookingStatusCancelled).
+		Count(&n).Error
+	return n, err
+}
+
 func (r *BookingRepository) ListByOwner(ctx context.Context, ownerID any) ([]models.Booking, error) {
 	var list []models.Booking
 	err := r.db.WithContext(ctx).
@@ -44,3 +78,55 @@ func (r *BookingRepository) ListByOffer(ctx context.Context, offerID any) ([]mod
 		Find(&list).Error
 	return list, err
 }
+
+// ListBySeries returns a series' bookings in slot order.
+func (r *BookingRepository) ListBySeries(ctx context.Context, seriesID any) ([]models.Booking, error) {
+	var list []models.Booking
+	err := r.db.WithContext(ctx).
+		Joins("JOIN availability_slots ON availability_slots.id = bookings.slot_id").
+		Where("bookings.series_id = ?", seriesID).
+		Order("availability_slots.start_time asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListActiveByFreelancer returns the active bookings of all the
+// freelancer's offers whose slot or stay overlaps [from, to).
+func (r *BookingRepository) ListActiveByFreelancer(ctx context.Context, freelancerID any, from, to time.Time) ([]models.Booking, error) {
+	var list []models.Booking
+	err := r.db.WithContext(ctx).
+		Joins("JOIN service_offers ON service_offers.id = bookings.offer_id").
+		Joins("LEFT JOIN availability_slots ON availability_slots.id = bookings.slot_id").
+		Where("service_offers.freelancer_id = ?", freelancerID).
+		Where("bookings.status IN ?", models.ActiveBookingStatuses).
+		Where("(availability_slots.start_time < ? AND availability_slots.end_time > ?) OR "+
+			"(bookings.check_in < ? AND bookings.check_out > ?)", to, from, to, from).
+		Order("bookings.created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListByParty returns the bookings the user made or takes as freelancer whose
+// slot or stay ends after since.
+func (r *BookingRepository) ListByParty(ctx context.Context, userID any, since time.Time) ([]models.Booking, error) {
+	var list []models.Booking
+	err := r.db.WithContext(ctx).
+		Joins("JOIN service_offers ON service_offers.id = bookings.offer_id").
+		Joins("LEFT JOIN availability_slots ON availability_slots.id = bookings.slot_id").
+		Where("bookings.owner_id = ? OR service_offers.freelancer_id = ?", userID, userID).
+		Where("availability_slots.end_time > ? OR bookings.check_out > ?", since, since).
+		Order("bookings.created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListAwaitingPayment returns the bookings still waiting for their up-front
+// payment that were made before the given time.
+func (r *BookingRepository) ListAwaitingPayment(ctx context.Context, before time.Time) ([]models.Booking, error) {
+	var list []models.Booking
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", models.BookingStatusAwaitingPayment, before).
+		Order("created_at asc").
+		Find(&list).Error
+	return list, err
+}
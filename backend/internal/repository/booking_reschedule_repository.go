@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type BookingRescheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingRescheduleRepository(db *gorm.DB) *BookingRescheduleRepository {
+	return &BookingRescheduleRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *BookingRescheduleRepository) WithTx(tx *gorm.DB) *BookingRescheduleRepository {
+	return &BookingRescheduleRepository{tx}
+}
+
+func (r *BookingRescheduleRepository) Create(ctx context.Context, rs *models.BookingReschedule) error {
+	return r.db.WithContext(ctx).Create(rs).Error
+}
+
+func (r *BookingRescheduleRepository) Update(ctx context.Context, rs *models.BookingReschedule) error {
+	return r.db.WithContext(ctx).Save(rs).Error
+}
+
+// FindProposed returns the booking's open reschedule proposal.
+func (r *BookingRescheduleRepository) FindProposed(ctx context.Context, bookingID any) (*models.BookingReschedule, error) {
+	var rs models.BookingReschedule
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ? AND status = ?", bookingID, models.RescheduleProposed).
+		First(&rs).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// ListByBooking returns the booking's slot history, oldest first.
+func (r *BookingRescheduleRepository) ListByBooking(ctx context.Context, bookingID any) ([]models.BookingReschedule, error) {
+	var list []models.BookingReschedule
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ?", bookingID).
+		Order("created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// CountApplied returns how often each of the bookings was moved.
+func (r *BookingRescheduleRepository) CountApplied(ctx context.Context, bookingIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		BookingID uuid.UUID
+		N         int
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.BookingReschedule{}).
+		Select("booking_id, count(*) as n").
+		Where("booking_id IN ? AND status = ?", bookingIDs, models.RescheduleApplied).
+		Group("booking_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		counts[row.BookingID] = row.N
+	}
+	return counts, nil
+}
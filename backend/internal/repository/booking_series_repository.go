@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type BookingSeriesRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingSeriesRepository(db *gorm.DB) *BookingSeriesRepository {
+	return &BookingSeriesRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *BookingSeriesRepository) WithTx(tx *gorm.DB) *BookingSeriesRepository {
+	return &BookingSeriesRepository{tx}
+}
+
+func (r *BookingSeriesRepository) Create(ctx context.Context, s *models.BookingSeries) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *BookingSeriesRepository) FindByID(ctx context.Context, id any) (*models.BookingSeries, error) {
+	var s models.BookingSeries
+	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *BookingSeriesRepository) Update(ctx context.Context, s *models.BookingSeries) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+func (r *BookingSeriesRepository) ListByOwner(ctx context.Context, ownerID any) ([]models.BookingSeries, error) {
+	var list []models.BookingSeries
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at desc").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type CalendarFeedRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarFeedRepository(db *gorm.DB) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db}
+}
+
+func (r *CalendarFeedRepository) FindByUser(ctx context.Context, userID any) (*models.CalendarFeed, error) {
+	var f models.CalendarFeed
+	if err := r.db.WithContext(ctx).First(&f, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *CalendarFeedRepository) FindByToken(ctx context.Context, token string) (*models.CalendarFeed, error) {
+	var f models.CalendarFeed
+	if err := r.db.WithContext(ctx).First(&f, "token = ?", token).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *CalendarFeedRepository) Save(ctx context.Context, f *models.CalendarFeed) error {
+	return r.db.WithContext(ctx).Save(f).Error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type CalendarImportRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarImportRepository(db *gorm.DB) *CalendarImportRepository {
+	return &CalendarImportRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CalendarImportRepository) WithTx(tx *gorm.DB) *CalendarImportRepository {
+	return &CalendarImportRepository{tx}
+}
+
+func (r *CalendarImportRepository) Create(ctx context.Context, c *models.CalendarImport) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *CalendarImportRepository) FindByID(ctx context.Context, id any) (*models.CalendarImport, error) {
+	var c models.CalendarImport
+	if err := r.db.WithContext(ctx).First(&c, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CalendarImportRepository) Update(ctx context.Context, c *models.CalendarImport) error {
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+func (r *CalendarImportRepository) Delete(ctx context.Context, id any) error {
+	return r.db.WithContext(ctx).Delete(&models.CalendarImport{}, "id = ?", id).Error
+}
+
+func (r *CalendarImportRepository) ListByFreelancer(ctx context.Context, freelancerID any) ([]models.CalendarImport, error) {
+	var list []models.CalendarImport
+	err := r.db.WithContext(ctx).
+		Where("freelancer_id = ?", freelancerID).
+		Order("created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListDue returns the imports whose next sync is at or before now.
+func (r *CalendarImportRepository) ListDue(ctx context.Context, now time.Time) ([]models.CalendarImport, error) {
+	var list []models.CalendarImport
+	err := r.db.WithContext(ctx).
+		Where("next_sync_at <= ?", now).
+		Order("next_sync_at asc").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CouponRepository stores coupons and the bookings they were redeemed on.
+type CouponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) *CouponRepository {
+	return &CouponRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CouponRepository) WithTx(tx *gorm.DB) *CouponRepository {
+	return &CouponRepository{tx}
+}
+
+func (r *CouponRepository) Create(ctx context.Context, c *models.Coupon) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *CouponRepository) Update(ctx context.Context, c *models.Coupon) error {
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+func (r *CouponRepository) FindByID(ctx context.Context, id any) (*models.Coupon, error) {
+	var c models.Coupon
+	if err := r.db.WithContext(ctx).First(&c, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CouponRepository) FindByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	var c models.Coupon
+	if err := r.db.WithContext(ctx).First(&c, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindByCodeForUpdate loads the coupon with the given code and locks its
+// row until the surrounding transaction ends.
+func (r *CouponRepository) FindByCodeForUpdate(ctx context.Context, code string) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&c, "code = ?", code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindByIDForUpdate loads a coupon and locks its row until the surrounding
+// transaction ends.
+func (r *CouponRepository) FindByIDForUpdate(ctx context.Context, id any) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&c, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns every coupon, newest first.
+func (r *CouponRepository) List(ctx context.Context) ([]models.Coupon, error) {
+	var list []models.Coupon
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&list).Error
+	return list, err
+}
+
+func (r *CouponRepository) CreateRedemption(ctx context.Context, red *models.CouponRedemption) error {
+	return r.db.WithContext(ctx).Create(red).Error
+}
+
+// DeleteRedemption removes the booking's redemption, if it has one.
+func (r *CouponRepository) DeleteRedemption(ctx context.Context, bookingID any) error {
+	return r.db.WithContext(ctx).Delete(&models.CouponRedemption{}, "booking_id = ?", bookingID).Error
+}
+
+// CountRedemptions returns how many times the user redeemed the coupon.
+func (r *CouponRepository) CountRedemptions(ctx context.Context, couponID, userID any) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&n).Error
+	return n, err
+}
+
+// ListRedemptions returns the coupon's redemptions, newest first.
+func (r *CouponRepository) ListRedemptions(ctx context.Context, couponID any) ([]models.CouponRedemption, error) {
+	var list []models.CouponRedemption
+	err := r.db.WithContext(ctx).
+		Where("coupon_id = ?", couponID).
+		Order("created_at desc").
+		Find(&list).Error
+	return list, err
+}
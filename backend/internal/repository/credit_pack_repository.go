@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreditPackRepository stores the packages freelancers sell and the credit
+// packs owners bought.
+type CreditPackRepository struct {
+	db *gorm.DB
+}
+
+func NewCreditPackRepository(db *gorm.DB) *CreditPackRepository {
+	return &CreditPackRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CreditPackRepository) WithTx(tx *gorm.DB) *CreditPackRepository {
+	return &CreditPackRepository{tx}
+}
+
+func (r *CreditPackRepository) CreatePackage(ctx context.Context, p *models.Package) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *CreditPackRepository) UpdatePackage(ctx context.Context, p *models.Package) error {
+	return r.db.WithContext(ctx).Save(p).Error
+}
+
+func (r *CreditPackRepository) FindPackage(ctx context.Context, id any) (*models.Package, error) {
+	var p models.Package
+	if err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPackages returns the offer's packages, smallest first, only the active
+// ones unless all is set.
+func (r *CreditPackRepository) ListPackages(ctx context.Context, offerID any, all bool) ([]models.Package, error) {
+	var list []models.Package
+	q := r.db.WithContext(ctx).Where("offer_id = ?", offerID)
+	if !all {
+		q = q.Where("is_active = ?", true)
+	}
+	err := q.Order("credits, name").Find(&list).Error
+	return list, err
+}
+
+func (r *CreditPackRepository) Create(ctx context.Context, p *models.CreditPack) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *CreditPackRepository) Update(ctx context.Context, p *models.CreditPack) error {
+	return r.db.WithContext(ctx).Save(p).Error
+}
+
+// FindByIDForUpdate loads a credit pack and locks its row until the
+// surrounding transaction ends.
+func (r *CreditPackRepository) FindByIDForUpdate(ctx context.Context, id any) (*models.CreditPack, error) {
+	var p models.CreditPack
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&p, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListByOwner returns the owner's credit packs, newest first.
+func (r *CreditPackRepository) ListByOwner(ctx context.Context, ownerID any) ([]models.CreditPack, error) {
+	var list []models.CreditPack
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at desc").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateRepository(db *gorm.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db}
+}
+
+// Replace swaps the whole rate table for rates in one transaction.
+func (r *ExchangeRateRepository) Replace(ctx context.Context, rates []models.ExchangeRate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ExchangeRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rates).Error
+	})
+}
+
+// Seed stores rates as the rate table if it is empty, and reports whether
+// it did.
+func (r *ExchangeRateRepository) Seed(ctx context.Context, rates []models.ExchangeRate) (bool, error) {
+	seeded := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.ExchangeRate{}).Count(&n).Error; err != nil || n > 0 {
+			return err
+		}
+		seeded = true
+		return tx.Create(&rates).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return seeded, nil
+}
+
+// List returns the rate table by currency.
+func (r *ExchangeRateRepository) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	var list []models.ExchangeRate
+	err := r.db.WithContext(ctx).Order("currency").Find(&list).Error
+	return list, err
+}
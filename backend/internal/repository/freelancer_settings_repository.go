@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FreelancerSettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewFreelancerSettingsRepository(db *gorm.DB) *FreelancerSettingsRepository {
+	return &FreelancerSettingsRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *FreelancerSettingsRepository) WithTx(tx *gorm.DB) *FreelancerSettingsRepository {
+	return &FreelancerSettingsRepository{tx}
+}
+
+// Find returns the freelancer's settings, or the defaults if none were saved.
+func (r *FreelancerSettingsRepository) Find(ctx context.Context, userID uuid.UUID) (*models.FreelancerSettings, error) {
+	var s models.FreelancerSettings
+	err := r.db.WithContext(ctx).First(&s, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.FreelancerSettings{UserID: userID, Timezone: "UTC"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Lock locks the freelancer's settings row until the transaction ends,
+// creating it with the defaults first if none were saved. Holding it
+// serializes everything that books the freelancer's time.
+func (r *FreelancerSettingsRepository) Lock(ctx context.Context, userID uuid.UUID) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.FreelancerSettings{UserID: userID, Timezone: "UTC"}).Error; err != nil {
+		return err
+	}
+	var s models.FreelancerSettings
+	return db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, "user_id = ?", userID).Error
+}
+
+// Save stores the settings the freelancer makes; their commission is left
+// as it is.
+func (r *FreelancerSettingsRepository) Save(ctx context.Context, s *models.FreelancerSettings) error {
+	return r.db.WithContext(ctx).Omit("commission_bps").Save(s).Error
+}
+
+// SetCommission sets the commission agreed with the freelancer in basis
+// points; nil leaves it to the service and the platform default.
+func (r *FreelancerSettingsRepository) SetCommission(ctx context.Context, userID uuid.UUID, bps *int) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.FreelancerSettings{UserID: userID, Timezone: "UTC"}).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.FreelancerSettings{}).
+		Where("user_id = ?", userID).
+		Update("commission_bps", bps).Error
+}
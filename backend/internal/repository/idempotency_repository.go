@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db}
+}
+
+// Find returns the stored key for a user, or gorm.ErrRecordNotFound.
+func (r *IdempotencyRepository) Find(ctx context.Context, userID, key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		First(&k).Error
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Reserve inserts k unless the same user already holds that key. It reports
+// whether the row was inserted.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, k *models.IdempotencyKey) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(k)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// Complete stores the response captured for a reserved key.
+func (r *IdempotencyRepository) Complete(ctx context.Context, k *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).
+		Model(k).
+		Updates(map[string]any{
+			"completed":    true,
+			"status_code":  k.StatusCode,
+			"content_type": k.ContentType,
+			"response":     k.Response,
+		}).Error
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, id any) error {
+	return r.db.WithContext(ctx).Delete(&models.IdempotencyKey{}, "id = ?", id).Error
+}
+
+// DeleteExpired removes every key whose TTL ended before now.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{}).Error
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InvoiceRepository stores invoices and credit notes. It can only add them;
+// issued documents are never changed.
+type InvoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *InvoiceRepository) WithTx(tx *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{tx}
+}
+
+// NextSeq takes the freelancer's next number for the kind of document. The
+// counter stays locked until the surrounding transaction ends, so numbers
+// are handed out one at a time and a rolled back document leaves no gap.
+func (r *InvoiceRepository) NextSeq(ctx context.Context, freelancerID uuid.UUID, kind string) (int, error) {
+	db := r.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.InvoiceCounter{FreelancerID: freelancerID, Kind: kind}).Error
+	if err != nil {
+		return 0, err
+	}
+	var c models.InvoiceCounter
+	err = db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&c, "freelancer_id = ? AND kind = ?", freelancerID, kind).Error
+	if err != nil {
+		return 0, err
+	}
+	c.Last++
+	err = db.Model(&models.InvoiceCounter{}).
+		Where("freelancer_id = ? AND kind = ?", freelancerID, kind).
+		Update("last", c.Last).Error
+	if err != nil {
+		return 0, err
+	}
+	return c.Last, nil
+}
+
+func (r *InvoiceRepository) Create(ctx context.Context, i *models.Invoice) error {
+	return r.db.WithContext(ctx).Create(i).Error
+}
+
+func (r *InvoiceRepository) FindByID(ctx context.Context, id any) (*models.Invoice, error) {
+	var i models.Invoice
+	if err := r.db.WithContext(ctx).First(&i, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// FindInvoice returns the invoice issued for the booking.
+func (r *InvoiceRepository) FindInvoice(ctx context.Context, bookingID any) (*models.Invoice, error) {
+	var i models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ? AND kind = ?", bookingID, models.InvoiceKindInvoice).
+		First(&i).Error
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// ListByBooking returns the booking's invoice and credit notes in the order
+// they were issued.
+func (r *InvoiceRepository) ListByBooking(ctx context.Context, bookingID any) ([]models.Invoice, error) {
+	var list []models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ?", bookingID).
+		Order("issued_at, seq").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerRepository stores ledger accounts and journal entries. Entries are
+// only ever inserted.
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *LedgerRepository) WithTx(tx *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{tx}
+}
+
+// Account returns the account of the given kind, user and currency, opening
+// it on first use.
+func (r *LedgerRepository) Account(ctx context.Context, kind string, userID uuid.UUID, currency string) (*models.LedgerAccount, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LedgerAccount{Kind: kind, UserID: userID, Currency: currency}).Error; err != nil {
+		return nil, err
+	}
+	var a models.LedgerAccount
+	err := db.First(&a, "kind = ? AND user_id = ? AND currency = ?", kind, userID, currency).Error
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateEntry posts the entry together with its lines.
+func (r *LedgerRepository) CreateEntry(ctx context.Context, e *models.JournalEntry) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// ListAccountsByUser returns the user's accounts in every currency.
+func (r *LedgerRepository) ListAccountsByUser(ctx context.Context, userID uuid.UUID) ([]models.LedgerAccount, error) {
+	var list []models.LedgerAccount
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("kind, currency").
+		Find(&list).Error
+	return list, err
+}
+
+// ListAccounts returns every account.
+func (r *LedgerRepository) ListAccounts(ctx context.Context) ([]models.LedgerAccount, error) {
+	var list []models.LedgerAccount
+	err := r.db.WithContext(ctx).Order("kind, currency").Find(&list).Error
+	return list, err
+}
+
+// Balances sums the lines of the given accounts, debits minus credits.
+// Accounts without lines are missing from the result.
+func (r *LedgerRepository) Balances(ctx context.Context, accountIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		AccountID uuid.UUID
+		Total     int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.JournalLine{}).
+		Select("account_id, SUM(amount) AS total").
+		Where("account_id IN ?", accountIDs).
+		Group("account_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		out[row.AccountID] = row.Total
+	}
+	return out, nil
+}
+
+// UnbalancedEntries returns the IDs of entries whose lines do not sum to
+// zero or that have fewer than two lines.
+func (r *LedgerRepository) UnbalancedEntries(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Table("journal_entries AS e").
+		Joins("LEFT JOIN journal_lines AS l ON l.entry_id = e.id").
+		Group("e.id").
+		Having("COALESCE(SUM(l.amount), 0) <> 0 OR COUNT(l.id) < 2").
+		Pluck("e.id", &ids).Error
+	return ids, err
+}
+
+// MismatchedLines returns the IDs of lines booked on an account in another
+// currency than their entry, or on an account that does not exist.
+func (r *LedgerRepository) MismatchedLines(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Table("journal_lines AS l").
+		Joins("JOIN journal_entries AS e ON e.id = l.entry_id").
+		Joins("LEFT JOIN ledger_accounts AS a ON a.id = l.account_id").
+		Where("a.id IS NULL OR a.currency <> e.currency").
+		Pluck("l.id", &ids).Error
+	return ids, err
+}
+
+// CashByBooking sums, per booking, what its charges and refunds moved on
+// the platform's cash accounts.
+func (r *LedgerRepository) CashByBooking(ctx context.Context) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		BookingID uuid.UUID
+		Total     int64
+	}
+	err := r.db.WithContext(ctx).
+		Table("journal_lines AS l").
+		Select("e.booking_id, SUM(l.amount) AS total").
+		Joins("JOIN journal_entries AS e ON e.id = l.entry_id").
+		Joins("JOIN ledger_accounts AS a ON a.id = l.account_id").
+		Where("a.kind = ? AND e.kind IN ? AND e.booking_id IS NOT NULL",
+			models.LedgerCash, []string{models.JournalCharge, models.JournalRefund}).
+		Group("e.booking_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		out[row.BookingID] = row.Total
+	}
+	return out, nil
+}
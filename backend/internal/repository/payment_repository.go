@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PaymentRepository) WithTx(tx *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{tx}
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, p *models.Payment) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *PaymentRepository) Update(ctx context.Context, p *models.Payment) error {
+	return r.db.WithContext(ctx).Save(p).Error
+}
+
+// FindByIntentForUpdate locks the payment made through the provider's intent.
+func (r *PaymentRepository) FindByIntentForUpdate(ctx context.Context, intentID string) (*models.Payment, error) {
+	var p models.Payment
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&p, "intent_id = ?", intentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FindLatestByBooking returns the booking's most recent payment attempt.
+func (r *PaymentRepository) FindLatestByBooking(ctx context.Context, bookingID any) (*models.Payment, error) {
+	var p models.Payment
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ?", bookingID).
+		Order("created_at desc").
+		First(&p).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProviderActions returns the payments whose call to the provider is
+// still outstanding, oldest first.
+func (r *PaymentRepository) ListProviderActions(ctx context.Context) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.db.WithContext(ctx).
+		Where("provider_action <> ''").
+		Order("created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ClearProviderAction marks action as made on the payment, unless the
+// payment owes another one by now.
+func (r *PaymentRepository) ClearProviderAction(ctx context.Context, id any, action string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Where("id = ? AND provider_action = ?", id, action).
+		Update("provider_action", "").Error
+}
+
+// ListByBooking returns every payment attempt of the booking, oldest first.
+func (r *PaymentRepository) ListByBooking(ctx context.Context, bookingID any) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.db.WithContext(ctx).
+		Where("booking_id = ?", bookingID).
+		Order("created_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// RecordEvent stores a webhook event and reports whether it is new.
+func (r *PaymentRepository) RecordEvent(ctx context.Context, e *models.PaymentEvent) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+	return res.RowsAffected == 1, res.Error
+}
+
+// NetByBooking sums, per booking, what was captured minus what was refunded.
+func (r *PaymentRepository) NetByBooking(ctx context.Context) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		BookingID uuid.UUID
+		Total     int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Select("booking_id, SUM(amount - refunded) AS total").
+		Where("status IN ?", []string{models.PaymentCaptured, models.PaymentPartlyRefunded, models.PaymentRefunded}).
+		Group("booking_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		out[row.BookingID] = row.Total
+	}
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PayoutRepository stores freelancer earnings and the payouts batching them.
+type PayoutRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutRepository(db *gorm.DB) *PayoutRepository {
+	return &PayoutRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PayoutRepository) WithTx(tx *gorm.DB) *PayoutRepository {
+	return &PayoutRepository{tx}
+}
+
+// CreateEarning stores the earning unless the booking already has one, and
+// reports whether it did.
+func (r *PayoutRepository) CreateEarning(ctx context.Context, e *models.Earning) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+	return res.RowsAffected == 1, res.Error
+}
+
+// FindEarningForUpdate loads the booking's earning and locks its row until
+// the surrounding transaction ends.
+func (r *PayoutRepository) FindEarningForUpdate(ctx context.Context, bookingID any) (*models.Earning, error) {
+	var e models.Earning
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&e, "booking_id = ?", bookingID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *PayoutRepository) UpdateEarning(ctx context.Context, e *models.Earning) error {
+	return r.db.WithContext(ctx).Save(e).Error
+}
+
+// ListPayable returns the earnings available at now that are not part of a
+// payout yet, grouped by freelancer and currency.
+func (r *PayoutRepository) ListPayable(ctx context.Context, now time.Time) ([]models.Earning, error) {
+	var list []models.Earning
+	err := r.db.WithContext(ctx).
+		Where("payout_id IS NULL AND available_at <= ? AND net > 0", now).
+		Order("freelancer_id, currency, available_at").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *PayoutRepository) CreatePayout(ctx context.Context, p *models.Payout) error {
+	return r.db.WithContext(ctx).Omit("Earnings").Create(p).Error
+}
+
+func (r *PayoutRepository) UpdatePayout(ctx context.Context, p *models.Payout) error {
+	return r.db.WithContext(ctx).Omit("Earnings").Save(p).Error
+}
+
+// AttachEarnings batches the earnings into the payout.
+func (r *PayoutRepository) AttachEarnings(ctx context.Context, payoutID uuid.UUID, earningIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Earning{}).
+		Where("id IN ?", earningIDs).
+		Update("payout_id", payoutID).Error
+}
+
+// DetachEarnings takes the earnings out of a failed payout, so the next run
+// batches them again.
+func (r *PayoutRepository) DetachEarnings(ctx context.Context, payoutID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Earning{}).
+		Where("payout_id = ?", payoutID).
+		Update("payout_id", nil).Error
+}
+
+// Totals sums the freelancer's earnings per currency as of now.
+func (r *PayoutRepository) Totals(ctx context.Context, freelancerID uuid.UUID, now time.Time) ([]models.EarningTotals, error) {
+	var list []models.EarningTotals
+	err := r.db.WithContext(ctx).
+		Table("earnings AS e").
+		Select(`e.currency,
+			SUM(CASE WHEN p.status = ? THEN 0 WHEN e.available_at > ? THEN e.net ELSE 0 END) AS pending,
+			SUM(CASE WHEN p.status = ? THEN 0 WHEN e.available_at <= ? THEN e.net ELSE 0 END) AS available,
+			SUM(CASE WHEN p.status = ? THEN e.net ELSE 0 END) AS paid`,
+			models.PayoutPaid, now, models.PayoutPaid, now, models.PayoutPaid).
+		Joins("LEFT JOIN payouts AS p ON p.id = e.payout_id").
+		Where("e.freelancer_id = ?", freelancerID).
+		Group("e.currency").
+		Order("e.currency").
+		Scan(&list).Error
+	for i := range list {
+		t := &list[i]
+		t.Pending.Currency, t.Available.Currency, t.Paid.Currency = t.Currency, t.Currency, t.Currency
+	}
+	return list, err
+}
+
+// ListPayouts returns the freelancer's most recent payouts with their
+// earnings, newest first.
+func (r *PayoutRepository) ListPayouts(ctx context.Context, freelancerID uuid.UUID, limit int) ([]models.Payout, error) {
+	var list []models.Payout
+	err := r.db.WithContext(ctx).
+		Preload("Earnings").
+		Where("freelancer_id = ?", freelancerID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
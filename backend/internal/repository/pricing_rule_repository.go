@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type PricingRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewPricingRuleRepository(db *gorm.DB) *PricingRuleRepository {
+	return &PricingRuleRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PricingRuleRepository) WithTx(tx *gorm.DB) *PricingRuleRepository {
+	return &PricingRuleRepository{tx}
+}
+
+func (r *PricingRuleRepository) Create(ctx context.Context, rule *models.PricingRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// Delete removes the offer's rule and reports whether there was one.
+func (r *PricingRuleRepository) Delete(ctx context.Context, offerID, id any) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&models.PricingRule{}, "id = ? AND offer_id = ?", id, offerID)
+	return res.RowsAffected == 1, res.Error
+}
+
+// ListByOffer returns the offer's rules in the order they were added.
+func (r *PricingRuleRepository) ListByOffer(ctx context.Context, offerID any) ([]models.PricingRule, error) {
+	var list []models.PricingRule
+	err := r.db.WithContext(ctx).
+		Where("offer_id = ?", offerID).
+		Order("created_at").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reliabilityColumns maps each event kind to the counter it increments.
+var reliabilityColumns = map[string]string{
+	models.ReliabilityCompleted:              "completed",
+	models.ReliabilityNoShow:                 "no_shows",
+	models.ReliabilityLateCancellation:       "late_cancellations",
+	models.ReliabilityFreelancerCancellation: "freelancer_cancellations",
+}
+
+type ReliabilityRepository struct {
+	db *gorm.DB
+}
+
+func NewReliabilityRepository(db *gorm.DB) *ReliabilityRepository {
+	return &ReliabilityRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *ReliabilityRepository) WithTx(tx *gorm.DB) *ReliabilityRepository {
+	return &ReliabilityRepository{tx}
+}
+
+// Find returns the user's counters, all zero if nothing was recorded yet.
+func (r *ReliabilityRepository) Find(ctx context.Context, userID uuid.UUID) (*models.ReliabilityStats, error) {
+	var s models.ReliabilityStats
+	err := r.db.WithContext(ctx).First(&s, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.ReliabilityStats{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Record stores the event and bumps the matching counter.
+func (r *ReliabilityRepository) Record(ctx context.Context, e *models.ReliabilityEvent) error {
+	column, ok := reliabilityColumns[e.Kind]
+	if !ok {
+		return fmt.Errorf("unknown reliability event %q", e.Kind)
+	}
+	db := r.db.WithContext(ctx)
+	if err := db.Create(e).Error; err != nil {
+		return err
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ReliabilityStats{UserID: e.UserID}).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.ReliabilityStats{}).
+		Where("user_id = ?", e.UserID).
+		Update(column, gorm.Expr(column+" + 1")).Error
+}
+
+// ListEvents returns the user's most recent events, newest first.
+func (r *ReliabilityRepository) ListEvents(ctx context.Context, userID uuid.UUID, limit int) ([]models.ReliabilityEvent, error) {
+	var list []models.ReliabilityEvent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
@@ -13,6 +13,11 @@ func NewServiceOfferRepository(db *gorm.DB) *ServiceOfferRepository {
 	return &ServiceOfferRepository{db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *ServiceOfferRepository) WithTx(tx *gorm.DB) *ServiceOfferRepository {
+	return &ServiceOfferRepository{tx}
+}
+
 func (r *ServiceOfferRepository) Create(ctx context.Context, o *models.ServiceOffer) error {
 	return r.db.WithContext(ctx).Create(o).Error
 }
@@ -38,3 +38,13 @@ func (r *ServiceRepository) ListAll(ctx context.Context) ([]models.Service, erro
 		Find(&list).Error
 	return list, err
 }
+
+// SetCommission sets the service's commission in basis points; nil goes
+// back to the platform default. It reports whether the service exists.
+func (r *ServiceRepository) SetCommission(ctx context.Context, id any, bps *int) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Service{}).
+		Where("id = ?", id).
+		Update("commission_bps", bps)
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StayNightRepository struct {
+	db *gorm.DB
+}
+
+func NewStayNightRepository(db *gorm.DB) *StayNightRepository {
+	return &StayNightRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *StayNightRepository) WithTx(tx *gorm.DB) *StayNightRepository {
+	return &StayNightRepository{tx}
+}
+
+func (r *StayNightRepository) Create(ctx context.Context, n *models.StayNight) error {
+	return r.db.WithContext(ctx).Create(n).Error
+}
+
+func (r *StayNightRepository) Update(ctx context.Context, n *models.StayNight) error {
+	return r.db.WithContext(ctx).Save(n).Error
+}
+
+// ListByOffer returns the offer's nights in [from, to), in date order.
+func (r *StayNightRepository) ListByOffer(ctx context.Context, offerID any, from, to time.Time) ([]models.StayNight, error) {
+	var list []models.StayNight
+	err := r.db.WithContext(ctx).
+		Where("offer_id = ? AND date >= ? AND date < ?", offerID, from, to).
+		Order("date asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListByOfferForUpdate is ListByOffer with the rows locked until the
+// surrounding transaction ends.
+func (r *StayNightRepository) ListByOfferForUpdate(ctx context.Context, offerID any, from, to time.Time) ([]models.StayNight, error) {
+	var list []models.StayNight
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("offer_id = ? AND date >= ? AND date < ?", offerID, from, to).
+		Order("date asc").
+		Find(&list).Error
+	return list, err
+}
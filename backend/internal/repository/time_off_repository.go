@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type TimeOffRepository struct {
+	db *gorm.DB
+}
+
+func NewTimeOffRepository(db *gorm.DB) *TimeOffRepository {
+	return &TimeOffRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *TimeOffRepository) WithTx(tx *gorm.DB) *TimeOffRepository {
+	return &TimeOffRepository{tx}
+}
+
+func (r *TimeOffRepository) Create(ctx context.Context, t *models.TimeOff) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *TimeOffRepository) FindByID(ctx context.Context, id any) (*models.TimeOff, error) {
+	var t models.TimeOff
+	if err := r.db.WithContext(ctx).First(&t, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TimeOffRepository) Delete(ctx context.Context, id any) error {
+	return r.db.WithContext(ctx).Delete(&models.TimeOff{}, "id = ?", id).Error
+}
+
+// ListByFreelancer returns the freelancer's time off ending after since.
+func (r *TimeOffRepository) ListByFreelancer(ctx context.Context, freelancerID any, since time.Time) ([]models.TimeOff, error) {
+	var list []models.TimeOff
+	err := r.db.WithContext(ctx).
+		Where("freelancer_id = ? AND ends_at > ?", freelancerID, since).
+		Order("starts_at asc").
+		Find(&list).Error
+	return list, err
+}
+
+// ListOverlapping returns the freelancer's time off overlapping [from, to).
+func (r *TimeOffRepository) ListOverlapping(ctx context.Context, freelancerID any, from, to time.Time) ([]models.TimeOff, error) {
+	var list []models.TimeOff
+	err := r.db.WithContext(ctx).
+		Where("freelancer_id = ? AND starts_at < ? AND ends_at > ?", freelancerID, to, from).
+		Order("starts_at asc").
+		Find(&list).Error
+	return list, err
+}
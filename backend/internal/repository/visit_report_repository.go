@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VisitReportRepository struct {
+	db *gorm.DB
+}
+
+func NewVisitReportRepository(db *gorm.DB) *VisitReportRepository {
+	return &VisitReportRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *VisitReportRepository) WithTx(tx *gorm.DB) *VisitReportRepository {
+	return &VisitReportRepository{tx}
+}
+
+func (r *VisitReportRepository) Create(ctx context.Context, rep *models.VisitReport) error {
+	return r.db.WithContext(ctx).Omit("Photos").Create(rep).Error
+}
+
+func (r *VisitReportRepository) Update(ctx context.Context, rep *models.VisitReport) error {
+	return r.db.WithContext(ctx).Omit("Photos").Save(rep).Error
+}
+
+// FindByBooking returns the booking's report with its photos, oldest first.
+func (r *VisitReportRepository) FindByBooking(ctx context.Context, bookingID any) (*models.VisitReport, error) {
+	var rep models.VisitReport
+	err := r.db.WithContext(ctx).
+		Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		First(&rep, "booking_id = ?", bookingID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+// FindByBookingForUpdate locks the booking's report for the rest of the
+// transaction.
+func (r *VisitReportRepository) FindByBookingForUpdate(ctx context.Context, bookingID any) (*models.VisitReport, error) {
+	var rep models.VisitReport
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&rep, "booking_id = ?", bookingID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+func (r *VisitReportRepository) AddPhoto(ctx context.Context, p *models.VisitPhoto) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *VisitReportRepository) CountPhotos(ctx context.Context, reportID any) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.VisitPhoto{}).Where("report_id = ?", reportID).Count(&n).Error
+	return n, err
+}
+
+func (r *VisitReportRepository) FindPhoto(ctx context.Context, reportID, photoID any) (*models.VisitPhoto, error) {
+	var p models.VisitPhoto
+	if err := r.db.WithContext(ctx).First(&p, "id = ? AND report_id = ?", photoID, reportID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
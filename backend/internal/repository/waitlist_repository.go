@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) *WaitlistRepository {
+	return &WaitlistRepository{db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *WaitlistRepository) WithTx(tx *gorm.DB) *WaitlistRepository {
+	return &WaitlistRepository{tx}
+}
+
+func (r *WaitlistRepository) Create(ctx context.Context, w *models.WaitlistEntry) error {
+	return r.db.WithContext(ctx).Create(w).Error
+}
+
+func (r *WaitlistRepository) Update(ctx context.Context, w *models.WaitlistEntry) error {
+	return r.db.WithContext(ctx).Save(w).Error
+}
+
+// FindOpen returns the owner's waiting or offered entry for a slot, locked
+// until the surrounding transaction ends.
+func (r *WaitlistRepository) FindOpen(ctx context.Context, slotID, ownerID any) (*models.WaitlistEntry, error) {
+	var w models.WaitlistEntry
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("slot_id = ? AND owner_id = ? AND status IN ?", slotID, ownerID,
+			[]string{models.WaitlistWaiting, models.WaitlistOffered}).
+		First(&w).Error
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// NextWaiting returns the slot's longest-waiting entry, locked until the
+// surrounding transaction ends.
+func (r *WaitlistRepository) NextWaiting(ctx context.Context, slotID any) (*models.WaitlistEntry, error) {
+	var w models.WaitlistEntry
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("slot_id = ? AND status = ?", slotID, models.WaitlistWaiting).
+		Order("created_at asc").
+		First(&w).Error
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// CountAhead counts the waiting entries queued before w.
+func (r *WaitlistRepository) CountAhead(ctx context.Context, w *models.WaitlistEntry) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&models.WaitlistEntry{}).
+		Where("slot_id = ? AND status = ? AND created_at < ?", w.SlotID, models.WaitlistWaiting, w.CreatedAt).
+		Count(&n).Error
+	return n, err
+}
+
+// ListExpiredOffers returns offered entries whose claim window closed before now.
+func (r *WaitlistRepository) ListExpiredOffers(ctx context.Context, now time.Time) ([]models.WaitlistEntry, error) {
+	var list []models.WaitlistEntry
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND offer_expires_at < ?", models.WaitlistOffered, now).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *WaitlistRepository) FindByIDForUpdate(ctx context.Context, id any) (*models.WaitlistEntry, error) {
+	var w models.WaitlistEntry
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&w, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
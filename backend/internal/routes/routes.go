@@ -1,18 +1,27 @@
 package routes
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/shardy678/pet-freelance/backend/internal/config"
 	"github.com/shardy678/pet-freelance/backend/internal/db"
 	"github.com/shardy678/pet-freelance/backend/internal/handlers"
 	"github.com/shardy678/pet-freelance/backend/internal/middleware"
+	"github.com/shardy678/pet-freelance/backend/internal/payments"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"github.com/shardy678/pet-freelance/backend/internal/secret"
 	"github.com/shardy678/pet-freelance/backend/internal/service"
 )
 
 func SetupRoutes(r *gin.Engine) {
 	cfg := config.Load()
+	if err := cfg.Check(); err != nil {
+		log.Fatalf("routes: %v", err)
+	}
 
 	// Repositories & services
 	userRepo := repository.NewUserRepository(db.DB)
@@ -20,22 +29,99 @@ func SetupRoutes(r *gin.Engine) {
 	offerRepo := repository.NewServiceOfferRepository(db.DB)
 	serviceRepo := repository.NewServiceRepository(db.DB)
 	slotRepo := repository.NewAvailabilitySlotRepository(db.DB)
+	settingsRepo := repository.NewFreelancerSettingsRepository(db.DB)
+	timeOffRepo := repository.NewTimeOffRepository(db.DB)
+	reliabilityRepo := repository.NewReliabilityRepository(db.DB)
 
 	// Handlers
 	authH := handlers.NewAuthHandler(authSvc)
-	profH := handlers.NewProfileHandler(userRepo)
-	offerSvc := service.NewServiceOfferService(offerRepo)
+	profH := handlers.NewProfileHandler(userRepo, reliabilityRepo)
+	ratesSvc := service.NewExchangeRateService(repository.NewExchangeRateRepository(db.DB))
+	ratesH := handlers.NewExchangeRateHandler(ratesSvc)
+	offerSvc := service.NewServiceOfferService(offerRepo, ratesSvc)
 	offerH := handlers.NewServiceOfferHandler(offerRepo, offerSvc)
 	serviceH := handlers.NewServiceHandler(service.NewServiceService(serviceRepo))
-	slotH := handlers.NewAvailabilitySlotHandler(service.NewAvailabilitySlotService(slotRepo))
+	slotSvc := service.NewAvailabilitySlotService(slotRepo, offerRepo, settingsRepo, timeOffRepo, db.DB)
+	slotH := handlers.NewAvailabilitySlotHandler(slotSvc)
 
 	activityRepo := repository.NewActivityRepository(db.DB)
 	activitySvc := service.NewActivityService(activityRepo)
 	activityH := handlers.NewActivityHandler(activitySvc)
+	settingsH := handlers.NewFreelancerSettingsHandler(settingsRepo)
 
 	bookingRepo := repository.NewBookingRepository(db.DB)
-	bookingSvc := service.NewBookingService(bookingRepo, slotRepo, activitySvc, db.DB)
-	bookingH := handlers.NewBookingHandler(bookingSvc)
+	rescheduleRepo := repository.NewBookingRescheduleRepository(db.DB)
+	nightRepo := repository.NewStayNightRepository(db.DB)
+	reportRepo := repository.NewVisitReportRepository(db.DB)
+	paymentRepo := repository.NewPaymentRepository(db.DB)
+	bookingSvc := service.NewBookingService(bookingRepo, slotRepo, offerRepo, rescheduleRepo, nightRepo, reportRepo, paymentRepo, settingsRepo, timeOffRepo, reliabilityRepo, activitySvc, db.DB)
+	addOnRepo := repository.NewAddOnRepository(db.DB)
+	creditPackRepo := repository.NewCreditPackRepository(db.DB)
+	pricingRuleRepo := repository.NewPricingRuleRepository(db.DB)
+	couponRepo := repository.NewCouponRepository(db.DB)
+	quoteH := handlers.NewQuoteHandler(service.NewQuoteService(bookingSvc, addOnRepo, creditPackRepo, pricingRuleRepo, couponRepo, cfg.TaxBps, cfg.ServiceFeeBps))
+	couponH := handlers.NewCouponHandler(service.NewCouponService(couponRepo))
+	pricingRuleH := handlers.NewPricingRuleHandler(service.NewPricingRuleService(offerRepo, pricingRuleRepo))
+	extrasH := handlers.NewOfferExtrasHandler(service.NewOfferExtrasService(offerRepo, addOnRepo, creditPackRepo))
+	seriesSvc := service.NewBookingSeriesService(bookingSvc, repository.NewBookingSeriesRepository(db.DB))
+	notesSvc := service.NewBookingNotesService(bookingSvc, secret.NewBox(cfg.DataKey))
+	bookingH := handlers.NewBookingHandler(bookingSvc, seriesSvc, notesSvc)
+	visitH := handlers.NewVisitHandler(service.NewVisitService(bookingSvc, reportRepo, cfg.UploadDir))
+	stayH := handlers.NewStayHandler(service.NewStayService(bookingSvc))
+	calendarH := handlers.NewCalendarHandler(service.NewCalendarService(
+		repository.NewCalendarFeedRepository(db.DB), bookingRepo, rescheduleRepo, offerRepo, settingsRepo, slotRepo, slotSvc))
+	importSvc := service.NewCalendarImportService(
+		repository.NewCalendarImportRepository(db.DB), slotRepo, settingsRepo, db.DB, cfg.CalendarImportInterval)
+	importH := handlers.NewCalendarImportHandler(importSvc)
+	timeOffH := handlers.NewTimeOffHandler(service.NewTimeOffService(timeOffRepo, bookingRepo, slotRepo, offerRepo))
+	waitlistSvc := service.NewWaitlistService(bookingSvc, repository.NewWaitlistRepository(db.DB), cfg.WaitlistOfferTTL)
+	waitlistH := handlers.NewWaitlistHandler(waitlistSvc)
+
+	// Without a provider, bookings that need no payment still work; the fake
+	// provider, which marks payments as made on request, is development only
+	provider, err := payments.New(cfg.PaymentProvider, cfg.PaymentWebhookSecret)
+	if err != nil {
+		log.Fatalf("routes: %v", err)
+	}
+	if cfg.PaymentProvider == "" {
+		log.Printf("payments: no PAYMENT_PROVIDER is set, payments are disabled")
+	}
+	fakePayments, _ := provider.(*payments.FakeProvider)
+	ledgerSvc := service.NewLedgerService(repository.NewLedgerRepository(db.DB), paymentRepo)
+	ledgerH := handlers.NewLedgerHandler(ledgerSvc)
+	paymentSvc := service.NewPaymentService(bookingSvc, paymentRepo, creditPackRepo, ledgerSvc, provider, cfg.PaymentTimeout)
+	paymentH := handlers.NewPaymentHandler(paymentSvc, fakePayments)
+	payoutSvc := service.NewPayoutService(bookingSvc, repository.NewPayoutRepository(db.DB), paymentRepo,
+		serviceRepo, settingsRepo, ledgerSvc, provider, cfg.CommissionBps, cfg.PayoutHold)
+	payoutH := handlers.NewPayoutHandler(payoutSvc)
+	invoiceH := handlers.NewInvoiceHandler(service.NewInvoiceService(bookingSvc,
+		repository.NewInvoiceRepository(db.DB), userRepo, settingsRepo, payoutSvc))
+
+	// Exchange rates for displaying prices can be seeded from a file; once
+	// there are rates, they are kept up to date through the admin API
+	if cfg.ExchangeRatesFile != "" {
+		if seeded, err := ratesSvc.LoadFile(context.Background(), cfg.ExchangeRatesFile); err != nil {
+			log.Printf("exchange rates: cannot load %s: %v", cfg.ExchangeRatesFile, err)
+		} else if !seeded {
+			log.Printf("exchange rates: keeping the stored rates, %s is not loaded", cfg.ExchangeRatesFile)
+		}
+	}
+
+	// Unclaimed waitlist offers move on to the next owner in line
+	go waitlistSvc.Run(context.Background(), time.Minute)
+	// Imported calendars are read as they are added and re-read when due
+	go importSvc.Run(context.Background(), time.Minute)
+	// Bookings whose up-front payment never arrives free their slot again
+	go paymentSvc.Run(context.Background(), time.Minute)
+	// Earnings past their hold are paid out to freelancers in batches
+	go payoutSvc.Run(context.Background(), cfg.PayoutInterval)
+
+	// Retried POST/PUT/DELETE requests carrying an Idempotency-Key replay
+	// the first response
+	idemRepo := repository.NewIdempotencyRepository(db.DB)
+	idem := middleware.Idempotency(idemRepo, cfg.IdempotencyTTL)
+	// Keys past their TTL are cleared out of the way of new requests
+	go middleware.PurgeExpiredKeys(context.Background(), idemRepo, time.Hour)
 
 	api := r.Group("/api")
 	{
@@ -51,14 +137,86 @@ func SetupRoutes(r *gin.Engine) {
 		secure.Use(middleware.JWT(cfg))
 		{
 			secure.GET("/profile/me", profH.Me)
-			secure.POST("/offers", offerH.Create)
+			secure.GET("/profile/reliability", profH.Reliability)
+			secure.GET("/profile/calendar", calendarH.Feed)
+			secure.GET("/profile/credit-packs", extrasH.CreditPacks)
+			secure.POST("/profile/calendar/rotate", idem, calendarH.Rotate)
+			secure.GET("/freelancer/settings", settingsH.Get)
+			secure.PUT("/freelancer/settings", idem, settingsH.Update)
+			secure.POST("/freelancer/time-off", idem, timeOffH.Create)
+			secure.GET("/freelancer/time-off", timeOffH.List)
+			secure.GET("/freelancer/time-off/:id", timeOffH.Get)
+			secure.DELETE("/freelancer/time-off/:id", idem, timeOffH.Delete)
+			secure.POST("/freelancer/calendar-imports", idem, importH.Create)
+			secure.GET("/freelancer/calendar-imports", importH.List)
+			secure.GET("/freelancer/calendar-imports/:id", importH.Get)
+			secure.POST("/freelancer/calendar-imports/:id/sync", idem, importH.Sync)
+			secure.DELETE("/freelancer/calendar-imports/:id", idem, importH.Delete)
+			secure.GET("/freelancer/earnings", payoutH.Earnings)
+			secure.POST("/offers", idem, offerH.Create)
 			secure.POST("/services", serviceH.Create)
-			secure.POST("/bookings", bookingH.Create)
+			secure.POST("/bookings", idem, bookingH.Create)
 			secure.GET("/bookings", bookingH.List)
 			secure.GET("/bookings/:id", bookingH.Get)
+			secure.PATCH("/bookings/:id", idem, bookingH.Update)
+			secure.POST("/bookings/:id/confirm", idem, bookingH.Confirm)
+			secure.POST("/bookings/:id/cancel", idem, bookingH.Cancel)
+			secure.POST("/bookings/:id/no-show", idem, bookingH.NoShow)
+			secure.POST("/bookings/:id/payment", idem, paymentH.Start)
+			secure.POST("/packages/:id/purchase", idem, paymentH.BuyPack)
+			secure.GET("/bookings/:id/payments", paymentH.List)
+			secure.GET("/bookings/:id/invoice", invoiceH.Invoice)
+			secure.GET("/bookings/:id/credit-notes", invoiceH.CreditNotes)
+			secure.GET("/bookings/:id/credit-notes/:note_id", invoiceH.CreditNote)
+			secure.POST("/bookings/:id/refund", idem, invoiceH.Refund)
+			secure.POST("/bookings/:id/reschedule", idem, bookingH.Reschedule)
+			secure.POST("/bookings/:id/reschedule/approve", idem, bookingH.ApproveReschedule)
+			secure.POST("/bookings/:id/reschedule/reject", idem, bookingH.RejectReschedule)
+			secure.GET("/bookings/:id/reschedules", bookingH.Reschedules)
+			secure.POST("/bookings/:id/check-in", idem, visitH.CheckIn)
+			secure.POST("/bookings/:id/check-out", idem, visitH.CheckOut)
+			secure.GET("/bookings/:id/report", visitH.Get)
+			secure.PUT("/bookings/:id/report", idem, visitH.Update)
+			secure.POST("/bookings/:id/report/photos", idem, visitH.AddPhoto)
+			secure.GET("/bookings/:id/report/photos/:photo_id", visitH.Photo)
+			secure.POST("/bookings/stays", idem, stayH.BookStay)
+			secure.POST("/bookings/series", idem, bookingH.CreateSeries)
+			secure.GET("/bookings/series/:id", bookingH.GetSeries)
+			secure.POST("/bookings/series/:id/cancel", idem, bookingH.CancelSeries)
+			secure.GET("/ledger/balances", ledgerH.Balances)
 			secure.GET("/activities", activityH.List)
 		}
 
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.JWT(cfg), middleware.RequireRole("admin"))
+		{
+			admin.POST("/coupons", idem, couponH.Create)
+			admin.GET("/coupons", couponH.List)
+			admin.GET("/coupons/:id", couponH.Get)
+			admin.PATCH("/coupons/:id", idem, couponH.Update)
+			admin.PUT("/exchange-rates", idem, ratesH.Replace)
+			admin.PUT("/services/:id/commission", idem, serviceH.SetCommission)
+			admin.PUT("/freelancers/:id/commission", idem, settingsH.SetCommission)
+		}
+
+		// Calendar feeds, authorised by the secret token in the URL
+		api.GET("/calendar/:token/bookings.ics", calendarH.Bookings)
+		api.GET("/calendar/:token/offers/:offer_id/availability.ics", calendarH.Availability)
+
+		// Payment provider callbacks, authorised by their signature
+		api.POST("/payments/webhook", paymentH.Webhook)
+		if fakePayments != nil {
+			// Stands in for the payer completing the provider's checkout
+			api.POST("/payments/fake/:intent_id/authorize", paymentH.FakeAuthorize)
+		}
+
+		// Public profiles
+		api.GET("/users/:id", profH.Public)
+
+		// Exchange rates offer prices can be displayed in
+		api.GET("/exchange-rates", ratesH.List)
+
 		// Public services
 		api.GET("/services", serviceH.List)
 		api.GET("/services/:id", serviceH.Get)
@@ -76,11 +234,29 @@ func SetupRoutes(r *gin.Engine) {
 				// GET  /api/offers/:offer_id/slots
 				specific.GET("/slots", slotH.List)
 
+				// GET  /api/offers/:offer_id/nights
+				specific.GET("/nights", stayH.ListNights)
+
+				// GET  /api/offers/:offer_id/add-ons and /packages
+				specific.GET("/add-ons", extrasH.ListAddOns)
+				specific.GET("/packages", extrasH.ListPackages)
+
+				// GET  /api/offers/:offer_id/pricing-rules
+				specific.GET("/pricing-rules", pricingRuleH.List)
+
 				// POST /api/offers/:offer_id/slots (protected)
 				specificAuth := specific.Group("")
 				specificAuth.Use(middleware.JWT(cfg))
 				{
-					specificAuth.POST("/slots", slotH.Create)
+					specificAuth.POST("/quote", quoteH.Quote)
+					specificAuth.POST("/slots", idem, slotH.Create)
+					specificAuth.PUT("/nights", idem, stayH.SetNights)
+					specificAuth.POST("/add-ons", idem, extrasH.CreateAddOn)
+					specificAuth.DELETE("/add-ons/:add_on_id", idem, extrasH.RemoveAddOn)
+					specificAuth.POST("/packages", idem, extrasH.CreatePackage)
+					specificAuth.DELETE("/packages/:package_id", idem, extrasH.RemovePackage)
+					specificAuth.POST("/pricing-rules", idem, pricingRuleH.Create)
+					specificAuth.DELETE("/pricing-rules/:rule_id", idem, pricingRuleH.Delete)
 				}
 			}
 		}
@@ -89,8 +265,12 @@ func SetupRoutes(r *gin.Engine) {
 		slotsByID := api.Group("/slots")
 		slotsByID.Use(middleware.JWT(cfg))
 		{
-			slotsByID.PUT("/:slot_id", slotH.Update)
-			slotsByID.DELETE("/:slot_id", slotH.Delete)
+			slotsByID.PUT("/:slot_id", idem, slotH.Update)
+			slotsByID.DELETE("/:slot_id", idem, slotH.Delete)
+			slotsByID.POST("/:slot_id/waitlist", idem, waitlistH.Join)
+			slotsByID.GET("/:slot_id/waitlist", waitlistH.Get)
+			slotsByID.DELETE("/:slot_id/waitlist", idem, waitlistH.Leave)
+			slotsByID.POST("/:slot_id/waitlist/claim", idem, waitlistH.Claim)
 		}
 	}
 }
@@ -0,0 +1,42 @@
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shardy678/pet-freelance/backend/internal/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+func registered(r *gin.Engine) map[string]bool {
+	paths := map[string]bool{}
+	for _, route := range r.Routes() {
+		paths[route.Method+" "+route.Path] = true
+	}
+	return paths
+}
+
+func TestProductionStartsWithoutPaymentProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "jwt")
+	t.Setenv("DATA_ENCRYPTION_KEY", "key")
+	t.Setenv("PAYMENT_PROVIDER", "")
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "")
+
+	r := gin.New()
+	routes.SetupRoutes(r)
+	paths := registered(r)
+	assert.True(t, paths["POST /api/bookings/:id/payment"])
+	assert.False(t, paths["POST /api/payments/fake/:intent_id/authorize"])
+}
+
+func TestDevelopmentServesFakePayments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("PAYMENT_PROVIDER", "")
+
+	r := gin.New()
+	routes.SetupRoutes(r)
+	assert.True(t, registered(r)["POST /api/payments/fake/:intent_id/authorize"])
+}
@@ -0,0 +1,63 @@
+// Package secret seals small pieces of sensitive data, such as door codes,
+// before they are written to the database.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+var ErrCorrupt = errors.New("sealed data is corrupt or was sealed with another key")
+
+// Box encrypts and authenticates data with AES-256-GCM. The nonce is stored
+// in front of the ciphertext.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox derives the 256-bit key from passphrase, so any configured string
+// can be used as the key.
+func NewBox(passphrase string) *Box {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err) // a 32-byte key is always valid
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &Box{aead}
+}
+
+// Seal encrypts plaintext. Empty input seals to nil.
+func (b *Box) Seal(plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, nil
+	}
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts what Seal returned.
+func (b *Box) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) == 0 {
+		return nil, nil
+	}
+	n := b.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, ErrCorrupt
+	}
+	plaintext, err := b.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return plaintext, nil
+}
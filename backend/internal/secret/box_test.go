@@ -0,0 +1,26 @@
+package secret
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	box := NewBox("test key")
+	sealed, err := box.Seal([]byte("door code 4711"))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, []byte("4711")))
+
+	plain, err := box.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "door code 4711", string(plain))
+
+	_, err = NewBox("other key").Open(sealed)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	empty, err := box.Seal(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
 )
 
 type ActivityService struct {
@@ -16,6 +17,12 @@ func NewActivityService(repo *repository.ActivityRepository) *ActivityService {
 	return &ActivityService{repo}
 }
 
+// WithTx returns a copy of the service that writes inside tx, so activities
+// are only kept if the transaction commits.
+func (s *ActivityService) WithTx(tx *gorm.DB) *ActivityService {
+	return &ActivityService{s.repo.WithTx(tx)}
+}
+
 // Emit an activity
 func (s *ActivityService) Emit(ctx context.Context, userID uuid.UUID, title, message, typ string) error {
 	a := &models.Activity{
@@ -2,50 +2,162 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSlotTimes = errors.New("a slot must end after it starts")
+	ErrSlotHasBookings  = errors.New("a slot that is booked or held cannot be moved")
 )
 
 type AvailabilitySlotService struct {
-	repo *repository.AvailabilitySlotRepository
+	repo      *repository.AvailabilitySlotRepository
+	offerRepo *repository.ServiceOfferRepository
+	schedule  scheduleChecker
+	db        *gorm.DB
 }
 
-func NewAvailabilitySlotService(r *repository.AvailabilitySlotRepository) *AvailabilitySlotService {
-	return &AvailabilitySlotService{repo: r}
+func NewAvailabilitySlotService(
+	r *repository.AvailabilitySlotRepository,
+	offerRepo *repository.ServiceOfferRepository,
+	settingsRepo *repository.FreelancerSettingsRepository,
+	timeOffRepo *repository.TimeOffRepository,
+	db *gorm.DB,
+) *AvailabilitySlotService {
+	return &AvailabilitySlotService{
+		repo:      r,
+		offerRepo: offerRepo,
+		schedule:  scheduleChecker{r, settingsRepo, timeOffRepo},
+		db:        db,
+	}
 }
 
-func (s *AvailabilitySlotService) CreateSlot(ctx context.Context, offerID uuid.UUID, start, end time.Time) (*models.AvailabilitySlot, error) {
+// CreateSlot opens a slot taking up to capacity bookings. The slot may not
+// fall into the freelancer's time off or overlap their booked appointments,
+// buffers and travel included.
+func (s *AvailabilitySlotService) CreateSlot(ctx context.Context, offerID uuid.UUID, start, end time.Time, capacity int) (*models.AvailabilitySlot, error) {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidSlotTimes
+	}
+	offer, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	if err := s.schedule.check(ctx, offer, start, end); err != nil {
+		return nil, err
+	}
 	slot := &models.AvailabilitySlot{
 		ID:        uuid.New(),
 		OfferID:   offerID,
 		StartTime: start,
 		EndTime:   end,
 		IsBooked:  false,
+		Capacity:  capacity,
 	}
 	if err := s.repo.Create(ctx, slot); err != nil {
 		return nil, err
 	}
+	slot.RefreshSeats()
 	return slot, nil
 }
 
+// ListSlots returns the offer's slots starting in [from, to), leaving out
+// unbooked slots that fall into the freelancer's time off.
 func (s *AvailabilitySlotService) ListSlots(ctx context.Context, offerID uuid.UUID, onlyAvailable bool, from, to time.Time) ([]models.AvailabilitySlot, error) {
-	return s.repo.ListByOffer(ctx, offerID, onlyAvailable, from, to)
-}
-
-func (s *AvailabilitySlotService) UpdateSlot(ctx context.Context, slotID uuid.UUID, start, end time.Time, isBooked bool) (*models.AvailabilitySlot, error) {
-	slot, err := s.repo.FindByID(ctx, slotID)
+	slots, err := s.repo.ListByOffer(ctx, offerID, onlyAvailable, from, to)
+	if err != nil || len(slots) == 0 {
+		return slots, err
+	}
+	offer, err := s.offerRepo.FindByID(ctx, offerID)
 	if err != nil {
 		return nil, err
 	}
-	slot.StartTime = start
-	slot.EndTime = end
-	slot.IsBooked = isBooked
-	if err := s.repo.Update(ctx, slot); err != nil {
+	off, err := s.schedule.timeOff.ListByFreelancer(ctx, offer.FreelancerID, slots[0].StartTime)
+	if err != nil || len(off) == 0 {
+		return slots, err
+	}
+
+	visible := slots[:0]
+	for _, slot := range slots {
+		if slot.BookedCount == 0 && slot.HeldCount == 0 && inTimeOff(off, slot.StartTime, slot.EndTime) {
+			continue
+		}
+		visible = append(visible, slot)
+	}
+	return visible, nil
+}
+
+func inTimeOff(off []models.TimeOff, start, end time.Time) bool {
+	for i := range off {
+		if off[i].Covers(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateSlot changes a slot's times and capacity; zero values keep the current
+// ones. isBooked closes the slot early; a full slot always stays booked. The
+// slot stays locked while it changes, so bookings and holds made meanwhile
+// are counted, and only a slot nobody booked or holds can be moved.
+func (s *AvailabilitySlotService) UpdateSlot(ctx context.Context, slotID uuid.UUID, start, end time.Time, isBooked bool, capacity int) (*models.AvailabilitySlot, error) {
+	var slot *models.AvailabilitySlot
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		slot, err = s.repo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSlotNotFound
+			}
+			return err
+		}
+		moved := (!start.IsZero() && !start.Equal(slot.StartTime)) || (!end.IsZero() && !end.Equal(slot.EndTime))
+		if moved {
+			if slot.BookedCount+slot.HeldCount > 0 {
+				return ErrSlotHasBookings
+			}
+			if !start.IsZero() {
+				slot.StartTime = start
+			}
+			if !end.IsZero() {
+				slot.EndTime = end
+			}
+			if !slot.EndTime.After(slot.StartTime) {
+				return ErrInvalidSlotTimes
+			}
+			offer, err := s.offerRepo.WithTx(tx).FindByID(ctx, slot.OfferID)
+			if err != nil {
+				return err
+			}
+			if err := s.schedule.withTx(tx).check(ctx, offer, slot.StartTime, slot.EndTime, slot.ID); err != nil {
+				return err
+			}
+		}
+		if capacity > 0 {
+			if capacity < slot.BookedCount+slot.HeldCount {
+				return ErrCapacityBelowBooked
+			}
+			slot.Capacity = capacity
+		}
+		slot.IsBooked = isBooked || slot.BookedCount+slot.HeldCount >= slot.Capacity
+		return s.repo.WithTx(tx).UpdateSchedule(ctx, slot)
+	})
+	if err != nil {
 		return nil, err
 	}
+	slot.RefreshSeats()
 	return slot, nil
 }
 
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/secret"
+	"gorm.io/gorm"
+)
+
+// accessGrace is how long after completion the freelancer still sees the
+// access details, e.g. to return a key.
+const accessGrace = 24 * time.Hour
+
+var (
+	ErrNotBookingOwner        = errors.New("only the owner can change the instructions")
+	ErrBookingNotEditable     = errors.New("instructions can no longer be changed")
+	ErrPrivateNotesFreelancer = errors.New("only the freelancer can keep private notes")
+)
+
+// BookingDetails is a booking as one of its parties sees it. AccessDetails
+// are left out while they are hidden from the viewer; FreelancerNotes are
+// only ever shown to the freelancer.
+type BookingDetails struct {
+	models.Booking
+	AccessDetails      *models.AccessDetails `json:"accessDetails,omitempty"`
+	AccessVisibleUntil *time.Time            `json:"accessVisibleUntil,omitempty"`
+	FreelancerNotes    *string               `json:"freelancerNotes,omitempty"`
+}
+
+// NotesUpdate carries the fields of a booking a party wants to change; nil
+// fields are left alone.
+type NotesUpdate struct {
+	Instructions    *models.Instructions
+	AccessDetails   *models.AccessDetails
+	FreelancerNotes *string
+}
+
+// BookingNotesService keeps the owner's instructions and access details and
+// the freelancer's private notes on a booking. Access details are sealed
+// before they are stored.
+type BookingNotesService struct {
+	bookings *BookingService
+	box      *secret.Box
+}
+
+func NewBookingNotesService(bookings *BookingService, box *secret.Box) *BookingNotesService {
+	return &BookingNotesService{bookings, box}
+}
+
+// Get returns the booking with what actorID may see of its notes. Only the
+// owner and the freelancer may see the booking at all.
+func (s *BookingNotesService) Get(ctx context.Context, bookingID, actorID uuid.UUID) (*BookingDetails, error) {
+	b := s.bookings
+	booking, err := b.bookingRepo.FindByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	offer, err := b.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return nil, err
+	}
+	return s.details(booking, offer, actorID, time.Now())
+}
+
+// Update applies the owner's instructions and access details, which can be
+// changed while the booking is active, or the freelancer's private notes.
+func (s *BookingNotesService) Update(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	upd NotesUpdate,
+) (*BookingDetails, error) {
+	b := s.bookings
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+	)
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = b.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if upd.Instructions != nil || upd.AccessDetails != nil {
+			if actorID != booking.OwnerID {
+				return ErrNotBookingOwner
+			}
+			if !booking.IsActive() {
+				return ErrBookingNotEditable
+			}
+		}
+		if upd.FreelancerNotes != nil && actorID != offer.FreelancerID {
+			return ErrPrivateNotesFreelancer
+		}
+
+		if upd.Instructions != nil {
+			booking.Instructions = *upd.Instructions
+		}
+		if upd.AccessDetails != nil {
+			if booking.SealedAccess, err = s.seal(upd.AccessDetails); err != nil {
+				return err
+			}
+		}
+		if upd.FreelancerNotes != nil {
+			booking.FreelancerNotes = *upd.FreelancerNotes
+		}
+		return b.bookingRepo.WithTx(tx).Update(ctx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if actorID == booking.OwnerID && (upd.Instructions != nil || upd.AccessDetails != nil) {
+		msg := fmt.Sprintf("The owner updated the instructions for %q.", offer.Title)
+		if err := b.activitySvc.Emit(ctx, offer.FreelancerID, "Instructions updated", msg, "instructions"); err != nil {
+			fmt.Printf("warning: could not emit activity: %v\n", err)
+		}
+	}
+	return s.details(booking, offer, actorID, time.Now())
+}
+
+func (s *BookingNotesService) details(
+	booking *models.Booking,
+	offer *models.ServiceOffer,
+	actorID uuid.UUID,
+	now time.Time,
+) (*BookingDetails, error) {
+	d := &BookingDetails{Booking: *booking}
+	switch actorID {
+	case booking.OwnerID:
+		access, err := s.open(booking.SealedAccess)
+		if err != nil {
+			return nil, err
+		}
+		d.AccessDetails = access
+	case offer.FreelancerID:
+		notes := booking.FreelancerNotes
+		d.FreelancerNotes = &notes
+		if visible, until := accessWindow(booking, now); visible {
+			access, err := s.open(booking.SealedAccess)
+			if err != nil {
+				return nil, err
+			}
+			d.AccessDetails = access
+			d.AccessVisibleUntil = until
+		}
+	default:
+		return nil, ErrNotBookingParty
+	}
+	return d, nil
+}
+
+// accessWindow reports whether the freelancer may see the access details:
+// once the booking is confirmed, and for accessGrace after it is completed.
+// until is nil while the booking is not completed yet.
+func accessWindow(b *models.Booking, now time.Time) (bool, *time.Time) {
+	switch b.Status {
+	case models.BookingStatusConfirmed:
+		return true, nil
+	case models.BookingStatusCompleted:
+		if b.CompletedAt == nil {
+			return false, nil
+		}
+		until := b.CompletedAt.Add(accessGrace)
+		return now.Before(until), &until
+	}
+	return false, nil
+}
+
+func (s *BookingNotesService) seal(a *models.AccessDetails) ([]byte, error) {
+	if *a == (models.AccessDetails{}) {
+		return nil, nil
+	}
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return s.box.Seal(raw)
+}
+
+func (s *BookingNotesService) open(sealed []byte) (*models.AccessDetails, error) {
+	raw, err := s.box.Open(sealed)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	var a models.AccessDetails
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
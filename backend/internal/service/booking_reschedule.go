@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRescheduleSameSlot      = errors.New("booking is already on this slot")
+	ErrReschedulePending       = errors.New("booking already has a pending reschedule")
+	ErrNoRescheduleProposal    = errors.New("booking has no pending reschedule")
+	ErrNotOfferFreelancer      = errors.New("only the offer's freelancer can do this")
+	ErrBookingNotReschedulable = errors.New("booking can no longer be rescheduled")
+)
+
+// Reschedule moves a booking to another free slot of the same offer. When the
+// owner asks and the offer requires approval, the new slot is only held and a
+// proposal is left for the freelancer to approve or reject.
+func (s *BookingService) Reschedule(
+	ctx context.Context,
+	bookingID, actorID, newSlotID uuid.UUID,
+) (*models.Booking, error) {
+	var (
+		booking  *models.Booking
+		offer    *models.ServiceOffer
+		proposed bool
+	)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if !booking.IsActive() || booking.IsStay() {
+			return ErrBookingNotReschedulable
+		}
+		if booking.ProposedSlotID != nil {
+			return ErrReschedulePending
+		}
+		if newSlotID == booking.SlotID {
+			return ErrRescheduleSameSlot
+		}
+
+		newSlot, err := s.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, newSlotID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSlotNotFound
+			}
+			return err
+		}
+		if err := validateBooking(offer, newSlot, booking.OwnerID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.checkSchedule(ctx, tx, offer, newSlot, booking.SlotID); err != nil {
+			return err
+		}
+		newSlot.Reserve()
+		if err := s.slotRepo.WithTx(tx).Update(ctx, newSlot); err != nil {
+			return err
+		}
+
+		history := &models.BookingReschedule{
+			BookingID:   booking.ID,
+			FromSlotID:  booking.SlotID,
+			ToSlotID:    newSlotID,
+			RequestedBy: actorID,
+		}
+		proposed = offer.RescheduleApproval && actorID == booking.OwnerID
+		if proposed {
+			history.Status = models.RescheduleProposed
+			booking.ProposedSlotID = &newSlotID
+		} else {
+			now := time.Now()
+			history.Status = models.RescheduleApplied
+			history.DecidedBy = &actorID
+			history.DecidedAt = &now
+			if _, err := s.releaseSlot(ctx, tx, booking.SlotID); err != nil {
+				return err
+			}
+			booking.SlotID = newSlotID
+		}
+		if err := s.rescheduleRepo.WithTx(tx).Create(ctx, history); err != nil {
+			return err
+		}
+		return s.bookingRepo.WithTx(tx).Update(ctx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if proposed {
+		s.emitReschedule(ctx, booking, offer, *booking.ProposedSlotID,
+			"Reschedule requested",
+			"You asked to move %q to %s. The freelancer has to approve the change.",
+			"The owner asked to move %q to %s. Please approve or reject the change.")
+	} else {
+		s.emitReschedule(ctx, booking, offer, booking.SlotID,
+			"Booking rescheduled",
+			"%q was moved to %s.",
+			"%q was moved to %s.")
+	}
+	return booking, nil
+}
+
+// DecideReschedule lets the freelancer approve or reject the owner's pending
+// reschedule proposal.
+func (s *BookingService) DecideReschedule(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	approve bool,
+) (*models.Booking, error) {
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+		toSlot  uuid.UUID
+	)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if actorID != offer.FreelancerID {
+			return ErrNotOfferFreelancer
+		}
+		history, err := s.rescheduleRepo.WithTx(tx).FindProposed(ctx, booking.ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoRescheduleProposal
+			}
+			return err
+		}
+
+		now := time.Now()
+		history.DecidedBy = &actorID
+		history.DecidedAt = &now
+		toSlot = history.ToSlotID
+		if approve {
+			history.Status = models.RescheduleApplied
+			if _, err := s.releaseSlot(ctx, tx, booking.SlotID); err != nil {
+				return err
+			}
+			booking.SlotID = history.ToSlotID
+		} else {
+			history.Status = models.RescheduleRejected
+			if _, err := s.releaseSlot(ctx, tx, history.ToSlotID); err != nil {
+				return err
+			}
+		}
+		booking.ProposedSlotID = nil
+		if err := s.rescheduleRepo.WithTx(tx).Update(ctx, history); err != nil {
+			return err
+		}
+		return s.bookingRepo.WithTx(tx).Update(ctx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if approve {
+		s.emitReschedule(ctx, booking, offer, toSlot,
+			"Reschedule approved",
+			"The freelancer approved moving %q to %s.",
+			"You approved moving %q to %s.")
+	} else {
+		s.emitReschedule(ctx, booking, offer, toSlot,
+			"Reschedule rejected",
+			"The freelancer rejected moving %q to %s. Your original time is kept.",
+			"You rejected moving %q to %s.")
+	}
+	return booking, nil
+}
+
+// ListReschedules returns a booking's slot history for one of its parties.
+func (s *BookingService) ListReschedules(ctx context.Context, bookingID, actorID uuid.UUID) ([]models.BookingReschedule, error) {
+	booking, err := s.bookingRepo.FindByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	offer, err := s.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID != booking.OwnerID && actorID != offer.FreelancerID {
+		return nil, ErrNotBookingParty
+	}
+	return s.rescheduleRepo.ListByBooking(ctx, bookingID)
+}
+
+// lockBookingForParty locks a booking inside tx, loads its offer and checks
+// that actorID is the booking's owner or the offer's freelancer.
+func (s *BookingService) lockBookingForParty(
+	ctx context.Context,
+	tx *gorm.DB,
+	bookingID, actorID uuid.UUID,
+) (*models.Booking, *models.ServiceOffer, error) {
+	booking, err := s.bookingRepo.WithTx(tx).FindByIDForUpdate(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrBookingNotFound
+		}
+		return nil, nil, err
+	}
+	offer, err := s.offerRepo.WithTx(tx).FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actorID != booking.OwnerID && actorID != offer.FreelancerID {
+		return nil, nil, ErrNotBookingParty
+	}
+	return booking, offer, nil
+}
+
+// withdrawReschedule drops a booking's pending proposal, freeing the held slot.
+func (s *BookingService) withdrawReschedule(ctx context.Context, tx *gorm.DB, booking *models.Booking) error {
+	if booking.ProposedSlotID == nil {
+		return nil
+	}
+	history, err := s.rescheduleRepo.WithTx(tx).FindProposed(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	history.Status = models.RescheduleWithdrawn
+	if err := s.rescheduleRepo.WithTx(tx).Update(ctx, history); err != nil {
+		return err
+	}
+	if _, err := s.releaseSlot(ctx, tx, *booking.ProposedSlotID); err != nil {
+		return err
+	}
+	booking.ProposedSlotID = nil
+	return nil
+}
+
+// releaseSlot gives a booking's seat back to the slot and lets the seat
+// listener claim it.
+func (s *BookingService) releaseSlot(ctx context.Context, tx *gorm.DB, slotID uuid.UUID) (*models.AvailabilitySlot, error) {
+	slot, err := s.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	slot.Release()
+	if s.seatReleased != nil {
+		if err := s.seatReleased(ctx, tx, slot); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.slotRepo.WithTx(tx).Update(ctx, slot); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// emitReschedule sends ownerFmt to the owner and freelancerFmt to the
+// freelancer, each formatted with the offer title and the slot's start.
+func (s *BookingService) emitReschedule(
+	ctx context.Context,
+	b *models.Booking,
+	offer *models.ServiceOffer,
+	slotID uuid.UUID,
+	title, ownerFmt, freelancerFmt string,
+) {
+	when := "a new time"
+	if slot, err := s.slotRepo.FindByID(ctx, slotID); err == nil {
+		when = slot.StartTime.Format("Jan 2, 2006 at 15:04")
+	}
+	for _, a := range []struct {
+		userID uuid.UUID
+		format string
+	}{{b.OwnerID, ownerFmt}, {offer.FreelancerID, freelancerFmt}} {
+		msg := fmt.Sprintf(a.format, offer.Title, when)
+		if err := s.activitySvc.Emit(ctx, a.userID, title, msg, "reschedule"); err != nil {
+			fmt.Printf("warning: could not emit activity: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSeries  = errors.New("series needs weekdays, a HH:MM start time, a valid timezone and 1-52 weeks")
+	ErrSeriesNotFound = errors.New("booking series not found")
+)
+
+// SeriesRequest describes a recurring booking: every listed weekday at
+// TimeOfDay (in Timezone) for Weeks weeks starting on StartDate.
+type SeriesRequest struct {
+	OfferID   uuid.UUID
+	Weekdays  []time.Weekday
+	TimeOfDay string
+	Timezone  string
+	StartDate time.Time
+	Weeks     int
+	Mode      string
+}
+
+// SeriesConflict explains why one occurrence of a series could not be booked.
+type SeriesConflict struct {
+	StartTime time.Time `json:"startTime"`
+	Reason    string    `json:"reason"`
+}
+
+// SeriesConflictError is returned when a series cannot be booked as asked.
+type SeriesConflictError struct {
+	Conflicts []SeriesConflict
+}
+
+func (e *SeriesConflictError) Error() string {
+	return fmt.Sprintf("%d occurrence(s) of the series cannot be booked", len(e.Conflicts))
+}
+
+type SeriesResult struct {
+	Series    *models.BookingSeries `json:"series"`
+	Bookings  []models.Booking      `json:"bookings"`
+	Conflicts []SeriesConflict      `json:"conflicts,omitempty"`
+}
+
+// GroupedBookings splits an owner's bookings into series and one-off bookings.
+type GroupedBookings struct {
+	Series   []SeriesResult   `json:"series"`
+	Bookings []models.Booking `json:"bookings"`
+}
+
+type BookingSeriesService struct {
+	bookings   *BookingService
+	seriesRepo *repository.BookingSeriesRepository
+}
+
+func NewBookingSeriesService(bookings *BookingService, seriesRepo *repository.BookingSeriesRepository) *BookingSeriesService {
+	return &BookingSeriesService{bookings, seriesRepo}
+}
+
+// BookSeries books every occurrence of req in one transaction. In
+// all-or-nothing mode any unavailable occurrence aborts the whole series; in
+// best-effort mode free occurrences are booked and the rest reported.
+func (s *BookingSeriesService) BookSeries(ctx context.Context, ownerID uuid.UUID, req SeriesRequest) (*SeriesResult, error) {
+	occurrences, loc, err := seriesOccurrences(req)
+	if err != nil {
+		return nil, err
+	}
+	b := s.bookings
+	result := &SeriesResult{}
+	var offer *models.ServiceOffer
+
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		offer, err = b.findOffer(ctx, tx, req.OfferID)
+		if err != nil {
+			return err
+		}
+		if offer.IsStay() {
+			return ErrStayOffer
+		}
+		if !offer.IsActive {
+			return ErrOfferInactive
+		}
+		if offer.FreelancerID == ownerID {
+			return ErrOwnOffer
+		}
+
+		last := occurrences[len(occurrences)-1]
+		slots, err := b.slotRepo.WithTx(tx).ListByOffer(ctx, offer.ID, false, occurrences[0], last.Add(time.Minute))
+		if err != nil {
+			return err
+		}
+		byStart := make(map[int64]uuid.UUID, len(slots))
+		for _, sl := range slots {
+			byStart[sl.StartTime.Unix()] = sl.ID
+		}
+
+		now := time.Now()
+		var free []*models.AvailabilitySlot
+		for _, at := range occurrences {
+			slotID, ok := byStart[at.Unix()]
+			if !ok {
+				result.Conflicts = append(result.Conflicts, SeriesConflict{at, "no slot offered at this time"})
+				continue
+			}
+			slot, err := b.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+			if err != nil {
+				return err
+			}
+			if err := validateBooking(offer, slot, ownerID, now); err != nil {
+				result.Conflicts = append(result.Conflicts, SeriesConflict{at, err.Error()})
+				continue
+			}
+			if err := b.checkSchedule(ctx, tx, offer, slot); err != nil {
+				if !errors.Is(err, ErrScheduleConflict) && !errors.Is(err, ErrInTimeOff) {
+					return err
+				}
+				result.Conflicts = append(result.Conflicts, SeriesConflict{at, err.Error()})
+				continue
+			}
+			free = append(free, slot)
+		}
+		if len(free) == 0 || (req.Mode == models.SeriesModeAllOrNothing && len(result.Conflicts) > 0) {
+			return &SeriesConflictError{Conflicts: result.Conflicts}
+		}
+
+		weekdays := make([]int, len(req.Weekdays))
+		for i, d := range req.Weekdays {
+			weekdays[i] = int(d)
+		}
+		result.Series = &models.BookingSeries{
+			OfferID:   offer.ID,
+			OwnerID:   ownerID,
+			Weekdays:  weekdays,
+			TimeOfDay: req.TimeOfDay,
+			Timezone:  loc.String(),
+			StartDate: req.StartDate,
+			Weeks:     req.Weeks,
+			Mode:      req.Mode,
+			Status:    models.SeriesStatusActive,
+		}
+		if err := s.seriesRepo.WithTx(tx).Create(ctx, result.Series); err != nil {
+			return err
+		}
+		for _, slot := range free {
+			booking := &models.Booking{OfferID: offer.ID, OwnerID: ownerID, SeriesID: &result.Series.ID}
+			if err := b.reserveSlot(ctx, tx, offer, slot, booking, Extras{}); err != nil {
+				return err
+			}
+			result.Bookings = append(result.Bookings, *booking)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(
+		"%d of %d %q appointments were booked, every %s at %s (%s).",
+		len(result.Bookings), len(occurrences), offer.Title,
+		weekdayList(req.Weekdays), req.TimeOfDay, loc,
+	)
+	s.emit(ctx, result.Series, offer, "Recurring booking created", msg)
+	return result, nil
+}
+
+// CancelSeries cancels every still-active booking of the series starting at
+// or after from, applying the offer's cancellation policy to each.
+func (s *BookingSeriesService) CancelSeries(
+	ctx context.Context,
+	seriesID, actorID uuid.UUID,
+	from time.Time,
+	reason string,
+) (*SeriesResult, error) {
+	b := s.bookings
+	result := &SeriesResult{}
+	var (
+		offer     *models.ServiceOffer
+		cancelled int
+		refund    money.Money
+	)
+	now := time.Now()
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result.Series, offer, err = s.findForParty(ctx, tx, seriesID, actorID)
+		if err != nil {
+			return err
+		}
+		bookings, err := b.bookingRepo.WithTx(tx).ListBySeries(ctx, seriesID)
+		if err != nil {
+			return err
+		}
+
+		stillActive := false
+		for i := range bookings {
+			booking := &bookings[i]
+			if !booking.IsActive() {
+				continue
+			}
+			slot, err := b.slotRepo.WithTx(tx).FindByID(ctx, booking.SlotID)
+			if err != nil {
+				return err
+			}
+			if slot.StartTime.Before(from) {
+				stillActive = true
+				continue
+			}
+			locked, err := b.bookingRepo.WithTx(tx).FindByIDForUpdate(ctx, booking.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := b.cancelLocked(ctx, tx, locked, offer, actorID, reason, now); err != nil {
+				return err
+			}
+			*booking = *locked
+			cancelled++
+			refund = refund.Add(locked.RefundAmount)
+		}
+		if !stillActive {
+			result.Series.Status = models.SeriesStatusCancelled
+			if err := s.seriesRepo.WithTx(tx).Update(ctx, result.Series); err != nil {
+				return err
+			}
+		}
+		result.Bookings = bookings
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Bookings {
+		if c := result.Bookings[i].CancelledAt; c != nil && c.Equal(now) {
+			b.notifyCancelled(ctx, &result.Bookings[i])
+		}
+	}
+	msg := fmt.Sprintf(
+		"%d upcoming %q appointments were cancelled. Total refund: %s.",
+		cancelled, offer.Title, refund,
+	)
+	s.emit(ctx, result.Series, offer, "Recurring booking cancelled", msg)
+	return result, nil
+}
+
+// GetSeries returns a series with its bookings for one of its parties.
+func (s *BookingSeriesService) GetSeries(ctx context.Context, seriesID, actorID uuid.UUID) (*SeriesResult, error) {
+	series, _, err := s.findForParty(ctx, s.bookings.db, seriesID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	bookings, err := s.bookings.bookingRepo.ListBySeries(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	return &SeriesResult{Series: series, Bookings: bookings}, nil
+}
+
+// ListGrouped returns the owner's bookings with series occurrences nested
+// under their series.
+func (s *BookingSeriesService) ListGrouped(ctx context.Context, ownerID uuid.UUID) (*GroupedBookings, error) {
+	series, err := s.seriesRepo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	bookings, err := s.bookings.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := &GroupedBookings{Series: []SeriesResult{}, Bookings: []models.Booking{}}
+	index := make(map[uuid.UUID]int, len(series))
+	for i := range series {
+		index[series[i].ID] = i
+		grouped.Series = append(grouped.Series, SeriesResult{Series: &series[i], Bookings: []models.Booking{}})
+	}
+	for _, booking := range bookings {
+		if booking.SeriesID != nil {
+			if i, ok := index[*booking.SeriesID]; ok {
+				grouped.Series[i].Bookings = append(grouped.Series[i].Bookings, booking)
+				continue
+			}
+		}
+		grouped.Bookings = append(grouped.Bookings, booking)
+	}
+	return grouped, nil
+}
+
+func (s *BookingSeriesService) findForParty(
+	ctx context.Context,
+	tx *gorm.DB,
+	seriesID, actorID uuid.UUID,
+) (*models.BookingSeries, *models.ServiceOffer, error) {
+	series, err := s.seriesRepo.WithTx(tx).FindByID(ctx, seriesID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSeriesNotFound
+		}
+		return nil, nil, err
+	}
+	offer, err := s.bookings.offerRepo.WithTx(tx).FindByID(ctx, series.OfferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actorID != series.OwnerID && actorID != offer.FreelancerID {
+		return nil, nil, ErrNotBookingParty
+	}
+	return series, offer, nil
+}
+
+func (s *BookingSeriesService) emit(ctx context.Context, series *models.BookingSeries, offer *models.ServiceOffer, title, msg string) {
+	for _, userID := range []uuid.UUID{series.OwnerID, offer.FreelancerID} {
+		if err := s.bookings.activitySvc.Emit(ctx, userID, title, msg, "series"); err != nil {
+			fmt.Printf("warning: could not emit activity: %v\n", err)
+		}
+	}
+}
+
+// seriesOccurrences expands a series request into its start times.
+func seriesOccurrences(req SeriesRequest) ([]time.Time, *time.Location, error) {
+	if len(req.Weekdays) == 0 || req.Weeks < 1 || req.Weeks > 52 {
+		return nil, nil, ErrInvalidSeries
+	}
+	tz := req.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, ErrInvalidSeries
+	}
+	tod, err := time.Parse("15:04", req.TimeOfDay)
+	if err != nil {
+		return nil, nil, ErrInvalidSeries
+	}
+
+	days := make(map[time.Weekday]bool, len(req.Weekdays))
+	for _, d := range req.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return nil, nil, ErrInvalidSeries
+		}
+		days[d] = true
+	}
+
+	y, m, d := req.StartDate.Date()
+	var out []time.Time
+	for i := 0; i < req.Weeks*7; i++ {
+		at := time.Date(y, m, d+i, tod.Hour(), tod.Minute(), 0, 0, loc)
+		if days[at.Weekday()] {
+			out = append(out, at.UTC())
+		}
+	}
+	return out, loc, nil
+}
+
+func weekdayList(days []time.Weekday) string {
+	sorted := append([]time.Weekday(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	out := ""
+	for i, d := range sorted {
+		if i > 0 {
+			out += "/"
+		}
+		out += d.String()[:3]
+	}
+	return out
+}
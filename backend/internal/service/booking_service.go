@@ -4,61 +4,143 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
 	"gorm.io/gorm"
 )
 
-var ErrSlotAlreadyBooked = errors.New("slot already booked")
+var (
+	ErrSlotAlreadyBooked = errors.New("slot is fully booked")
+	ErrOfferNotFound     = errors.New("offer not found")
+	ErrSlotNotFound      = errors.New("slot not found")
+	ErrSlotOfferMismatch = errors.New("slot does not belong to this offer")
+	ErrOfferInactive     = errors.New("offer is not active")
+	ErrSlotInPast        = errors.New("slot has already started")
+	ErrOwnOffer          = errors.New("cannot book your own offer")
+	ErrStayOffer         = errors.New("stay offers are booked by check-in and check-out date")
+
+	ErrBookingNotFound       = errors.New("booking not found")
+	ErrNotBookingParty       = errors.New("only the owner or the freelancer can change this booking")
+	ErrBookingNotCancellable = errors.New("booking can no longer be cancelled")
+	ErrBookingNotPending     = errors.New("only pending bookings can be confirmed")
+	ErrDepositOutstanding    = errors.New("the owner has not paid the required deposit yet")
+	ErrNoShowTooEarly        = errors.New("a no-show can only be reported once the booking has started")
+	ErrNoShowNotConfirmed    = errors.New("only confirmed bookings can be reported as a no-show")
+	ErrNoShowVisited         = errors.New("the visit was checked in, so the owner did show up")
+)
+
+// lateCancellationWindow is how close to the start an owner's cancellation
+// counts as late.
+const lateCancellationWindow = 24 * time.Hour
+
+// SeatListener is called inside the releasing transaction whenever a booking
+// gives a seat back to a slot, before the slot is saved.
+type SeatListener func(ctx context.Context, tx *gorm.DB, slot *models.AvailabilitySlot) error
+
+// BookingListener is called after a cancellation or completion has been
+// committed.
+type BookingListener func(ctx context.Context, booking *models.Booking)
 
 type BookingService struct {
-	bookingRepo *repository.BookingRepository
-	slotRepo    *repository.AvailabilitySlotRepository
-	activitySvc *ActivityService
-	db          *gorm.DB
+	bookingRepo    *repository.BookingRepository
+	slotRepo       *repository.AvailabilitySlotRepository
+	offerRepo      *repository.ServiceOfferRepository
+	rescheduleRepo *repository.BookingRescheduleRepository
+	nightRepo      *repository.StayNightRepository
+	reportRepo     *repository.VisitReportRepository
+	paymentRepo    *repository.PaymentRepository
+	reliability    *repository.ReliabilityRepository
+	schedule       scheduleChecker
+	activitySvc    *ActivityService
+	quotes         *QuoteService
+	db             *gorm.DB
+	seatReleased   SeatListener
+	cancelled      []BookingListener
+	completed      []BookingListener
 }
 
 func NewBookingService(
 	bookingRepo *repository.BookingRepository,
 	slotRepo *repository.AvailabilitySlotRepository,
+	offerRepo *repository.ServiceOfferRepository,
+	rescheduleRepo *repository.BookingRescheduleRepository,
+	nightRepo *repository.StayNightRepository,
+	reportRepo *repository.VisitReportRepository,
+	paymentRepo *repository.PaymentRepository,
+	settingsRepo *repository.FreelancerSettingsRepository,
+	timeOffRepo *repository.TimeOffRepository,
+	reliability *repository.ReliabilityRepository,
 	activitySvc *ActivityService,
 	db *gorm.DB,
 ) *BookingService {
-	return &BookingService{bookingRepo, slotRepo, activitySvc, db}
+	s := &BookingService{
+		bookingRepo:    bookingRepo,
+		slotRepo:       slotRepo,
+		offerRepo:      offerRepo,
+		rescheduleRepo: rescheduleRepo,
+		nightRepo:      nightRepo,
+		reportRepo:     reportRepo,
+		paymentRepo:    paymentRepo,
+		reliability:    reliability,
+		schedule:       scheduleChecker{slotRepo, settingsRepo, timeOffRepo},
+		activitySvc:    activitySvc,
+		db:             db,
+	}
+	// neither tax nor fees until a QuoteService is configured
+	s.quotes = &QuoteService{bookings: s}
+	return s
+}
+
+// OnSeatReleased registers the listener told about freed slot seats.
+func (s *BookingService) OnSeatReleased(l SeatListener) {
+	s.seatReleased = l
+}
+
+// OnCancelled registers a listener told about cancelled bookings. Listeners
+// run in the order they were registered.
+func (s *BookingService) OnCancelled(l BookingListener) {
+	s.cancelled = append(s.cancelled, l)
+}
+
+// OnCompleted registers a listener told about completed bookings.
+func (s *BookingService) OnCompleted(l BookingListener) {
+	s.completed = append(s.completed, l)
 }
 
 // BookSlot reserves a slot and creates a booking within a single transaction.
 func (s *BookingService) BookSlot(
 	ctx context.Context,
 	offerID, slotID, ownerID uuid.UUID,
+	extras Extras,
 ) (*models.Booking, error) {
 	var booking *models.Booking
 
-	// 1) Transactionally reserve the slot & create booking
+	// 1) Transactionally validate & reserve the slot, then create the booking
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		slot, err := s.slotRepo.FindByID(ctx, slotID)
+		offer, err := s.findOffer(ctx, tx, offerID)
 		if err != nil {
 			return err
 		}
-		if slot.IsBooked {
-			return ErrSlotAlreadyBooked
-		}
-		slot.IsBooked = true
-		if err := s.slotRepo.Update(ctx, slot); err != nil {
+		slot, err := s.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSlotNotFound
+			}
 			return err
 		}
-		booking = &models.Booking{
-			OfferID: offerID,
-			SlotID:  slotID,
-			OwnerID: ownerID,
-			Status:  "pending",
+		if err := validateBooking(offer, slot, ownerID, time.Now()); err != nil {
+			return err
 		}
-		if err := s.bookingRepo.Create(ctx, booking); err != nil {
+		if err := s.checkSchedule(ctx, tx, offer, slot); err != nil {
 			return err
 		}
-		return nil
+
+		booking = &models.Booking{OfferID: offerID, OwnerID: ownerID}
+		return s.reserveSlot(ctx, tx, offer, slot, booking, extras)
 	})
 	if err != nil {
 		return nil, err
@@ -79,6 +161,444 @@ func (s *BookingService) BookSlot(
 	return booking, nil
 }
 
+// findOffer loads an offer inside tx, mapping a missing row to ErrOfferNotFound.
+func (s *BookingService) findOffer(ctx context.Context, tx *gorm.DB, offerID uuid.UUID) (*models.ServiceOffer, error) {
+	offer, err := s.offerRepo.WithTx(tx).FindByID(ctx, offerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	return offer, nil
+}
+
+// reserveSlot takes a seat in a validated, locked slot and stores booking on it.
+func (s *BookingService) reserveSlot(
+	ctx context.Context,
+	tx *gorm.DB,
+	offer *models.ServiceOffer,
+	slot *models.AvailabilitySlot,
+	booking *models.Booking,
+	extras Extras,
+) error {
+	if err := s.priceBooking(ctx, tx, offer, booking, slotItem(slot, extras)); err != nil {
+		return err
+	}
+	slot.Reserve()
+	if err := s.slotRepo.WithTx(tx).Update(ctx, slot); err != nil {
+		return err
+	}
+	booking.SlotID = slot.ID
+	return s.bookingRepo.WithTx(tx).Create(ctx, booking)
+}
+
+// priceBooking stores the quote for item on booking, sets what is due up
+// front and redeems the credit it is paid with.
+func (s *BookingService) priceBooking(
+	ctx context.Context,
+	tx *gorm.DB,
+	offer *models.ServiceOffer,
+	booking *models.Booking,
+	item quoteItem,
+) error {
+	q, err := s.quotes.compute(ctx, tx, offer, booking.OwnerID, item)
+	if err != nil {
+		return err
+	}
+	booking.Price = q.price
+	booking.Total = q.price.Total
+	booking.AddOnIDs = item.AddOnIDs
+	booking.Pets = item.pets()
+	if err := s.requireDeposit(ctx, tx, offer, booking, q.price.Total); err != nil {
+		return err
+	}
+	return s.quotes.redeem(ctx, tx, q, booking)
+}
+
+// requireDeposit sets what the owner pays up front: the full price for
+// prepaid offers, the offer's deposit for owners with too many no-shows.
+// Such bookings await payment and hold their slot until it arrives.
+func (s *BookingService) requireDeposit(
+	ctx context.Context,
+	tx *gorm.DB,
+	offer *models.ServiceOffer,
+	booking *models.Booking,
+	price money.Money,
+) error {
+	booking.Status = models.BookingStatusPending
+	booking.Currency = offer.Currency
+	booking.DepositAmount = money.New(0, offer.Currency)
+	booking.RefundAmount = money.New(0, offer.Currency)
+	switch {
+	case offer.Prepaid:
+		booking.DepositAmount = price
+	case offer.DepositPercent > 0:
+		stats, err := s.reliability.WithTx(tx).Find(ctx, booking.OwnerID)
+		if err != nil {
+			return err
+		}
+		if offer.RequiresDeposit(stats.NoShows) {
+			booking.DepositAmount = price.Percent(offer.DepositPercent)
+		}
+	}
+	if booking.DepositOutstanding() {
+		booking.Status = models.BookingStatusAwaitingPayment
+	}
+	return nil
+}
+
+// checkSchedule makes sure the freelancer is neither off nor busy, buffers and
+// travel time included, around slot. The slot's own seats and the ignored
+// slots do not count as conflicts. The freelancer's settings stay locked until
+// tx ends, so bookings of their other offers cannot slip in between the check
+// and the booking.
+func (s *BookingService) checkSchedule(
+	ctx context.Context,
+	tx *gorm.DB,
+	offer *models.ServiceOffer,
+	slot *models.AvailabilitySlot,
+	ignore ...uuid.UUID,
+) error {
+	if err := s.schedule.settings.WithTx(tx).Lock(ctx, offer.FreelancerID); err != nil {
+		return err
+	}
+	return s.schedule.withTx(tx).check(ctx, offer, slot.StartTime, slot.EndTime, append(ignore, slot.ID)...)
+}
+
+// validateBooking checks that ownerID may book slot under offer at time now.
+func validateBooking(offer *models.ServiceOffer, slot *models.AvailabilitySlot, ownerID uuid.UUID, now time.Time) error {
+	switch {
+	case offer.IsStay():
+		return ErrStayOffer
+	case slot.OfferID != offer.ID:
+		return ErrSlotOfferMismatch
+	case !offer.IsActive:
+		return ErrOfferInactive
+	case offer.FreelancerID == ownerID:
+		return ErrOwnOffer
+	case !slot.StartTime.After(now):
+		return ErrSlotInPast
+	case !slot.HasSeat():
+		return ErrSlotAlreadyBooked
+	}
+	return nil
+}
+
+// CancelBooking cancels a booking on behalf of its owner or the offer's
+// freelancer, frees the slot and records the refund due under the offer's
+// cancellation policy. Freelancer cancellations are always fully refunded.
+func (s *BookingService) CancelBooking(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	reason string,
+) (*models.Booking, error) {
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+		start   time.Time
+	)
+	now := time.Now()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		start, err = s.cancelLocked(ctx, tx, booking, offer, actorID, reason, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.emitCancellation(ctx, booking, offer, start, now)
+	s.notifyCancelled(ctx, booking)
+	return booking, nil
+}
+
+// cancelLocked cancels a booking already locked inside tx, frees what it
+// reserved and returns when the booking was due to start.
+func (s *BookingService) cancelLocked(
+	ctx context.Context,
+	tx *gorm.DB,
+	booking *models.Booking,
+	offer *models.ServiceOffer,
+	actorID uuid.UUID,
+	reason string,
+	now time.Time,
+) (time.Time, error) {
+	if !booking.IsActive() {
+		return time.Time{}, ErrBookingNotCancellable
+	}
+
+	start, err := s.release(ctx, tx, booking, offer)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	percent := 100
+	if actorID == booking.OwnerID {
+		percent = offer.RefundPercent(start.Sub(now).Hours())
+	}
+	if err := s.recordCancellation(ctx, tx, booking, offer, actorID, start, now); err != nil {
+		return time.Time{}, err
+	}
+	if percent == 100 {
+		if err := s.quotes.returnRedeemed(ctx, tx, booking); err != nil {
+			return time.Time{}, err
+		}
+	}
+	booking.Status = models.BookingStatusCancelled
+	booking.CancelledAt = &now
+	booking.CancelledBy = &actorID
+	booking.CancellationReason = reason
+	refund, err := s.refundDue(ctx, tx, booking, percent)
+	if err != nil {
+		return time.Time{}, err
+	}
+	booking.RefundPercent = percent
+	booking.RefundAmount = refund
+	if err := s.bookingRepo.WithTx(tx).Update(ctx, booking); err != nil {
+		return time.Time{}, err
+	}
+	return start, nil
+}
+
+// recordCancellation counts owner cancellations made less than
+// lateCancellationWindow before the start, and every freelancer cancellation,
+// against the cancelling party's reliability.
+func (s *BookingService) recordCancellation(
+	ctx context.Context,
+	tx *gorm.DB,
+	booking *models.Booking,
+	offer *models.ServiceOffer,
+	actorID uuid.UUID,
+	start, now time.Time,
+) error {
+	if booking.Status == models.BookingStatusAwaitingPayment {
+		return nil // nothing was paid, nobody was let down yet
+	}
+	kind := models.ReliabilityFreelancerCancellation
+	if actorID == booking.OwnerID {
+		if start.Sub(now) >= lateCancellationWindow {
+			return nil
+		}
+		kind = models.ReliabilityLateCancellation
+	}
+	return s.reliability.WithTx(tx).Record(ctx, &models.ReliabilityEvent{
+		UserID:    actorID,
+		BookingID: booking.ID,
+		Kind:      kind,
+	})
+}
+
+// refundDue is what cancelling gives back of the booking's captured
+// payments when percent of them is refunded. Nothing is due on a booking
+// that was never paid.
+func (s *BookingService) refundDue(ctx context.Context, tx *gorm.DB, booking *models.Booking, percent int) (money.Money, error) {
+	list, err := s.paymentRepo.WithTx(tx).ListByBooking(ctx, booking.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	due := money.New(0, booking.Currency)
+	for i := range list {
+		if amount := cancellationRefund(&list[i], percent); amount.IsPositive() {
+			due = due.Add(amount)
+		}
+	}
+	return due, nil
+}
+
+// cancellationRefund is what is still to be refunded on a captured payment
+// when percent of it is given back.
+func cancellationRefund(p *models.Payment, percent int) money.Money {
+	if p.Status != models.PaymentCaptured && p.Status != models.PaymentPartlyRefunded {
+		return money.New(0, p.Currency)
+	}
+	return p.Amount.Percent(percent).Sub(p.Refunded)
+}
+
+// release frees the slot or nights a booking holds and returns when it was
+// due to start.
+func (s *BookingService) release(
+	ctx context.Context,
+	tx *gorm.DB,
+	booking *models.Booking,
+	offer *models.ServiceOffer,
+) (time.Time, error) {
+	if booking.IsStay() {
+		if err := s.releaseNights(ctx, tx, booking, offer); err != nil {
+			return time.Time{}, err
+		}
+		return *booking.CheckIn, nil
+	}
+	if err := s.withdrawReschedule(ctx, tx, booking); err != nil {
+		return time.Time{}, err
+	}
+	slot, err := s.releaseSlot(ctx, tx, booking.SlotID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return slot.StartTime, nil
+}
+
+func (s *BookingService) notifyCancelled(ctx context.Context, booking *models.Booking) {
+	for _, l := range s.cancelled {
+		l(ctx, booking)
+	}
+}
+
+func (s *BookingService) notifyCompleted(ctx context.Context, booking *models.Booking) {
+	for _, l := range s.completed {
+		l(ctx, booking)
+	}
+}
+
+// emitCancellation tells both parties who cancelled and what gets refunded.
+func (s *BookingService) emitCancellation(
+	ctx context.Context,
+	b *models.Booking,
+	offer *models.ServiceOffer,
+	start time.Time,
+	now time.Time,
+) {
+	when := start.Format("Jan 2, 2006 at 15:04")
+	refund := fmt.Sprintf("%d%% (%s)", b.RefundPercent, b.RefundAmount)
+
+	var ownerMsg, freelancerMsg string
+	if *b.CancelledBy == b.OwnerID {
+		hours := int(start.Sub(now).Hours())
+		ownerMsg = fmt.Sprintf(
+			"You cancelled %q on %s, %d hours before the start. Under the %s cancellation policy you will be refunded %s.",
+			offer.Title, when, hours, offer.CancellationPolicy, refund,
+		)
+		freelancerMsg = fmt.Sprintf(
+			"The owner cancelled %q on %s, %d hours before the start. They are refunded %s under your %s policy.",
+			offer.Title, when, hours, refund, offer.CancellationPolicy,
+		)
+	} else {
+		ownerMsg = fmt.Sprintf(
+			"The freelancer cancelled %q on %s. You will be refunded %s.",
+			offer.Title, when, refund,
+		)
+		freelancerMsg = fmt.Sprintf(
+			"You cancelled %q on %s. The owner is refunded %s.",
+			offer.Title, when, refund,
+		)
+	}
+
+	for _, a := range []struct {
+		userID  uuid.UUID
+		message string
+	}{{b.OwnerID, ownerMsg}, {offer.FreelancerID, freelancerMsg}} {
+		if err := s.activitySvc.Emit(ctx, a.userID, "Booking cancelled", a.message, "cancellation"); err != nil {
+			fmt.Printf("warning: could not emit activity: %v\n", err)
+		}
+	}
+}
+
+// Confirm accepts a pending booking on behalf of the offer's freelancer.
+func (s *BookingService) Confirm(ctx context.Context, bookingID, actorID uuid.UUID) (*models.Booking, error) {
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+	)
+	now := time.Now()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if actorID != offer.FreelancerID {
+			return ErrNotOfferFreelancer
+		}
+		if booking.DepositOutstanding() {
+			return ErrDepositOutstanding
+		}
+		if booking.Status != models.BookingStatusPending {
+			return ErrBookingNotPending
+		}
+		booking.Status = models.BookingStatusConfirmed
+		booking.ConfirmedAt = &now
+		return s.bookingRepo.WithTx(tx).Update(ctx, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("The freelancer confirmed your booking of %q.", offer.Title)
+	if err := s.activitySvc.Emit(ctx, booking.OwnerID, "Booking confirmed", msg, "confirmation"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+	return booking, nil
+}
+
+// MarkNoShow lets the offer's freelancer report that the owner did not show
+// up for a confirmed booking that has started and was not checked in. It
+// counts against the owner's reliability.
+func (s *BookingService) MarkNoShow(ctx context.Context, bookingID, actorID uuid.UUID) (*models.Booking, error) {
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+	)
+	now := time.Now()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if actorID != offer.FreelancerID {
+			return ErrNotOfferFreelancer
+		}
+		if booking.Status != models.BookingStatusConfirmed {
+			return ErrNoShowNotConfirmed
+		}
+		report, err := s.reportRepo.WithTx(tx).FindByBooking(ctx, booking.ID)
+		if err == nil && report.CheckedInAt != nil {
+			return ErrNoShowVisited
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		start := booking.CheckIn
+		if start == nil {
+			slot, err := s.slotRepo.WithTx(tx).FindByID(ctx, booking.SlotID)
+			if err != nil {
+				return err
+			}
+			start = &slot.StartTime
+		}
+		if now.Before(*start) {
+			return ErrNoShowTooEarly
+		}
+
+		booking.Status = models.BookingStatusNoShow
+		if err := s.bookingRepo.WithTx(tx).Update(ctx, booking); err != nil {
+			return err
+		}
+		return s.reliability.WithTx(tx).Record(ctx, &models.ReliabilityEvent{
+			UserID:    booking.OwnerID,
+			BookingID: booking.ID,
+			Kind:      models.ReliabilityNoShow,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("The freelancer reported that you did not show up for %q.", offer.Title)
+	if err := s.activitySvc.Emit(ctx, booking.OwnerID, "Missed booking", msg, "no_show"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+	return booking, nil
+}
+
 func (s *BookingService) GetBooking(ctx context.Context, id uuid.UUID) (*models.Booking, error) {
 	return s.bookingRepo.FindByID(ctx, id)
 }
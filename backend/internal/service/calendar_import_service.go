@@ -0,0 +1,372 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/ical"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCalendarSize = 5 << 20
+	// imported busy time blocks slots this far ahead
+	importHorizon = 180 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidCalendarURL    = errors.New("calendar URL must be an http, https or webcal address")
+	ErrCalendarImportMissing = errors.New("calendar import not found")
+	ErrEmptyCalendar         = errors.New("calendar file is empty or too large")
+	ErrPrivateCalendarHost   = errors.New("calendars can only be fetched from public addresses")
+)
+
+// CalendarImportService blocks freelancers' open slots that clash with busy
+// time in their own calendars. Imports are synced by a background job, right
+// after they are added and then every interval.
+type CalendarImportService struct {
+	repo         *repository.CalendarImportRepository
+	slotRepo     *repository.AvailabilitySlotRepository
+	settingsRepo *repository.FreelancerSettingsRepository
+	db           *gorm.DB
+	client       *http.Client
+	interval     time.Duration
+	queue        chan uuid.UUID
+}
+
+func NewCalendarImportService(
+	repo *repository.CalendarImportRepository,
+	slotRepo *repository.AvailabilitySlotRepository,
+	settingsRepo *repository.FreelancerSettingsRepository,
+	db *gorm.DB,
+	interval time.Duration,
+) *CalendarImportService {
+	return &CalendarImportService{
+		repo:         repo,
+		slotRepo:     slotRepo,
+		settingsRepo: settingsRepo,
+		db:           db,
+		client:       publicClient(20 * time.Second),
+		interval:     interval,
+		queue:        make(chan uuid.UUID, 64),
+	}
+}
+
+// AddURL registers a calendar to be fetched from rawURL.
+func (s *CalendarImportService) AddURL(ctx context.Context, freelancerID uuid.UUID, rawURL string) (*models.CalendarImport, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, ErrInvalidCalendarURL
+	}
+	switch u.Scheme {
+	case "webcal":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, ErrInvalidCalendarURL
+	}
+	return s.add(ctx, &models.CalendarImport{FreelancerID: freelancerID, SourceURL: u.String()})
+}
+
+// AddUpload registers an uploaded .ics file.
+func (s *CalendarImportService) AddUpload(ctx context.Context, freelancerID uuid.UUID, content []byte) (*models.CalendarImport, error) {
+	if len(bytes.TrimSpace(content)) == 0 || len(content) > maxCalendarSize {
+		return nil, ErrEmptyCalendar
+	}
+	return s.add(ctx, &models.CalendarImport{FreelancerID: freelancerID, Content: string(content)})
+}
+
+func (s *CalendarImportService) add(ctx context.Context, imp *models.CalendarImport) (*models.CalendarImport, error) {
+	imp.Status = models.CalendarImportPending
+	imp.NextSyncAt = time.Now()
+	if err := s.repo.Create(ctx, imp); err != nil {
+		return nil, err
+	}
+	s.enqueue(imp.ID)
+	return imp, nil
+}
+
+func (s *CalendarImportService) List(ctx context.Context, freelancerID uuid.UUID) ([]models.CalendarImport, error) {
+	return s.repo.ListByFreelancer(ctx, freelancerID)
+}
+
+func (s *CalendarImportService) Get(ctx context.Context, id, freelancerID uuid.UUID) (*models.CalendarImport, error) {
+	imp, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCalendarImportMissing
+		}
+		return nil, err
+	}
+	if imp.FreelancerID != freelancerID {
+		return nil, ErrCalendarImportMissing
+	}
+	return imp, nil
+}
+
+// Resync queues the import to be synced right away.
+func (s *CalendarImportService) Resync(ctx context.Context, id, freelancerID uuid.UUID) (*models.CalendarImport, error) {
+	imp, err := s.Get(ctx, id, freelancerID)
+	if err != nil {
+		return nil, err
+	}
+	imp.NextSyncAt = time.Now()
+	if err := s.repo.Update(ctx, imp); err != nil {
+		return nil, err
+	}
+	s.enqueue(imp.ID)
+	return imp, nil
+}
+
+// UseClient replaces the client calendars are fetched with, e.g. in tests
+// that serve calendars from a local server.
+func (s *CalendarImportService) UseClient(c *http.Client) {
+	s.client = c
+}
+
+// Delete removes the import and frees the slots it blocked.
+func (s *CalendarImportService) Delete(ctx context.Context, id, freelancerID uuid.UUID) error {
+	if _, err := s.Get(ctx, id, freelancerID); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.slotRepo.WithTx(tx).Unblock(ctx, id); err != nil {
+			return err
+		}
+		return s.repo.WithTx(tx).Delete(ctx, id)
+	})
+}
+
+// Sync fetches and parses one import, then blocks the open slots that
+// overlap its busy time and frees the ones it no longer covers. A calendar
+// that cannot be fetched keeps blocking what it blocked before.
+func (s *CalendarImportService) Sync(ctx context.Context, id uuid.UUID) error {
+	imp, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	imp.NextSyncAt = now.Add(s.interval)
+
+	content := []byte(imp.Content)
+	if !imp.IsUpload() {
+		if content, err = s.fetch(ctx, imp.SourceURL); err != nil {
+			imp.Status = models.CalendarImportFailed
+			imp.LastError = err.Error()
+			return s.repo.Update(ctx, imp)
+		}
+	}
+
+	settings, err := s.settingsRepo.Find(ctx, imp.FreelancerID)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	events, parseErrs := ical.Parse(bytes.NewReader(content), loc)
+	imp.ParseErrors = make([]string, 0, len(parseErrs))
+	for _, e := range parseErrs {
+		imp.ParseErrors = append(imp.ParseErrors, e.Error())
+	}
+	if len(events) == 0 && len(parseErrs) > 0 {
+		imp.Status = models.CalendarImportFailed
+		imp.LastError = "no events could be read"
+		return s.repo.Update(ctx, imp)
+	}
+
+	var busy []ical.Interval
+	for i := range events {
+		busy = append(busy, events[i].Occurrences(now, now.Add(importHorizon))...)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		slots, err := s.slotRepo.WithTx(tx).ListBlockable(ctx, imp.FreelancerID, imp.ID, now, now.Add(importHorizon))
+		if err != nil {
+			return err
+		}
+		blocked := 0
+		for i := range slots {
+			slot := &slots[i]
+			clash := overlapsAny(busy, slot.StartTime, slot.EndTime)
+			if clash == (slot.BlockedBy != nil) {
+				if clash {
+					blocked++
+				}
+				continue
+			}
+			var by *uuid.UUID
+			if clash {
+				by = &imp.ID
+			}
+			// a slot booked since it was listed is left to its booking
+			changed, err := s.slotRepo.WithTx(tx).SetBlockedBy(ctx, slot.ID, by)
+			if err != nil {
+				return err
+			}
+			if clash && changed {
+				blocked++
+			}
+		}
+
+		imp.Status = models.CalendarImportSynced
+		imp.LastError = ""
+		imp.EventCount = len(events)
+		imp.BlockedSlots = blocked
+		imp.LastSyncedAt = &now
+		return s.repo.WithTx(tx).Update(ctx, imp)
+	})
+}
+
+// SyncDue syncs every import whose next sync is due.
+func (s *CalendarImportService) SyncDue(ctx context.Context, now time.Time) error {
+	due, err := s.repo.ListDue(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, imp := range due {
+		if err := s.Sync(ctx, imp.ID); err != nil {
+			log.Printf("calendar import %s: sync failed: %v", imp.ID, err)
+		}
+	}
+	return nil
+}
+
+// Run syncs newly added imports as they come in and due ones every tick
+// until ctx is done.
+func (s *CalendarImportService) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			if err := s.Sync(ctx, id); err != nil {
+				log.Printf("calendar import %s: sync failed: %v", id, err)
+			}
+		case now := <-ticker.C:
+			if err := s.SyncDue(ctx, now); err != nil {
+				log.Printf("calendar import: listing due imports failed: %v", err)
+			}
+		}
+	}
+}
+
+// enqueue asks the worker to sync id soon; when the queue is full the next
+// tick picks it up instead.
+func (s *CalendarImportService) enqueue(id uuid.UUID) {
+	select {
+	case s.queue <- id:
+	default:
+	}
+}
+
+func (s *CalendarImportService) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching calendar: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxCalendarSize {
+		return nil, ErrEmptyCalendar
+	}
+	return body, nil
+}
+
+// reservedPrefixes are the ranges not covered by the netip checks in
+// publicAddr that still do not reach the public internet.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// publicAddr reports whether ip is on the public internet, rather than the
+// machine itself, the local network or a cloud metadata service.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicClient returns a client that only connects to public addresses.
+// The address is checked when the connection is made, after DNS resolution,
+// so neither a hostname resolving to a private address nor a redirect to
+// one gets through. Proxies from the environment are not used.
+func publicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !publicAddr(ip) {
+				return ErrPrivateCalendarHost
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidCalendarURL
+			}
+			return nil
+		},
+	}
+}
+
+func overlapsAny(busy []ical.Interval, start, end time.Time) bool {
+	for _, b := range busy {
+		if b.Start.Before(end) && b.End.After(start) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/ical"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	calendarProdID = "-//pet-freelance//calendar feed//EN"
+	uidDomain      = "pet-freelance"
+
+	// feeds reach this far back and, for availability, this far ahead
+	feedHistory = 30 * 24 * time.Hour
+	feedHorizon = 90 * 24 * time.Hour
+
+	// cancelled bookings stay in feeds this long after they last changed, so
+	// that subscribed clients see the cancellation and drop the event
+	cancelledFeedWindow = 7 * 24 * time.Hour
+)
+
+var (
+	ErrFeedNotFound    = errors.New("calendar feed not found")
+	ErrUnknownTimezone = errors.New("unknown timezone")
+)
+
+// CalendarService publishes a user's bookings and an offer's free slots as
+// iCalendar feeds behind a per-user secret token.
+type CalendarService struct {
+	feedRepo       *repository.CalendarFeedRepository
+	bookingRepo    *repository.BookingRepository
+	rescheduleRepo *repository.BookingRescheduleRepository
+	offerRepo      *repository.ServiceOfferRepository
+	settingsRepo   *repository.FreelancerSettingsRepository
+	slotRepo       *repository.AvailabilitySlotRepository
+	slotSvc        *AvailabilitySlotService
+}
+
+func NewCalendarService(
+	feedRepo *repository.CalendarFeedRepository,
+	bookingRepo *repository.BookingRepository,
+	rescheduleRepo *repository.BookingRescheduleRepository,
+	offerRepo *repository.ServiceOfferRepository,
+	settingsRepo *repository.FreelancerSettingsRepository,
+	slotRepo *repository.AvailabilitySlotRepository,
+	slotSvc *AvailabilitySlotService,
+) *CalendarService {
+	return &CalendarService{
+		feedRepo:       feedRepo,
+		bookingRepo:    bookingRepo,
+		rescheduleRepo: rescheduleRepo,
+		offerRepo:      offerRepo,
+		settingsRepo:   settingsRepo,
+		slotRepo:       slotRepo,
+		slotSvc:        slotSvc,
+	}
+}
+
+// Feed returns the user's feed token, creating one on first use or replacing
+// it when rotate is set.
+func (s *CalendarService) Feed(ctx context.Context, userID uuid.UUID, rotate bool) (*models.CalendarFeed, error) {
+	feed, err := s.feedRepo.FindByUser(ctx, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		feed = &models.CalendarFeed{UserID: userID}
+	case err != nil:
+		return nil, err
+	case !rotate:
+		return feed, nil
+	}
+	if feed.Token, err = newFeedToken(); err != nil {
+		return nil, err
+	}
+	if err := s.feedRepo.Save(ctx, feed); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// BookingsFeed renders the bookings the token's user made or takes on. An
+// empty tz uses the timezone from the user's settings. Cancelled bookings and
+// no-shows are cancelled events until cancelledFeedWindow has passed.
+func (s *CalendarService) BookingsFeed(ctx context.Context, token, tz string) ([]byte, error) {
+	userID, loc, err := s.resolve(ctx, token, tz)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	bookings, err := s.bookingRepo.ListByParty(ctx, userID, now.Add(-feedHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ids     = make([]uuid.UUID, 0, len(bookings))
+		slotIDs []uuid.UUID
+	)
+	for _, b := range bookings {
+		ids = append(ids, b.ID)
+		if !b.IsStay() {
+			slotIDs = append(slotIDs, b.SlotID)
+		}
+	}
+	moves, err := s.rescheduleRepo.CountApplied(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := s.slotRepo.ListByIDs(ctx, slotIDs)
+	if err != nil {
+		return nil, err
+	}
+	slotByID := make(map[uuid.UUID]*models.AvailabilitySlot, len(slots))
+	for i := range slots {
+		slotByID[slots[i].ID] = &slots[i]
+	}
+	offers := make(map[uuid.UUID]*models.ServiceOffer)
+
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: "Pet care bookings", Location: loc}
+	for _, b := range bookings {
+		offer, ok := offers[b.OfferID]
+		if !ok {
+			if offer, err = s.offerRepo.FindByID(ctx, b.OfferID); err != nil {
+				return nil, err
+			}
+			offers[b.OfferID] = offer
+		}
+
+		ev := ical.Event{
+			UID:          fmt.Sprintf("booking-%s@%s", b.ID, uidDomain),
+			Sequence:     moves[b.ID],
+			Summary:      offer.Title,
+			Description:  offer.Description,
+			Created:      b.CreatedAt,
+			LastModified: b.UpdatedAt,
+		}
+		if b.IsStay() {
+			ev.Start, ev.End = *b.CheckIn, *b.CheckOut
+		} else if slot, ok := slotByID[b.SlotID]; ok {
+			ev.Start, ev.End = slot.StartTime, slot.EndTime
+		} else {
+			continue
+		}
+		switch b.Status {
+		case models.BookingStatusCancelled, models.BookingStatusNoShow:
+			if now.Sub(b.UpdatedAt) > cancelledFeedWindow {
+				continue
+			}
+			ev.Status = ical.StatusCancelled
+			ev.Transparent = true
+			ev.Sequence++
+		case models.BookingStatusPending, models.BookingStatusAwaitingPayment:
+			ev.Status = ical.StatusTentative
+		default:
+			ev.Status = ical.StatusConfirmed
+		}
+		cal.Events = append(cal.Events, ev)
+	}
+	return cal.Encode(), nil
+}
+
+// AvailabilityFeed renders the free slots of one of the token user's offers
+// over the coming weeks, leaving out time off.
+func (s *CalendarService) AvailabilityFeed(ctx context.Context, token string, offerID uuid.UUID, tz string) ([]byte, error) {
+	userID, loc, err := s.resolve(ctx, token, tz)
+	if err != nil {
+		return nil, err
+	}
+	offer, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	if offer.FreelancerID != userID {
+		return nil, ErrOfferNotFound
+	}
+	now := time.Now()
+	slots, err := s.slotSvc.ListSlots(ctx, offerID, true, now, now.Add(feedHorizon))
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: offer.Title + " availability", Location: loc}
+	for _, slot := range slots {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:          fmt.Sprintf("slot-%s@%s", slot.ID, uidDomain),
+			Start:        slot.StartTime,
+			End:          slot.EndTime,
+			Summary:      fmt.Sprintf("Available: %s (%d of %d free)", offer.Title, slot.RemainingSeats, slot.Capacity),
+			Status:       ical.StatusTentative,
+			Transparent:  true,
+			Created:      slot.CreatedAt,
+			LastModified: slot.UpdatedAt,
+		})
+	}
+	return cal.Encode(), nil
+}
+
+// resolve maps a feed token to its user and the timezone to write in.
+func (s *CalendarService) resolve(ctx context.Context, token, tz string) (uuid.UUID, *time.Location, error) {
+	feed, err := s.feedRepo.FindByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, nil, ErrFeedNotFound
+		}
+		return uuid.Nil, nil, err
+	}
+	if tz == "" {
+		settings, err := s.settingsRepo.Find(ctx, feed.UserID)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+		tz = settings.Timezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return uuid.Nil, nil, ErrUnknownTimezone
+	}
+	return feed.UserID, loc, nil
+}
+
+func newFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
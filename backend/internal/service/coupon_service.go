@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCoupon   = errors.New("invalid coupon: percent coupons need a percent from 1 to 100, fixed ones a positive amount and a currency, and a minimum spend needs a currency")
+	ErrCouponCodeTaken = errors.New("coupon code is already in use")
+)
+
+// CouponInput describes a new coupon. Amount and MinSpend are in Currency.
+type CouponInput struct {
+	Code             string
+	Description      string
+	Kind             string
+	Percent          int
+	Amount           money.Decimal
+	Currency         string
+	MinSpend         money.Decimal
+	ValidFrom        *time.Time
+	ValidUntil       *time.Time
+	MaxUses          int
+	MaxUsesPerUser   int
+	ServiceIDs       []uuid.UUID
+	OfferIDs         []uuid.UUID
+	FreelancerIDs    []uuid.UUID
+	FirstBookingOnly bool
+}
+
+// CouponUpdate changes the fields of a coupon that are set. What a coupon
+// takes off and where it applies cannot change once it may have been used.
+type CouponUpdate struct {
+	Description    *string
+	IsActive       *bool
+	ValidFrom      *time.Time
+	ValidUntil     *time.Time
+	MaxUses        *int
+	MaxUsesPerUser *int
+}
+
+// CouponDetail is a coupon with the bookings it was redeemed on.
+type CouponDetail struct {
+	Coupon      *models.Coupon            `json:"coupon"`
+	Redemptions []models.CouponRedemption `json:"redemptions"`
+}
+
+// CouponService lets admins manage coupons. They are redeemed through the
+// QuoteService when bookings are made.
+type CouponService struct {
+	repo *repository.CouponRepository
+}
+
+func NewCouponService(repo *repository.CouponRepository) *CouponService {
+	return &CouponService{repo}
+}
+
+func (s *CouponService) Create(ctx context.Context, in CouponInput) (*models.Coupon, error) {
+	c := &models.Coupon{
+		Code:             NormalizeCouponCode(in.Code),
+		Description:      in.Description,
+		Kind:             in.Kind,
+		Percent:          in.Percent,
+		Currency:         in.Currency,
+		ValidFrom:        in.ValidFrom,
+		ValidUntil:       in.ValidUntil,
+		MaxUses:          in.MaxUses,
+		MaxUsesPerUser:   in.MaxUsesPerUser,
+		ServiceIDs:       in.ServiceIDs,
+		OfferIDs:         in.OfferIDs,
+		FreelancerIDs:    in.FreelancerIDs,
+		FirstBookingOnly: in.FirstBookingOnly,
+		IsActive:         true,
+	}
+	if c.Code == "" || (in.Amount != "" || in.MinSpend != "") && c.Currency == "" {
+		return nil, ErrInvalidCoupon
+	}
+	var err error
+	if c.Amount, err = parseOptional(in.Amount, c.Currency); err != nil {
+		return nil, ErrInvalidCoupon
+	}
+	if c.MinSpend, err = parseOptional(in.MinSpend, c.Currency); err != nil || c.MinSpend.Amount < 0 {
+		return nil, ErrInvalidCoupon
+	}
+	switch c.Kind {
+	case models.CouponPercent:
+		if c.Percent < 1 || c.Percent > 100 || !c.Amount.IsZero() {
+			return nil, ErrInvalidCoupon
+		}
+	case models.CouponFixed:
+		if c.Percent != 0 || !c.Amount.IsPositive() {
+			return nil, ErrInvalidCoupon
+		}
+	default:
+		return nil, ErrInvalidCoupon
+	}
+	if err := validWindow(c); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.FindByCode(ctx, c.Code); err == nil {
+		return nil, ErrCouponCodeTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *CouponService) List(ctx context.Context) ([]models.Coupon, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *CouponService) Get(ctx context.Context, id uuid.UUID) (*CouponDetail, error) {
+	c, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	redemptions, err := s.repo.ListRedemptions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &CouponDetail{c, redemptions}, nil
+}
+
+func (s *CouponService) Update(ctx context.Context, id uuid.UUID, in CouponUpdate) (*models.Coupon, error) {
+	c, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Description != nil {
+		c.Description = *in.Description
+	}
+	if in.IsActive != nil {
+		c.IsActive = *in.IsActive
+	}
+	if in.ValidFrom != nil {
+		c.ValidFrom = in.ValidFrom
+	}
+	if in.ValidUntil != nil {
+		c.ValidUntil = in.ValidUntil
+	}
+	if in.MaxUses != nil {
+		c.MaxUses = *in.MaxUses
+	}
+	if in.MaxUsesPerUser != nil {
+		c.MaxUsesPerUser = *in.MaxUsesPerUser
+	}
+	if err := validWindow(c); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *CouponService) find(ctx context.Context, id uuid.UUID) (*models.Coupon, error) {
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func validWindow(c *models.Coupon) error {
+	if c.MaxUses < 0 || c.MaxUsesPerUser < 0 {
+		return ErrInvalidCoupon
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidUntil.After(*c.ValidFrom) {
+		return ErrInvalidCoupon
+	}
+	return nil
+}
+
+// parseOptional parses d in currency, an empty d being zero.
+func parseOptional(d money.Decimal, currency string) (money.Money, error) {
+	if d == "" {
+		return money.New(0, currency), nil
+	}
+	return money.Parse(string(d), currency)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"regexp"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+)
+
+var (
+	ErrInvalidRates   = errors.New("exchange rates need a base currency and positive rates keyed by 3-letter currency codes")
+	ErrNoExchangeRate = errors.New("no exchange rate for the requested currency")
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// RateTable is a set of exchange rates as loaded from a file or sent by an
+// admin: how many units of each currency one unit of Base buys, e.g.
+//
+//	{"base": "EUR", "rates": {"USD": "1.0825", "GBP": "0.8561"}}
+type RateTable struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// Rates converts between the currencies of a rate table.
+type Rates map[string]*big.Rat
+
+// Convert returns m in currency to, or false without a rate for either
+// currency.
+func (r Rates) Convert(m money.Money, to string) (money.Money, bool) {
+	if m.Currency == to {
+		return m, true
+	}
+	from, ok1 := r[m.Currency]
+	into, ok2 := r[to]
+	if !ok1 || !ok2 {
+		return money.Money{}, false
+	}
+	return m.Convert(to, new(big.Rat).Quo(into, from)), true
+}
+
+// ExchangeRateService keeps the exchange rates offer prices are displayed
+// with. They only serve to compare prices; bookings are always charged in
+// the offer's own currency.
+type ExchangeRateService struct {
+	repo *repository.ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo *repository.ExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{repo}
+}
+
+// Replace makes t the rate table.
+func (s *ExchangeRateService) Replace(ctx context.Context, t RateTable) ([]models.ExchangeRate, error) {
+	list, err := t.rows()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Replace(ctx, list); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx)
+}
+
+// LoadFile seeds the rate table with the RateTable in the JSON file at path.
+// Once there are rates, set through Replace or an earlier start, the file is
+// ignored, and it reports false.
+func (s *ExchangeRateService) LoadFile(ctx context.Context, path string) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var t RateTable
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return false, err
+	}
+	list, err := t.rows()
+	if err != nil {
+		return false, err
+	}
+	return s.repo.Seed(ctx, list)
+}
+
+// rows validates the table and returns it as stored, the base at rate 1.
+func (t RateTable) rows() ([]models.ExchangeRate, error) {
+	if !currencyCode.MatchString(t.Base) {
+		return nil, ErrInvalidRates
+	}
+	list := []models.ExchangeRate{{Currency: t.Base, Rate: "1"}}
+	for currency, rate := range t.Rates {
+		if currency == t.Base {
+			continue
+		}
+		r := models.ExchangeRate{Currency: currency, Rate: rate}
+		if _, ok := r.Rat(); !ok || !currencyCode.MatchString(currency) {
+			return nil, ErrInvalidRates
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (s *ExchangeRateService) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	return s.repo.List(ctx)
+}
+
+// Rates returns the current rate table for converting.
+func (s *ExchangeRateService) Rates(ctx context.Context) (Rates, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(Rates, len(list))
+	for i := range list {
+		if r, ok := list[i].Rat(); ok {
+			rates[list[i].Currency] = r
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/pdf"
+)
+
+// invoiceDocument is an invoice or credit note as it is printed. Subject
+// names what was booked; RefersTo is the number of the invoice a credit
+// note corrects.
+type invoiceDocument struct {
+	*models.Invoice
+	Subject  string
+	RefersTo string
+}
+
+func (d invoiceDocument) Title() string {
+	if d.Kind == models.InvoiceKindCreditNote {
+		return "Credit note"
+	}
+	return "Invoice"
+}
+
+func (d invoiceDocument) Date() string {
+	return d.IssuedAt.Format("2 January 2006")
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"args": func(heading string, p models.Party) map[string]any {
+		return map[string]any{"Heading": heading, "Party": p}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 40px; color: #222; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+td { padding: 4px 0; }
+td.amount { text-align: right; font-family: Courier, monospace; }
+tr.total td { border-top: 1px solid #222; font-weight: bold; }
+.parties { display: flex; gap: 80px; margin-top: 24px; }
+.address { white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} {{.Number}}</h1>
+<p>Issued {{.Date}}{{if .RefersTo}} &middot; corrects invoice {{.RefersTo}}{{end}}</p>
+<div class="parties">
+{{template "party" (args "From" .Seller)}}
+{{template "party" (args "To" .Buyer)}}
+</div>
+<p>{{.Subject}}</p>
+{{if .Reason}}<p>Reason: {{.Reason}}</p>{{end}}
+<table>
+{{range .Price.Lines}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount.Decimal}}</td></tr>
+{{end}}<tr class="total"><td>Total ({{.Currency}})</td><td class="amount">{{.Total.Decimal}}</td></tr>
+</table>
+</body>
+</html>
+{{define "party"}}<div>
+<h3>{{.Heading}}</h3>
+{{with .Party}}{{if .Name}}<div>{{.Name}}</div>{{end}}
+{{if .Address}}<div class="address">{{.Address}}</div>{{end}}
+<div>{{.Email}}</div>
+{{if .Phone}}<div>{{.Phone}}</div>{{end}}
+{{if .TaxID}}<div>Tax ID: {{.TaxID}}</div>{{end}}{{end}}
+</div>{{end}}`))
+
+// HTML renders the document as a standalone HTML page.
+func (d invoiceDocument) HTML() (string, error) {
+	var b bytes.Buffer
+	if err := invoiceTemplate.Execute(&b, d); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// PDF renders the document as an A4 PDF.
+func (d invoiceDocument) PDF() []byte {
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		bottom = 60.0
+		size   = 10.0
+		lead   = 14.0
+	)
+	doc := pdf.New()
+	page := doc.AddPage()
+	y := pdf.PageHeight - 60
+	// next moves down a line, onto a new page when this one is full
+	next := func() {
+		y -= lead
+		if y < bottom {
+			page = doc.AddPage()
+			y = pdf.PageHeight - 60
+		}
+	}
+
+	page.Text(left, y, pdf.Bold, 20, d.Title()+" "+d.Number)
+	next()
+	next()
+	issued := "Issued " + d.Date()
+	if d.RefersTo != "" {
+		issued += ", corrects invoice " + d.RefersTo
+	}
+	page.Text(left, y, pdf.Regular, size, issued)
+	next()
+	next()
+
+	seller, buyer := partyLines(d.Seller), partyLines(d.Buyer)
+	page.Text(left, y, pdf.Bold, size, "From")
+	page.Text(pdf.PageWidth/2, y, pdf.Bold, size, "To")
+	for i := 0; i < max(len(seller), len(buyer)); i++ {
+		next()
+		if i < len(seller) {
+			page.Text(left, y, pdf.Regular, size, seller[i])
+		}
+		if i < len(buyer) {
+			page.Text(pdf.PageWidth/2, y, pdf.Regular, size, buyer[i])
+		}
+	}
+	next()
+	next()
+	page.Text(left, y, pdf.Regular, size, d.Subject)
+	if d.Reason != "" {
+		next()
+		page.Text(left, y, pdf.Regular, size, "Reason: "+d.Reason)
+	}
+	next()
+	next()
+
+	for _, l := range d.Price.Lines {
+		page.Text(left, y, pdf.Regular, size, l.Label)
+		page.TextRight(right, y, size, l.Amount.Decimal())
+		next()
+	}
+	page.Line(left, y+lead-4, right, y+lead-4)
+	page.Text(left, y, pdf.Bold, size, "Total ("+d.Currency+")")
+	page.TextRight(right, y, size, d.Total.Decimal())
+	return doc.Bytes()
+}
+
+// partyLines returns what is printed of a party, one line each.
+func partyLines(p models.Party) []string {
+	var lines []string
+	if p.Name != "" {
+		lines = append(lines, p.Name)
+	}
+	for _, l := range strings.Split(p.Address, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, p.Email)
+	if p.Phone != "" {
+		lines = append(lines, p.Phone)
+	}
+	if p.TaxID != "" {
+		lines = append(lines, "Tax ID: "+p.TaxID)
+	}
+	return lines
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBookingNotCompleted = errors.New("invoices are only issued for completed bookings")
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+	ErrInvalidRefund       = errors.New("refund must be a positive amount in the invoice's currency")
+)
+
+// InvoiceService issues the invoice of a completed booking, in the
+// freelancer's name to the owner, and credit notes for what the freelancer
+// refunds on it afterwards. Documents are rendered to HTML and PDF when they
+// are issued and stored with them.
+type InvoiceService struct {
+	bookings *BookingService
+	repo     *repository.InvoiceRepository
+	users    *repository.UserRepository
+	settings *repository.FreelancerSettingsRepository
+	payouts  *PayoutService
+}
+
+// NewInvoiceService registers for completed bookings. It has to be created
+// after the PayoutService, so a booking's earning is recorded before it can
+// be refunded.
+func NewInvoiceService(
+	bookings *BookingService,
+	repo *repository.InvoiceRepository,
+	users *repository.UserRepository,
+	settings *repository.FreelancerSettingsRepository,
+	payouts *PayoutService,
+) *InvoiceService {
+	s := &InvoiceService{bookings, repo, users, settings, payouts}
+	bookings.OnCompleted(s.issueCompleted)
+	return s
+}
+
+// Invoice returns the booking's invoice, issuing it if that has not happened
+// yet. Only the owner and the freelancer can see it.
+func (s *InvoiceService) Invoice(ctx context.Context, bookingID, actorID uuid.UUID) (*models.Invoice, error) {
+	booking, offer, err := s.load(ctx, bookingID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusCompleted {
+		return nil, ErrBookingNotCompleted
+	}
+	return s.issue(ctx, booking, offer)
+}
+
+// CreditNotes returns the credit notes issued on the booking's invoice,
+// oldest first.
+func (s *InvoiceService) CreditNotes(ctx context.Context, bookingID, actorID uuid.UUID) ([]models.Invoice, error) {
+	if _, _, err := s.load(ctx, bookingID, actorID); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListByBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]models.Invoice, 0, len(list))
+	for _, i := range list {
+		if i.Kind == models.InvoiceKindCreditNote {
+			notes = append(notes, i)
+		}
+	}
+	return notes, nil
+}
+
+// CreditNote returns one of the booking's credit notes.
+func (s *InvoiceService) CreditNote(ctx context.Context, bookingID, noteID, actorID uuid.UUID) (*models.Invoice, error) {
+	if _, _, err := s.load(ctx, bookingID, actorID); err != nil {
+		return nil, err
+	}
+	note, err := s.repo.FindByID(ctx, noteID)
+	if err != nil || note.BookingID != bookingID || note.Kind != models.InvoiceKindCreditNote {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	return note, nil
+}
+
+// Refund pays amount of a completed booking back to the owner and issues a
+// credit note for it. Only the freelancer can refund, and only until the
+// booking's earning is paid out; the refund comes off the earning, and the
+// commission is recomputed on what is left. If the provider fails to pay it
+// back, nothing is refunded and ErrRefundFailed is returned.
+func (s *InvoiceService) Refund(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	amount money.Decimal,
+	reason string,
+) (*models.Invoice, error) {
+	booking, offer, err := s.load(ctx, bookingID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID != offer.FreelancerID {
+		return nil, ErrNotOfferFreelancer
+	}
+	if booking.Status != models.BookingStatusCompleted {
+		return nil, ErrBookingNotCompleted
+	}
+	invoice, err := s.issue(ctx, booking, offer)
+	if err != nil {
+		return nil, err
+	}
+	value, err := money.Parse(string(amount), invoice.Currency)
+	if err != nil || !value.IsPositive() {
+		return nil, ErrInvalidRefund
+	}
+
+	note := &models.Invoice{
+		Kind:         models.InvoiceKindCreditNote,
+		FreelancerID: invoice.FreelancerID,
+		BookingID:    booking.ID,
+		OwnerID:      booking.OwnerID,
+		InvoiceID:    &invoice.ID,
+		Seller:       invoice.Seller,
+		Buyer:        invoice.Buyer,
+		Price:        creditLines(invoice, value),
+		Currency:     invoice.Currency,
+		Total:        money.New(-value.Amount, invoice.Currency),
+		Reason:       reason,
+	}
+	err = s.bookings.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.payouts.reverseEarning(ctx, tx, booking, value); err != nil {
+			return err
+		}
+		if err := s.create(ctx, tx, note, offer, invoice.Number); err != nil {
+			return err
+		}
+		// last, so a refund the provider refuses leaves nothing behind
+		return s.payouts.refundPayments(ctx, tx, booking, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.bookings.activitySvc.Emit(ctx, booking.OwnerID, "Refund issued",
+		fmt.Sprintf("You were refunded %s for %q (credit note %s).", value, offer.Title, note.Number), "payment"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+	return note, nil
+}
+
+// issueCompleted issues the invoice as soon as the booking is completed.
+func (s *InvoiceService) issueCompleted(ctx context.Context, booking *models.Booking) {
+	offer, err := s.bookings.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		fmt.Printf("warning: could not load offer of booking %s: %v\n", booking.ID, err)
+		return
+	}
+	if _, err := s.issue(ctx, booking, offer); err != nil {
+		fmt.Printf("warning: could not issue invoice of booking %s: %v\n", booking.ID, err)
+	}
+}
+
+// load returns the booking and its offer if actorID is one of its parties.
+func (s *InvoiceService) load(ctx context.Context, bookingID, actorID uuid.UUID) (*models.Booking, *models.ServiceOffer, error) {
+	b := s.bookings
+	booking, err := b.bookingRepo.FindByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrBookingNotFound
+		}
+		return nil, nil, err
+	}
+	offer, err := b.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actorID != booking.OwnerID && actorID != offer.FreelancerID {
+		return nil, nil, ErrNotBookingParty
+	}
+	return booking, offer, nil
+}
+
+// issue returns the booking's invoice, issuing it first if there is none.
+// Bookings made before quotes existed are invoiced as one line of their
+// total.
+func (s *InvoiceService) issue(ctx context.Context, booking *models.Booking, offer *models.ServiceOffer) (*models.Invoice, error) {
+	existing, err := s.repo.FindInvoice(ctx, booking.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	seller, buyer, err := s.parties(ctx, offer.FreelancerID, booking.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	price := booking.Price
+	if price == nil {
+		currency := booking.Currency
+		if currency == "" {
+			currency = offer.Currency
+		}
+		price = models.NewPriceBreakdown(currency)
+		price.Add(models.PriceLineBase, offer.Title, money.New(booking.Total.Amount, currency))
+	}
+	invoice := &models.Invoice{
+		Kind:         models.InvoiceKindInvoice,
+		FreelancerID: offer.FreelancerID,
+		BookingID:    booking.ID,
+		OwnerID:      booking.OwnerID,
+		Seller:       *seller,
+		Buyer:        *buyer,
+		Price:        price,
+		Currency:     price.Currency,
+		Total:        price.Total,
+	}
+	err = s.bookings.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the freelancer's invoice counter is locked from here on, so a
+		// second issuer waits and then finds the invoice
+		seq, err := s.repo.WithTx(tx).NextSeq(ctx, offer.FreelancerID, models.InvoiceKindInvoice)
+		if err != nil {
+			return err
+		}
+		if found, err := s.repo.WithTx(tx).FindInvoice(ctx, booking.ID); err == nil {
+			existing = found
+			return errRolledBack
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		invoice.Seq = seq
+		return s.create(ctx, tx, invoice, offer, "")
+	})
+	if errors.Is(err, errRolledBack) {
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// errRolledBack rolls back a transaction that found nothing left to do.
+var errRolledBack = errors.New("rolled back")
+
+// create numbers the document unless it has a number already, renders it
+// and stores it. refersTo is the number of the invoice a credit note
+// corrects.
+func (s *InvoiceService) create(ctx context.Context, tx *gorm.DB, i *models.Invoice, offer *models.ServiceOffer, refersTo string) error {
+	repo := s.repo.WithTx(tx)
+	if i.Seq == 0 {
+		seq, err := repo.NextSeq(ctx, i.FreelancerID, i.Kind)
+		if err != nil {
+			return err
+		}
+		i.Seq = seq
+	}
+	i.Number = models.InvoiceNumber(i.Kind, i.Seq)
+	i.IssuedAt = time.Now().UTC()
+
+	doc := invoiceDocument{Invoice: i, Subject: offer.Title, RefersTo: refersTo}
+	html, err := doc.HTML()
+	if err != nil {
+		return err
+	}
+	i.HTML = html
+	i.PDF = doc.PDF()
+	return repo.Create(ctx, i)
+}
+
+// parties returns the seller and buyer details as they are now, to be kept
+// on the invoice.
+func (s *InvoiceService) parties(ctx context.Context, freelancerID, ownerID uuid.UUID) (*models.Party, *models.Party, error) {
+	freelancer, err := s.users.FindByID(ctx, freelancerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	owner, err := s.users.FindByID(ctx, ownerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings, err := s.settings.Find(ctx, freelancerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	seller := &models.Party{
+		Name:    settings.InvoiceName,
+		Email:   freelancer.Email,
+		Address: settings.InvoiceAddress,
+		TaxID:   settings.TaxID,
+	}
+	if freelancer.Phone != nil {
+		seller.Phone = *freelancer.Phone
+	}
+	buyer := &models.Party{Email: owner.Email}
+	if owner.Phone != nil {
+		buyer.Phone = *owner.Phone
+	}
+	return seller, buyer, nil
+}
+
+// creditLines itemises a credit note for value refunded on the invoice: the
+// tax and fees it included in proportion to the invoice's total, the rest
+// as the refund itself. All amounts are negative.
+func creditLines(invoice *models.Invoice, value money.Money) *models.PriceBreakdown {
+	p := models.NewPriceBreakdown(invoice.Currency)
+	var tax, fees money.Money
+	if total := invoice.Price.Total.Amount; total > 0 {
+		tax = invoice.Price.Tax.Scale(value.Amount, total)
+		fees = invoice.Price.Fees.Scale(value.Amount, total)
+	}
+	p.Add(models.PriceLineBase, "Refund on invoice "+invoice.Number, money.New(tax.Amount+fees.Amount-value.Amount, invoice.Currency))
+	if !tax.IsZero() {
+		p.Add(models.PriceLineTax, "Tax", money.New(-tax.Amount, invoice.Currency))
+	}
+	if !fees.IsZero() {
+		p.Add(models.PriceLineFee, "Service fee", money.New(-fees.Amount, invoice.Currency))
+	}
+	return p
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry does not balance")
+
+// LedgerService keeps the double-entry books of the marketplace. Money an
+// owner pays lands in the platform's cash and is held in the owner's wallet;
+// what is refunded leaves cash again through the refunds clearing account.
+// What is kept moves on to the freelancer's payable, less the platform's
+// fee, and leaves cash when it is paid out.
+type LedgerService struct {
+	repo     *repository.LedgerRepository
+	payments *repository.PaymentRepository
+}
+
+func NewLedgerService(repo *repository.LedgerRepository, payments *repository.PaymentRepository) *LedgerService {
+	return &LedgerService{repo, payments}
+}
+
+// Leg is one line of a posting: Amount on the account of Kind held by
+// UserID, or by the platform when UserID is uuid.Nil. Positive amounts are
+// debits, negative amounts credits.
+type Leg struct {
+	Kind   string
+	UserID uuid.UUID
+	Amount money.Money
+}
+
+// AccountBalance is an account together with its balance, positive when the
+// account holds money in its normal direction.
+type AccountBalance struct {
+	models.LedgerAccount
+	Balance money.Money `json:"balance"`
+}
+
+// Post records entry with one line per leg inside tx. The legs must be in
+// the entry's currency and sum to zero; the accounts are opened as needed.
+func (s *LedgerService) Post(ctx context.Context, tx *gorm.DB, entry *models.JournalEntry, legs ...Leg) error {
+	sum := money.New(0, entry.Currency)
+	for _, l := range legs {
+		if l.Amount.Currency != entry.Currency {
+			return ErrUnbalancedEntry
+		}
+		sum = sum.Add(l.Amount)
+	}
+	if !sum.IsZero() || len(legs) < 2 {
+		return ErrUnbalancedEntry
+	}
+
+	repo := s.repo.WithTx(tx)
+	for _, l := range legs {
+		account, err := repo.Account(ctx, l.Kind, l.UserID, entry.Currency)
+		if err != nil {
+			return err
+		}
+		entry.Lines = append(entry.Lines, models.JournalLine{AccountID: account.ID, Amount: l.Amount})
+	}
+	return repo.CreateEntry(ctx, entry)
+}
+
+// Charge books a captured payment: the cash is held in the owner's wallet.
+func (s *LedgerService) Charge(ctx context.Context, tx *gorm.DB, p *models.Payment, ownerID uuid.UUID) error {
+	entry := &models.JournalEntry{
+		Kind:      models.JournalCharge,
+		BookingID: &p.BookingID,
+		Reference: p.IntentID,
+		Currency:  p.Currency,
+		Memo:      "payment captured",
+	}
+	return s.Post(ctx, tx, entry,
+		Leg{models.LedgerCash, uuid.Nil, p.Amount},
+		Leg{models.LedgerOwnerWallet, ownerID, p.Amount.Neg()},
+	)
+}
+
+// Refund books amount paid back on p out of the owner's wallet.
+func (s *LedgerService) Refund(ctx context.Context, tx *gorm.DB, p *models.Payment, ownerID uuid.UUID, amount money.Money) error {
+	entry := &models.JournalEntry{
+		Kind:      models.JournalRefund,
+		BookingID: &p.BookingID,
+		Reference: p.IntentID,
+		Currency:  p.Currency,
+		Memo:      "payment refunded",
+	}
+	return s.Post(ctx, tx, entry,
+		Leg{models.LedgerOwnerWallet, ownerID, amount},
+		Leg{models.LedgerRefunds, uuid.Nil, amount.Neg()},
+		Leg{models.LedgerRefunds, uuid.Nil, amount},
+		Leg{models.LedgerCash, uuid.Nil, amount.Neg()},
+	)
+}
+
+// Earn books an earning: the money held for the booking becomes payable to
+// the freelancer, and the commission moves on to the platform's revenue.
+func (s *LedgerService) Earn(ctx context.Context, tx *gorm.DB, e *models.Earning, ownerID uuid.UUID) error {
+	earning := &models.JournalEntry{
+		Kind:      models.JournalEarning,
+		BookingID: &e.BookingID,
+		Reference: e.ID.String(),
+		Currency:  e.Currency,
+		Memo:      "booking settled",
+	}
+	err := s.Post(ctx, tx, earning,
+		Leg{models.LedgerOwnerWallet, ownerID, e.Gross},
+		Leg{models.LedgerFreelancerPayable, e.FreelancerID, e.Gross.Neg()},
+	)
+	if err != nil || e.Commission.IsZero() {
+		return err
+	}
+	fee := &models.JournalEntry{
+		Kind:      models.JournalFee,
+		BookingID: &e.BookingID,
+		Reference: e.ID.String(),
+		Currency:  e.Currency,
+		Memo:      fmt.Sprintf("commission of %d bps", e.CommissionBps),
+	}
+	return s.Post(ctx, tx, fee,
+		Leg{models.LedgerFreelancerPayable, e.FreelancerID, e.Commission},
+		Leg{models.LedgerPlatformRevenue, uuid.Nil, e.Commission.Neg()},
+	)
+}
+
+// Reverse books amount of an earning going back to the owner's wallet to be
+// refunded, commission of it out of the platform's revenue.
+func (s *LedgerService) Reverse(ctx context.Context, tx *gorm.DB, e *models.Earning, ownerID uuid.UUID, amount, commission money.Money) error {
+	entry := &models.JournalEntry{
+		Kind:      models.JournalReversal,
+		BookingID: &e.BookingID,
+		Reference: e.ID.String(),
+		Currency:  e.Currency,
+		Memo:      "earning reversed for a refund",
+	}
+	return s.Post(ctx, tx, entry,
+		Leg{models.LedgerFreelancerPayable, e.FreelancerID, amount.Sub(commission)},
+		Leg{models.LedgerPlatformRevenue, uuid.Nil, commission},
+		Leg{models.LedgerOwnerWallet, ownerID, amount.Neg()},
+	)
+}
+
+// Payout books the part of a payout that pays the earning out.
+func (s *LedgerService) Payout(ctx context.Context, tx *gorm.DB, p *models.Payout, e *models.Earning) error {
+	entry := &models.JournalEntry{
+		Kind:      models.JournalPayout,
+		BookingID: &e.BookingID,
+		Reference: p.ID.String(),
+		Currency:  p.Currency,
+		Memo:      "paid out in " + p.TransferID,
+	}
+	return s.Post(ctx, tx, entry,
+		Leg{models.LedgerFreelancerPayable, e.FreelancerID, e.Net},
+		Leg{models.LedgerCash, uuid.Nil, e.Net.Neg()},
+	)
+}
+
+// Balances returns the user's accounts and what they hold.
+func (s *LedgerService) Balances(ctx context.Context, userID uuid.UUID) ([]AccountBalance, error) {
+	accounts, err := s.repo.ListAccountsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.balances(ctx, accounts)
+}
+
+// TrialBalance returns every account and what it holds.
+func (s *LedgerService) TrialBalance(ctx context.Context) ([]AccountBalance, error) {
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.balances(ctx, accounts)
+}
+
+// Check looks for inconsistencies: entries that do not balance, lines in a
+// currency other than their entry's, and bookings whose cash in the ledger
+// differs from what their payments captured net of refunds.
+func (s *LedgerService) Check(ctx context.Context) ([]string, error) {
+	var problems []string
+
+	unbalanced, err := s.repo.UnbalancedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range unbalanced {
+		problems = append(problems, fmt.Sprintf("entry %s does not balance", id))
+	}
+
+	mismatched, err := s.repo.MismatchedLines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range mismatched {
+		problems = append(problems, fmt.Sprintf("line %s is not booked in its entry's currency", id))
+	}
+
+	cash, err := s.repo.CashByBooking(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := s.payments.NetByBooking(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var bookings []uuid.UUID
+	for id := range cash {
+		bookings = append(bookings, id)
+	}
+	for id := range paid {
+		if _, ok := cash[id]; !ok {
+			bookings = append(bookings, id)
+		}
+	}
+	sort.Slice(bookings, func(i, j int) bool { return bookings[i].String() < bookings[j].String() })
+	for _, id := range bookings {
+		if cash[id] != paid[id] {
+			problems = append(problems, fmt.Sprintf(
+				"booking %s: ledger cash is %d but payments net %d", id, cash[id], paid[id]))
+		}
+	}
+	return problems, nil
+}
+
+func (s *LedgerService) balances(ctx context.Context, accounts []models.LedgerAccount) ([]AccountBalance, error) {
+	ids := make([]uuid.UUID, len(accounts))
+	for i, a := range accounts {
+		ids[i] = a.ID
+	}
+	totals, err := s.repo.Balances(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AccountBalance, len(accounts))
+	for i, a := range accounts {
+		balance := totals[a.ID]
+		if !a.DebitNormal() {
+			balance = -balance
+		}
+		out[i] = AccountBalance{LedgerAccount: a, Balance: money.New(balance, a.Currency)}
+	}
+	return out, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPrice    = errors.New("price must be a positive amount in the offer's currency")
+	ErrAddOnNotFound   = errors.New("add-on not found")
+	ErrPackageNotFound = errors.New("package not found")
+)
+
+// AddOnInput describes an add-on. Price is in the offer's currency.
+type AddOnInput struct {
+	Name        string
+	Description string
+	Price       money.Decimal
+	ExtraMin    int
+}
+
+// PackageInput describes a package. Price is in the offer's currency.
+type PackageInput struct {
+	Name      string
+	Credits   int
+	Price     money.Decimal
+	ValidDays int
+}
+
+// OfferExtrasService manages what freelancers sell besides their offers:
+// add-ons picked when booking, and packages of prepaid bookings. Removed
+// extras are only deactivated, since bookings and packs refer to them.
+type OfferExtrasService struct {
+	offers *repository.ServiceOfferRepository
+	addOns *repository.AddOnRepository
+	packs  *repository.CreditPackRepository
+}
+
+func NewOfferExtrasService(
+	offers *repository.ServiceOfferRepository,
+	addOns *repository.AddOnRepository,
+	packs *repository.CreditPackRepository,
+) *OfferExtrasService {
+	return &OfferExtrasService{offers, addOns, packs}
+}
+
+func (s *OfferExtrasService) CreateAddOn(ctx context.Context, offerID, actorID uuid.UUID, in AddOnInput) (*models.AddOn, error) {
+	offer, err := s.ownOffer(ctx, offerID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	price, err := money.Parse(string(in.Price), offer.Currency)
+	if err != nil || price.Amount < 0 {
+		return nil, ErrInvalidPrice
+	}
+	a := &models.AddOn{
+		OfferID:     offer.ID,
+		Name:        in.Name,
+		Description: in.Description,
+		Price:       price,
+		Currency:    offer.Currency,
+		ExtraMin:    in.ExtraMin,
+		IsActive:    true,
+	}
+	if err := s.addOns.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListAddOns returns the offer's active add-ons.
+func (s *OfferExtrasService) ListAddOns(ctx context.Context, offerID uuid.UUID) ([]models.AddOn, error) {
+	return s.addOns.ListByOffer(ctx, offerID, false)
+}
+
+// RemoveAddOn stops the add-on from being offered.
+func (s *OfferExtrasService) RemoveAddOn(ctx context.Context, offerID, addOnID, actorID uuid.UUID) error {
+	if _, err := s.ownOffer(ctx, offerID, actorID); err != nil {
+		return err
+	}
+	a, err := s.addOns.FindByID(ctx, addOnID)
+	if err != nil || a.OfferID != offerID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAddOnNotFound
+		}
+		return err
+	}
+	a.IsActive = false
+	return s.addOns.Update(ctx, a)
+}
+
+func (s *OfferExtrasService) CreatePackage(ctx context.Context, offerID, actorID uuid.UUID, in PackageInput) (*models.Package, error) {
+	offer, err := s.ownOffer(ctx, offerID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	price, err := money.Parse(string(in.Price), offer.Currency)
+	if err != nil || !price.IsPositive() {
+		return nil, ErrInvalidPrice
+	}
+	p := &models.Package{
+		OfferID:   offer.ID,
+		Name:      in.Name,
+		Credits:   in.Credits,
+		Price:     price,
+		Currency:  offer.Currency,
+		ValidDays: in.ValidDays,
+		IsActive:  true,
+	}
+	if err := s.packs.CreatePackage(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListPackages returns the packages on sale for the offer.
+func (s *OfferExtrasService) ListPackages(ctx context.Context, offerID uuid.UUID) ([]models.Package, error) {
+	return s.packs.ListPackages(ctx, offerID, false)
+}
+
+// RemovePackage takes the package off sale. Packs already bought stay
+// usable.
+func (s *OfferExtrasService) RemovePackage(ctx context.Context, offerID, packageID, actorID uuid.UUID) error {
+	if _, err := s.ownOffer(ctx, offerID, actorID); err != nil {
+		return err
+	}
+	p, err := s.packs.FindPackage(ctx, packageID)
+	if err != nil || p.OfferID != offerID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPackageNotFound
+		}
+		return err
+	}
+	p.IsActive = false
+	return s.packs.UpdatePackage(ctx, p)
+}
+
+// CreditPacks returns the packs the owner bought with what remains of them.
+func (s *OfferExtrasService) CreditPacks(ctx context.Context, ownerID uuid.UUID) ([]models.CreditPack, error) {
+	return s.packs.ListByOwner(ctx, ownerID)
+}
+
+func (s *OfferExtrasService) ownOffer(ctx context.Context, offerID, actorID uuid.UUID) (*models.ServiceOffer, error) {
+	return findOwnOffer(ctx, s.offers, offerID, actorID)
+}
+
+// findOwnOffer loads the offer, provided actorID is its freelancer.
+func findOwnOffer(ctx context.Context, offers *repository.ServiceOfferRepository, offerID, actorID uuid.UUID) (*models.ServiceOffer, error) {
+	offer, err := offers.FindByID(ctx, offerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	if offer.FreelancerID != actorID {
+		return nil, ErrNotOfferFreelancer
+	}
+	return offer, nil
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/payments"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNothingToPay = errors.New("booking has no outstanding payment")
+	ErrNotPayer     = errors.New("only the owner pays for a booking")
+)
+
+// PaymentService collects what owners pay up front for prepaid offers and
+// deposits. A booking awaiting payment holds its slot until the provider
+// reports the payment authorized, at which point it is captured and the
+// booking becomes pending; unpaid bookings are cancelled after timeout.
+// Refunds of cancelled bookings are settled by the PayoutService. Credit
+// packs are paid the same way and become usable once captured. Captures and
+// refunds are booked in the ledger along with the payment. The provider is
+// only asked to capture or release a payment once that is committed; calls
+// that fail are made again by Run.
+type PaymentService struct {
+	bookings *BookingService
+	repo     *repository.PaymentRepository
+	packs    *repository.CreditPackRepository
+	ledger   *LedgerService
+	provider payments.PaymentProvider
+	timeout  time.Duration
+}
+
+// PackPurchase is a credit pack bought by an owner and the payment that
+// activates it.
+type PackPurchase struct {
+	Pack    *models.CreditPack `json:"pack"`
+	Payment *models.Payment    `json:"payment"`
+}
+
+func NewPaymentService(
+	bookings *BookingService,
+	repo *repository.PaymentRepository,
+	packs *repository.CreditPackRepository,
+	ledger *LedgerService,
+	provider payments.PaymentProvider,
+	timeout time.Duration,
+) *PaymentService {
+	return &PaymentService{bookings, repo, packs, ledger, provider, timeout}
+}
+
+// Start returns the open payment of the owner's booking, creating an intent
+// with the provider if there is none.
+func (s *PaymentService) Start(ctx context.Context, bookingID, actorID uuid.UUID) (*models.Payment, error) {
+	b := s.bookings
+	var payment *models.Payment
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		booking, offer, err := b.lockBookingForParty(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if actorID != booking.OwnerID {
+			return ErrNotPayer
+		}
+		if booking.Status != models.BookingStatusAwaitingPayment || !booking.DepositOutstanding() {
+			return ErrNothingToPay
+		}
+
+		attempts, err := s.repo.WithTx(tx).ListByBooking(ctx, booking.ID)
+		if err != nil {
+			return err
+		}
+		if n := len(attempts); n > 0 && attempts[n-1].Status == models.PaymentRequiresPayment {
+			payment = &attempts[n-1]
+			return nil
+		}
+
+		intent, err := s.provider.CreateIntent(ctx, payments.IntentRequest{
+			Amount:         booking.DepositAmount.Amount,
+			Currency:       offer.Currency,
+			Reference:      booking.ID.String(),
+			IdempotencyKey: fmt.Sprintf("booking-%s-%d", booking.ID, len(attempts)+1),
+		})
+		if err != nil {
+			return err
+		}
+		payment = &models.Payment{
+			BookingID:    booking.ID,
+			Provider:     s.provider.Name(),
+			IntentID:     intent.ID,
+			ClientSecret: intent.ClientSecret,
+			Amount:       money.New(intent.Amount, intent.Currency),
+			Refunded:     money.New(0, intent.Currency),
+			Currency:     intent.Currency,
+			Status:       models.PaymentRequiresPayment,
+		}
+		return s.repo.WithTx(tx).Create(ctx, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// BuyPack starts the owner's purchase of a package: the credit pack is
+// created awaiting payment, together with an intent for its price.
+func (s *PaymentService) BuyPack(ctx context.Context, packageID, ownerID uuid.UUID) (*PackPurchase, error) {
+	b := s.bookings
+	purchase := &PackPurchase{}
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		pkg, err := s.packs.WithTx(tx).FindPackage(ctx, packageID)
+		if err != nil || !pkg.IsActive {
+			if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPackageNotFound
+			}
+			return err
+		}
+		offer, err := b.findOffer(ctx, tx, pkg.OfferID)
+		if err != nil {
+			return err
+		}
+		if offer.FreelancerID == ownerID {
+			return ErrOwnOffer
+		}
+
+		pack := &models.CreditPack{
+			PackageID: pkg.ID,
+			OfferID:   pkg.OfferID,
+			OwnerID:   ownerID,
+			Name:      pkg.Name,
+			Credits:   pkg.Credits,
+			Remaining: pkg.Credits,
+			Price:     pkg.Price,
+			Currency:  pkg.Currency,
+			ValidDays: pkg.ValidDays,
+			Status:    models.CreditPackAwaitingPayment,
+		}
+		if err := s.packs.WithTx(tx).Create(ctx, pack); err != nil {
+			return err
+		}
+		intent, err := s.provider.CreateIntent(ctx, payments.IntentRequest{
+			Amount:         pack.Price.Amount,
+			Currency:       pack.Currency,
+			Reference:      pack.ID.String(),
+			IdempotencyKey: "pack-" + pack.ID.String(),
+		})
+		if err != nil {
+			return err
+		}
+		payment := &models.Payment{
+			PackID:       &pack.ID,
+			Provider:     s.provider.Name(),
+			IntentID:     intent.ID,
+			ClientSecret: intent.ClientSecret,
+			Amount:       money.New(intent.Amount, intent.Currency),
+			Refunded:     money.New(0, intent.Currency),
+			Currency:     intent.Currency,
+			Status:       models.PaymentRequiresPayment,
+		}
+		purchase.Pack, purchase.Payment = pack, payment
+		return s.repo.WithTx(tx).Create(ctx, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
+
+// List returns the payment attempts of a booking to either party.
+func (s *PaymentService) List(ctx context.Context, bookingID, actorID uuid.UUID) ([]models.Payment, error) {
+	b := s.bookings
+	booking, err := b.bookingRepo.FindByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	offer, err := b.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID != booking.OwnerID && actorID != offer.FreelancerID {
+		return nil, ErrNotBookingParty
+	}
+	return s.repo.ListByBooking(ctx, bookingID)
+}
+
+// HandleWebhook applies a provider notification. Events are applied once;
+// redeliveries and events about unknown intents are acknowledged and ignored.
+func (s *PaymentService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	ev, err := s.provider.VerifyWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+
+	b := s.bookings
+	var (
+		settled *models.Payment
+		booking *models.Booking
+		offer   *models.ServiceOffer
+		pack    *models.CreditPack
+		paid    bool
+	)
+	now := time.Now()
+
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fresh, err := s.repo.WithTx(tx).RecordEvent(ctx, &models.PaymentEvent{
+			ID:       ev.ID,
+			Provider: s.provider.Name(),
+			Type:     ev.Type,
+			IntentID: ev.IntentID,
+		})
+		if err != nil || !fresh {
+			return err
+		}
+		payment, err := s.repo.WithTx(tx).FindByIntentForUpdate(ctx, ev.IntentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentRequiresPayment {
+			return nil
+		}
+
+		switch ev.Type {
+		case payments.EventIntentFailed:
+			payment.Status = models.PaymentFailed
+			return s.repo.WithTx(tx).Update(ctx, payment)
+
+		case payments.EventIntentAuthorized:
+			settled = payment
+			if payment.PackID != nil {
+				pack, err = s.activatePack(ctx, tx, payment, now)
+				return err
+			}
+			booking, err = b.bookingRepo.WithTx(tx).FindByIDForUpdate(ctx, payment.BookingID)
+			if err != nil {
+				return err
+			}
+			if booking.Status != models.BookingStatusAwaitingPayment {
+				// the booking expired or was cancelled meanwhile
+				payment.Status = models.PaymentRefunded
+				payment.Refunded = payment.Amount
+				payment.ProviderAction = models.PaymentActionRelease
+				return s.repo.WithTx(tx).Update(ctx, payment)
+			}
+			payment.Status = models.PaymentCaptured
+			payment.ProviderAction = models.PaymentActionCapture
+			if err := s.repo.WithTx(tx).Update(ctx, payment); err != nil {
+				return err
+			}
+			if err := s.ledger.Charge(ctx, tx, payment, booking.OwnerID); err != nil {
+				return err
+			}
+			booking.DepositPaidAt = &now
+			booking.Status = models.BookingStatusPending
+			if err := b.bookingRepo.WithTx(tx).Update(ctx, booking); err != nil {
+				return err
+			}
+			offer, err = b.offerRepo.WithTx(tx).FindByID(ctx, booking.OfferID)
+			paid = err == nil
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// what was committed stands; a failed call is made again by Run
+	if settled != nil {
+		if err := s.completeProviderAction(ctx, settled); err != nil {
+			fmt.Printf("warning: %v\n", err)
+		}
+	}
+	if pack != nil {
+		s.emit(ctx, pack.OwnerID, "Credit pack ready",
+			fmt.Sprintf("Your payment of %s went through. %q has %d credits to use until %s.",
+				pack.Price, pack.Name, pack.Remaining, pack.ExpiresAt.Format("Jan 2, 2006")))
+	}
+	if paid {
+		amount := booking.DepositAmount.String()
+		s.emit(ctx, booking.OwnerID, "Payment received",
+			fmt.Sprintf("Your payment of %s for %q went through. The freelancer will confirm the booking.", amount, offer.Title))
+		s.emit(ctx, offer.FreelancerID, "New paid booking",
+			fmt.Sprintf("An owner paid %s up front for %q. Please confirm the booking.", amount, offer.Title))
+	}
+	return nil
+}
+
+// activatePack records the capture of a credit pack's payment and starts
+// the pack's validity.
+func (s *PaymentService) activatePack(ctx context.Context, tx *gorm.DB, payment *models.Payment, now time.Time) (*models.CreditPack, error) {
+	pack, err := s.packs.WithTx(tx).FindByIDForUpdate(ctx, *payment.PackID)
+	if err != nil {
+		return nil, err
+	}
+	payment.Status = models.PaymentCaptured
+	payment.ProviderAction = models.PaymentActionCapture
+	if err := s.repo.WithTx(tx).Update(ctx, payment); err != nil {
+		return nil, err
+	}
+	if err := s.ledger.Charge(ctx, tx, payment, pack.OwnerID); err != nil {
+		return nil, err
+	}
+	expires := now.AddDate(0, 0, pack.ValidDays)
+	pack.Status = models.CreditPackActive
+	pack.PaidAt = &now
+	pack.ExpiresAt = &expires
+	return pack, s.packs.WithTx(tx).Update(ctx, pack)
+}
+
+// ExpireUnpaid cancels the bookings whose payment did not arrive within the
+// timeout and frees what they held.
+func (s *PaymentService) ExpireUnpaid(ctx context.Context, now time.Time) error {
+	b := s.bookings
+	due, err := b.bookingRepo.ListAwaitingPayment(ctx, now.Add(-s.timeout))
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		var (
+			booking *models.Booking
+			offer   *models.ServiceOffer
+		)
+		err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			booking, err = b.bookingRepo.WithTx(tx).FindByIDForUpdate(ctx, d.ID)
+			if err != nil {
+				return err
+			}
+			if booking.Status != models.BookingStatusAwaitingPayment {
+				booking = nil
+				return nil
+			}
+			if offer, err = b.offerRepo.WithTx(tx).FindByID(ctx, booking.OfferID); err != nil {
+				return err
+			}
+			if _, err := b.release(ctx, tx, booking, offer); err != nil {
+				return err
+			}
+			if err := b.quotes.returnRedeemed(ctx, tx, booking); err != nil {
+				return err
+			}
+			booking.Status = models.BookingStatusCancelled
+			booking.CancelledAt = &now
+			booking.CancellationReason = "payment was not received in time"
+			return b.bookingRepo.WithTx(tx).Update(ctx, booking)
+		})
+		if err != nil {
+			log.Printf("payments: expiring booking %s failed: %v", d.ID, err)
+			continue
+		}
+		if booking != nil {
+			s.emit(ctx, booking.OwnerID, "Booking expired",
+				fmt.Sprintf("Your booking of %q was cancelled because the payment did not arrive in time.", offer.Title))
+		}
+	}
+	return nil
+}
+
+// CompleteProviderActions makes the provider calls that failed after their
+// payments were committed.
+func (s *PaymentService) CompleteProviderActions(ctx context.Context) error {
+	list, err := s.repo.ListProviderActions(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if err := s.completeProviderAction(ctx, &list[i]); err != nil {
+			log.Printf("payments: %v", err)
+		}
+	}
+	return nil
+}
+
+// completeProviderAction asks the provider to capture or release p, as
+// recorded, and clears the action once it has.
+func (s *PaymentService) completeProviderAction(ctx context.Context, p *models.Payment) error {
+	var err error
+	switch p.ProviderAction {
+	case models.PaymentActionCapture:
+		_, err = s.provider.Capture(ctx, p.IntentID)
+	case models.PaymentActionRelease:
+		_, err = s.provider.Refund(ctx, p.IntentID, p.Amount.Amount)
+		if errors.Is(err, payments.ErrInvalidState) {
+			err = nil // nothing is authorized any more
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not %s payment %s: %w", p.ProviderAction, p.ID, err)
+	}
+	return s.repo.ClearProviderAction(ctx, p.ID, p.ProviderAction)
+}
+
+// Run expires unpaid bookings and retries outstanding provider calls every
+// tick until ctx is done.
+func (s *PaymentService) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.ExpireUnpaid(ctx, now); err != nil {
+				log.Printf("payments: listing unpaid bookings failed: %v", err)
+			}
+			if err := s.CompleteProviderActions(ctx); err != nil {
+				log.Printf("payments: listing outstanding provider calls failed: %v", err)
+			}
+		}
+	}
+}
+
+// refundPayment pays amount of the captured payment p back to ownerID and
+// books it, first making a capture the provider still owes. On failure, what
+// was not refunded stays in the owner's wallet in the ledger.
+func refundPayment(
+	ctx context.Context,
+	db *gorm.DB,
+	repo *repository.PaymentRepository,
+	provider payments.PaymentProvider,
+	ledger *LedgerService,
+	p *models.Payment,
+	ownerID uuid.UUID,
+	amount money.Money,
+) error {
+	if p.ProviderAction == models.PaymentActionCapture {
+		if _, err := provider.Capture(ctx, p.IntentID); err != nil {
+			return fmt.Errorf("%w %s: %v", ErrRefundFailed, p.ID, err)
+		}
+		p.ProviderAction = ""
+	}
+	if _, err := provider.Refund(ctx, p.IntentID, amount.Amount); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrRefundFailed, p.ID, err)
+	}
+	p.Refunded = p.Refunded.Add(amount)
+	p.Status = models.PaymentPartlyRefunded
+	if p.Refunded.Amount == p.Amount.Amount {
+		p.Status = models.PaymentRefunded
+	}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := repo.WithTx(tx).Update(ctx, p); err != nil {
+			return err
+		}
+		return ledger.Refund(ctx, tx, p, ownerID, amount)
+	})
+	if err != nil {
+		return fmt.Errorf("saving refund of payment %s: %w", p.ID, err)
+	}
+	return nil
+}
+
+func (s *PaymentService) emit(ctx context.Context, userID uuid.UUID, title, msg string) {
+	if err := s.bookings.activitySvc.Emit(ctx, userID, title, msg, "payment"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+}
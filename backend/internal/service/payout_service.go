@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/payments"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNothingToRefund = errors.New("nothing was paid for this booking that could be refunded")
+	ErrRefundTooLarge  = errors.New("refund is more than what was paid and not yet refunded")
+	ErrEarningPaidOut  = errors.New("the booking's earnings were already paid out")
+	ErrRefundFailed    = errors.New("the payment provider could not refund the payment")
+)
+
+// PayoutService turns what the platform collected for a booking into the
+// freelancer's earning once the booking is completed, or cancelled with
+// part of the payment kept, and pays available earnings out in batches.
+//
+// The commission is the one agreed with the freelancer, else the service's,
+// else the platform default. Earnings are held for a while after completion
+// so disputes can still be settled before the money leaves.
+type PayoutService struct {
+	bookings      *BookingService
+	repo          *repository.PayoutRepository
+	payments      *repository.PaymentRepository
+	services      *repository.ServiceRepository
+	settings      *repository.FreelancerSettingsRepository
+	ledger        *LedgerService
+	provider      payments.PaymentProvider
+	commissionBps int
+	hold          time.Duration
+}
+
+// EarningsSummary is what a freelancer sees of their earnings.
+type EarningsSummary struct {
+	Totals  []models.EarningTotals `json:"totals"`
+	Payouts []models.Payout        `json:"payouts"`
+}
+
+// NewPayoutService registers for completed and cancelled bookings.
+func NewPayoutService(
+	bookings *BookingService,
+	repo *repository.PayoutRepository,
+	paymentRepo *repository.PaymentRepository,
+	services *repository.ServiceRepository,
+	settings *repository.FreelancerSettingsRepository,
+	ledger *LedgerService,
+	provider payments.PaymentProvider,
+	commissionBps int,
+	hold time.Duration,
+) *PayoutService {
+	s := &PayoutService{bookings, repo, paymentRepo, services, settings, ledger, provider, commissionBps, hold}
+	bookings.OnCompleted(s.recordEarning)
+	bookings.OnCancelled(s.settleCancelled)
+	return s
+}
+
+// Earnings returns the freelancer's totals per currency and recent payouts.
+func (s *PayoutService) Earnings(ctx context.Context, freelancerID uuid.UUID) (*EarningsSummary, error) {
+	totals, err := s.repo.Totals(ctx, freelancerID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListPayouts(ctx, freelancerID, 50)
+	if err != nil {
+		return nil, err
+	}
+	return &EarningsSummary{Totals: totals, Payouts: list}, nil
+}
+
+// PayAvailable batches the earnings available at now into one payout per
+// freelancer and currency and sends them. A failed payout gives its earnings
+// back, so the next run tries again.
+func (s *PayoutService) PayAvailable(ctx context.Context, now time.Time) error {
+	due, err := s.repo.ListPayable(ctx, now)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(due); {
+		end := start + 1
+		for end < len(due) && due[end].FreelancerID == due[start].FreelancerID && due[end].Currency == due[start].Currency {
+			end++
+		}
+		if err := s.pay(ctx, due[start:end]); err != nil {
+			log.Printf("payouts: paying freelancer %s failed: %v", due[start].FreelancerID, err)
+		}
+		start = end
+	}
+	return nil
+}
+
+// Run pays available earnings out every tick until ctx is done.
+func (s *PayoutService) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.PayAvailable(ctx, now); err != nil {
+				log.Printf("payouts: listing available earnings failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *PayoutService) pay(ctx context.Context, earnings []models.Earning) error {
+	db := s.bookings.db.WithContext(ctx)
+	payout := &models.Payout{
+		FreelancerID: earnings[0].FreelancerID,
+		Currency:     earnings[0].Currency,
+		Amount:       money.New(0, earnings[0].Currency),
+		Status:       models.PayoutPending,
+	}
+	ids := make([]uuid.UUID, len(earnings))
+	for i, e := range earnings {
+		payout.Amount = payout.Amount.Add(e.Net)
+		ids[i] = e.ID
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).CreatePayout(ctx, payout); err != nil {
+			return err
+		}
+		return s.repo.WithTx(tx).AttachEarnings(ctx, payout.ID, ids)
+	})
+	if err != nil {
+		return err
+	}
+
+	transferID, sendErr := s.provider.Payout(ctx, payments.PayoutRequest{
+		Amount:         payout.Amount.Amount,
+		Currency:       payout.Currency,
+		Payee:          payout.FreelancerID.String(),
+		IdempotencyKey: "payout-" + payout.ID.String(),
+	})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if sendErr != nil {
+			payout.Status = models.PayoutFailed
+			payout.FailureReason = sendErr.Error()
+			if err := repo.UpdatePayout(ctx, payout); err != nil {
+				return err
+			}
+			return repo.DetachEarnings(ctx, payout.ID)
+		}
+		now := time.Now()
+		payout.Status = models.PayoutPaid
+		payout.TransferID = transferID
+		payout.PaidAt = &now
+		if err := repo.UpdatePayout(ctx, payout); err != nil {
+			return err
+		}
+		for i := range earnings {
+			if err := s.ledger.Payout(ctx, tx, payout, &earnings[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if err := s.bookings.activitySvc.Emit(ctx, payout.FreelancerID, "Payout sent",
+		fmt.Sprintf("We sent you %s for %d completed booking(s).", payout.Amount, len(earnings)), "payout"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+	return nil
+}
+
+// settleCancelled refunds what the cancellation policy gives back on the
+// booking's captured payments, then earns the rest. A refund that fails is
+// not earned either; it stays owed to the owner.
+func (s *PayoutService) settleCancelled(ctx context.Context, booking *models.Booking) {
+	list, err := s.payments.ListByBooking(ctx, booking.ID)
+	if err != nil {
+		fmt.Printf("warning: could not load payments of booking %s: %v\n", booking.ID, err)
+		return
+	}
+	var owed int64
+	for i := range list {
+		p := &list[i]
+		amount := cancellationRefund(p, booking.RefundPercent)
+		if !amount.IsPositive() {
+			continue
+		}
+		if err := refundPayment(ctx, s.bookings.db, s.payments, s.provider, s.ledger, p, booking.OwnerID, amount); err != nil {
+			fmt.Printf("warning: could not refund cancelled booking %s: %v\n", booking.ID, err)
+			owed += amount.Amount
+		}
+	}
+	s.earn(ctx, booking, owed)
+}
+
+// recordEarning settles what the platform holds for a completed booking.
+func (s *PayoutService) recordEarning(ctx context.Context, booking *models.Booking) {
+	s.earn(ctx, booking, 0)
+}
+
+// earn records the booking's earning: captured payments net of their
+// refunds and of owed minor units still to be refunded on them, plus the
+// value of a credit it redeemed, less the commission.
+func (s *PayoutService) earn(ctx context.Context, booking *models.Booking, owed int64) {
+	list, err := s.payments.ListByBooking(ctx, booking.ID)
+	if err != nil {
+		fmt.Printf("warning: could not load payments of booking %s: %v\n", booking.ID, err)
+		return
+	}
+	// a redeemed credit was paid for with its pack
+	gross := booking.CreditValue.Amount - owed
+	currency := booking.Currency
+	for _, p := range list {
+		switch p.Status {
+		case models.PaymentCaptured, models.PaymentPartlyRefunded, models.PaymentRefunded:
+			gross += p.Amount.Amount - p.Refunded.Amount
+			currency = p.Currency
+		}
+	}
+	if gross <= 0 {
+		return
+	}
+	total := money.New(gross, currency)
+
+	offer, err := s.bookings.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		fmt.Printf("warning: could not load offer of booking %s: %v\n", booking.ID, err)
+		return
+	}
+	bps, err := s.commissionFor(ctx, offer)
+	if err != nil {
+		fmt.Printf("warning: could not find commission of booking %s: %v\n", booking.ID, err)
+		return
+	}
+	commission := models.Commission(total, bps)
+	earning := &models.Earning{
+		BookingID:     booking.ID,
+		FreelancerID:  offer.FreelancerID,
+		Currency:      currency,
+		Gross:         total,
+		CommissionBps: bps,
+		Commission:    commission,
+		Net:           total.Sub(commission),
+		AvailableAt:   time.Now().Add(s.hold),
+	}
+	err = s.bookings.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		created, err := s.repo.WithTx(tx).CreateEarning(ctx, earning)
+		if err != nil || !created {
+			return err
+		}
+		return s.ledger.Earn(ctx, tx, earning, booking.OwnerID)
+	})
+	if err != nil {
+		fmt.Printf("warning: could not record earning of booking %s: %v\n", booking.ID, err)
+	}
+}
+
+// reverseEarning takes amount off the booking's earning so it can be
+// refunded, as long as the earning has not been paid out. The commission is
+// recomputed on what remains.
+func (s *PayoutService) reverseEarning(ctx context.Context, tx *gorm.DB, booking *models.Booking, amount money.Money) error {
+	e, err := s.repo.WithTx(tx).FindEarningForUpdate(ctx, booking.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNothingToRefund
+		}
+		return err
+	}
+	if e.PayoutID != nil {
+		return ErrEarningPaidOut
+	}
+	// what is left of the earning beyond a redeemed credit was paid in cash
+	refundable := e.Gross.Amount - booking.CreditValue.Amount
+	if refundable <= 0 {
+		return ErrNothingToRefund
+	}
+	if amount.Amount > refundable {
+		return ErrRefundTooLarge
+	}
+
+	gross := e.Gross.Sub(amount)
+	commission := models.Commission(gross, e.CommissionBps)
+	reversed := e.Commission.Sub(commission)
+	e.Gross, e.Commission, e.Net = gross, commission, gross.Sub(commission)
+	if err := s.repo.WithTx(tx).UpdateEarning(ctx, e); err != nil {
+		return err
+	}
+	return s.ledger.Reverse(ctx, tx, e, booking.OwnerID, amount, reversed)
+}
+
+// refundPayments pays amount back on the booking's captured payments, in
+// the order they were made, as part of tx.
+func (s *PayoutService) refundPayments(ctx context.Context, tx *gorm.DB, booking *models.Booking, amount money.Money) error {
+	repo := s.payments.WithTx(tx)
+	list, err := repo.ListByBooking(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		p := &list[i]
+		if amount.IsZero() {
+			break
+		}
+		if p.Status != models.PaymentCaptured && p.Status != models.PaymentPartlyRefunded {
+			continue
+		}
+		part := p.Amount.Sub(p.Refunded)
+		if amount.Amount < part.Amount {
+			part = amount
+		}
+		if err := refundPayment(ctx, tx, repo, s.provider, s.ledger, p, booking.OwnerID, part); err != nil {
+			return err
+		}
+		amount = amount.Sub(part)
+	}
+	return nil
+}
+
+// commissionFor returns the commission in basis points for bookings of the
+// offer.
+func (s *PayoutService) commissionFor(ctx context.Context, offer *models.ServiceOffer) (int, error) {
+	settings, err := s.settings.Find(ctx, offer.FreelancerID)
+	if err != nil {
+		return 0, err
+	}
+	if settings.CommissionBps != nil {
+		return *settings.CommissionBps, nil
+	}
+	if svc, err := s.services.FindByID(ctx, offer.ServiceID); err == nil && svc.CommissionBps != nil {
+		return *svc.CommissionBps, nil
+	}
+	return s.commissionBps, nil
+}
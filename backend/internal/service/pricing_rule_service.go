@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+)
+
+var ErrPricingRuleNotFound = errors.New("pricing rule not found")
+
+// PricingRuleService lets freelancers manage the rules the QuoteService
+// applies to their offers' prices.
+type PricingRuleService struct {
+	offers *repository.ServiceOfferRepository
+	repo   *repository.PricingRuleRepository
+}
+
+func NewPricingRuleService(offers *repository.ServiceOfferRepository, repo *repository.PricingRuleRepository) *PricingRuleService {
+	return &PricingRuleService{offers, repo}
+}
+
+func (s *PricingRuleService) Create(ctx context.Context, offerID, actorID uuid.UUID, rule *models.PricingRule) (*models.PricingRule, error) {
+	if _, err := findOwnOffer(ctx, s.offers, offerID, actorID); err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.OfferID = offerID
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *PricingRuleService) List(ctx context.Context, offerID uuid.UUID) ([]models.PricingRule, error) {
+	return s.repo.ListByOffer(ctx, offerID)
+}
+
+// Delete removes the rule. Bookings priced with it keep their breakdown.
+func (s *PricingRuleService) Delete(ctx context.Context, offerID, ruleID, actorID uuid.UUID) error {
+	if _, err := findOwnOffer(ctx, s.offers, offerID, actorID); err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(ctx, offerID, ruleID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPricingRuleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidQuote        = errors.New("quote a slot or a duration for slot offers, and check-in and check-out dates for stays")
+	ErrUnknownAddOn        = errors.New("add-on not found for this offer")
+	ErrSlotTooShort        = errors.New("slot is too short for the offer with the chosen add-ons")
+	ErrCreditPackNotFound  = errors.New("credit pack not found")
+	ErrCreditPackUnusable  = errors.New("credit pack is not yours, not for this offer, used up or expired")
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponUnusable      = errors.New("coupon is not active, not valid yet, expired or used up")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this booking")
+	ErrCouponMinSpend      = errors.New("booking does not reach the coupon's minimum spend")
+)
+
+// QuoteRequest is what to price. Slot offers are quoted for SlotID or, before
+// a slot is picked, for DurationMin minutes (the offer's estimate if zero).
+// Stay offers are quoted for the nights from CheckIn to CheckOut.
+type QuoteRequest struct {
+	SlotID      *uuid.UUID
+	DurationMin int
+	CheckIn     time.Time
+	CheckOut    time.Time
+	Extras
+}
+
+// Extras are what an owner picks on top of the offer when booking: add-ons,
+// a credit pack to pay with, a coupon code, and how many pets the booking
+// is for (one if zero).
+type Extras struct {
+	AddOnIDs     []uuid.UUID
+	CreditPackID *uuid.UUID
+	CouponCode   string
+	Pets         int
+}
+
+func (e Extras) pets() int {
+	if e.Pets < 1 {
+		return 1
+	}
+	return e.Pets
+}
+
+// quoteItem is what gets booked: a visit of minutes starting at start, or
+// nights nights from start. Visits in a slot must be long enough for the
+// add-ons; estimated visits are not checked and have a zero start, so rules
+// depending on when the visit is do not apply to them.
+type quoteItem struct {
+	start   time.Time
+	minutes int
+	nights  int
+	inSlot  bool
+	Extras
+}
+
+func slotItem(slot *models.AvailabilitySlot, extras Extras) quoteItem {
+	return quoteItem{
+		start:   slot.StartTime,
+		minutes: int(slot.EndTime.Sub(slot.StartTime) / time.Minute),
+		inSlot:  true,
+		Extras:  extras,
+	}
+}
+
+// quoted is a computed price together with the credit pack and coupon it
+// redeems.
+type quoted struct {
+	price    *models.PriceBreakdown
+	pack     *models.CreditPack
+	coupon   *models.Coupon
+	discount money.Money
+}
+
+// QuoteService prices bookings. Quotes can be previewed, and the same
+// computation is stored on each booking as it is made.
+type QuoteService struct {
+	bookings *BookingService
+	addOns   *repository.AddOnRepository
+	packs    *repository.CreditPackRepository
+	rules    *repository.PricingRuleRepository
+	coupons  *repository.CouponRepository
+	taxBps   int
+	feeBps   int
+}
+
+// NewQuoteService charges taxBps tax and a feeBps service fee, both in basis
+// points of the subtotal, on every booking the BookingService makes, after
+// applying the offer's pricing rules.
+func NewQuoteService(
+	bookings *BookingService,
+	addOns *repository.AddOnRepository,
+	packs *repository.CreditPackRepository,
+	rules *repository.PricingRuleRepository,
+	coupons *repository.CouponRepository,
+	taxBps, feeBps int,
+) *QuoteService {
+	s := &QuoteService{bookings, addOns, packs, rules, coupons, taxBps, feeBps}
+	bookings.quotes = s
+	return s
+}
+
+// Quote previews what ownerID would pay to book the offer.
+func (s *QuoteService) Quote(ctx context.Context, offerID, ownerID uuid.UUID, req QuoteRequest) (*models.PriceBreakdown, error) {
+	tx := s.bookings.db.WithContext(ctx)
+	offer, err := s.bookings.findOffer(ctx, tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.itemFor(ctx, offer, req)
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.compute(ctx, tx, offer, ownerID, item)
+	if err != nil {
+		return nil, err
+	}
+	return q.price, nil
+}
+
+func (s *QuoteService) itemFor(ctx context.Context, offer *models.ServiceOffer, req QuoteRequest) (quoteItem, error) {
+	if offer.IsStay() {
+		checkIn, checkOut := dateOnly(req.CheckIn), dateOnly(req.CheckOut)
+		nights := int(checkOut.Sub(checkIn).Hours() / 24)
+		if req.CheckIn.IsZero() || nights < 1 || nights > maxStayNights {
+			return quoteItem{}, ErrInvalidStayDates
+		}
+		loc, err := s.bookings.location(ctx, s.bookings.db, offer.FreelancerID)
+		if err != nil {
+			return quoteItem{}, err
+		}
+		return quoteItem{start: atTimeOfDay(checkIn, offer.CheckInTime, 14, loc), nights: nights, Extras: req.Extras}, nil
+	}
+	if req.SlotID != nil {
+		slot, err := s.bookings.slotRepo.FindByID(ctx, *req.SlotID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return quoteItem{}, ErrSlotNotFound
+			}
+			return quoteItem{}, err
+		}
+		if slot.OfferID != offer.ID {
+			return quoteItem{}, ErrSlotOfferMismatch
+		}
+		return slotItem(slot, req.Extras), nil
+	}
+	minutes := req.DurationMin
+	if minutes == 0 {
+		minutes = offer.DurationEstimateMin
+	}
+	if minutes <= 0 {
+		return quoteItem{}, ErrInvalidQuote
+	}
+	return quoteItem{minutes: minutes, Extras: req.Extras}, nil
+}
+
+// compute prices booking item of the offer for ownerID. A credit pack and
+// coupon are locked when tx is a transaction.
+func (s *QuoteService) compute(
+	ctx context.Context,
+	tx *gorm.DB,
+	offer *models.ServiceOffer,
+	ownerID uuid.UUID,
+	item quoteItem,
+) (*quoted, error) {
+	p := models.NewPriceBreakdown(offer.Currency)
+	q := &quoted{price: p}
+	pets := item.pets()
+	forPets := ""
+	if pets > 1 {
+		forPets = fmt.Sprintf(" for %d pets", pets)
+	}
+	var base money.Money
+	switch {
+	case offer.IsStay():
+		base = offer.Price.Mul(int64(item.nights * pets))
+		p.Add(models.PriceLineBase, fmt.Sprintf("%d nights at %s%s", item.nights, offer.Price, forPets), base)
+	case offer.PriceType == models.PriceTypeHourly:
+		base = offer.Price.Scale(int64(item.minutes*pets), 60)
+		p.Add(models.PriceLineBase, fmt.Sprintf("%d min at %s per hour%s", item.minutes, offer.Price, forPets), base)
+	default:
+		base = offer.Price.Mul(int64(pets))
+		p.Add(models.PriceLineBase, offer.Title+forPets, base)
+	}
+	visit, err := s.applyRules(ctx, tx, p, offer, item, base)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(item.AddOnIDs) > 0 {
+		addOns, err := s.addOns.WithTx(tx).ListActive(ctx, offer.ID, item.AddOnIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(addOns) != len(distinct(item.AddOnIDs)) {
+			return nil, ErrUnknownAddOn
+		}
+		extra := 0
+		for _, a := range addOns {
+			p.Add(models.PriceLineAddOn, a.Name, a.Price)
+			extra += a.ExtraMin
+		}
+		if item.inSlot && !offer.IsStay() && item.minutes < offer.DurationEstimateMin+extra {
+			return nil, ErrSlotTooShort
+		}
+	}
+
+	if item.CreditPackID != nil {
+		pack, err := s.packs.WithTx(tx).FindByIDForUpdate(ctx, *item.CreditPackID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCreditPackNotFound
+			}
+			return nil, err
+		}
+		if pack.OwnerID != ownerID || pack.OfferID != offer.ID || !pack.Usable(time.Now()) {
+			return nil, ErrCreditPackUnusable
+		}
+		p.Add(models.PriceLineCredit, "Credit from "+pack.Name, visit.Mul(-1))
+		q.pack = pack
+	}
+
+	if item.CouponCode != "" {
+		if err := s.applyCoupon(ctx, tx, q, offer, ownerID, item.CouponCode); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.taxBps > 0 && p.Subtotal.IsPositive() {
+		p.Add(models.PriceLineTax, "Tax ("+percent(s.taxBps)+")", p.Subtotal.Scale(int64(s.taxBps), 10000))
+	}
+	if s.feeBps > 0 && p.Subtotal.IsPositive() {
+		p.Add(models.PriceLineFee, "Service fee ("+percent(s.feeBps)+")", p.Subtotal.Scale(int64(s.feeBps), 10000))
+	}
+	return q, nil
+}
+
+// applyRules adds a surcharge or discount line for each of the offer's
+// pricing rules that applies to item, and returns base with them applied.
+// Rules are applied to base, not to each other.
+// Weekday and holiday rules apply per night of a stay, in the stay's dates,
+// and to visits by their start in the freelancer's time zone.
+func (s *QuoteService) applyRules(
+	ctx context.Context,
+	tx *gorm.DB,
+	p *models.PriceBreakdown,
+	offer *models.ServiceOffer,
+	item quoteItem,
+	base money.Money,
+) (money.Money, error) {
+	rules, err := s.rules.WithTx(tx).ListByOffer(ctx, offer.ID)
+	if err != nil || len(rules) == 0 {
+		return base, err
+	}
+
+	type unit struct {
+		start  time.Time
+		amount money.Money
+	}
+	var units []unit
+	switch {
+	case offer.IsStay():
+		night := offer.Price.Mul(int64(item.pets()))
+		for i := 0; i < item.nights; i++ {
+			units = append(units, unit{dateOnly(item.start).AddDate(0, 0, i), night})
+		}
+	case !item.start.IsZero():
+		loc, err := s.bookings.location(ctx, tx, offer.FreelancerID)
+		if err != nil {
+			return base, err
+		}
+		units = append(units, unit{item.start.In(loc), base})
+	}
+
+	visit := base
+	for i := range rules {
+		r := &rules[i]
+		applied := money.New(0, base.Currency)
+		switch r.Kind {
+		case models.PricingRuleWeekday, models.PricingRuleHoliday:
+			for _, u := range units {
+				if r.AppliesOn(u.start) {
+					applied = applied.Add(u.amount)
+				}
+			}
+		case models.PricingRuleLastMinute, models.PricingRuleEarlyBird:
+			if !item.start.IsZero() && r.AppliesToBooking(time.Until(item.start).Hours(), item.pets()) {
+				applied = base
+			}
+		case models.PricingRuleMultiPet:
+			if r.AppliesToBooking(0, item.pets()) {
+				applied = base
+			}
+		}
+		if applied.IsZero() {
+			continue
+		}
+		amount := applied.Percent(r.Percent)
+		kind := models.PriceLineSurcharge
+		if r.Percent < 0 {
+			kind = models.PriceLineDiscount
+		}
+		p.Add(kind, r.Label, amount)
+		visit = visit.Add(amount)
+	}
+	return visit, nil
+}
+
+// applyCoupon takes the coupon with the given code off the quote's subtotal,
+// provided ownerID may use it on the offer.
+func (s *QuoteService) applyCoupon(
+	ctx context.Context,
+	tx *gorm.DB,
+	q *quoted,
+	offer *models.ServiceOffer,
+	ownerID uuid.UUID,
+	code string,
+) error {
+	coupons := s.coupons.WithTx(tx)
+	coupon, err := coupons.FindByCodeForUpdate(ctx, NormalizeCouponCode(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		return err
+	}
+	if !coupon.Live(time.Now()) {
+		return ErrCouponUnusable
+	}
+	if coupon.MaxUsesPerUser > 0 {
+		n, err := coupons.CountRedemptions(ctx, coupon.ID, ownerID)
+		if err != nil {
+			return err
+		}
+		if n >= int64(coupon.MaxUsesPerUser) {
+			return ErrCouponUnusable
+		}
+	}
+	if !coupon.Covers(offer) || !q.price.Subtotal.IsPositive() {
+		return ErrCouponNotApplicable
+	}
+	if coupon.FirstBookingOnly {
+		n, err := s.bookings.bookingRepo.WithTx(tx).CountByOwner(ctx, ownerID)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCouponNotApplicable
+		}
+	}
+	if q.price.Subtotal.Amount < coupon.MinSpend.Amount {
+		return ErrCouponMinSpend
+	}
+	q.discount = coupon.Discount(q.price.Subtotal)
+	q.price.Add(models.PriceLineDiscount, "Coupon "+coupon.Code, q.discount.Mul(-1))
+	q.coupon = coupon
+	return nil
+}
+
+// NormalizeCouponCode is how coupon codes are stored and looked up:
+// trimmed and upper case.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// redeem uses up a credit of the pack the quote was paid with, and a use of
+// its coupon.
+func (s *QuoteService) redeem(ctx context.Context, tx *gorm.DB, q *quoted, booking *models.Booking) error {
+	if q.coupon != nil {
+		if err := s.redeemCoupon(ctx, tx, q, booking); err != nil {
+			return err
+		}
+	}
+	if q.pack == nil {
+		return nil
+	}
+	q.pack.Remaining--
+	if err := s.packs.WithTx(tx).Update(ctx, q.pack); err != nil {
+		return err
+	}
+	booking.CreditPackID = &q.pack.ID
+	booking.CreditValue = q.pack.CreditValue()
+	return nil
+}
+
+func (s *QuoteService) redeemCoupon(ctx context.Context, tx *gorm.DB, q *quoted, booking *models.Booking) error {
+	q.coupon.Uses++
+	if err := s.coupons.WithTx(tx).Update(ctx, q.coupon); err != nil {
+		return err
+	}
+	// the booking is created after it is priced
+	if booking.ID == uuid.Nil {
+		booking.ID = uuid.New()
+	}
+	booking.CouponID = &q.coupon.ID
+	return s.coupons.WithTx(tx).CreateRedemption(ctx, &models.CouponRedemption{
+		CouponID:  q.coupon.ID,
+		UserID:    booking.OwnerID,
+		BookingID: booking.ID,
+		Discount:  q.discount,
+		Currency:  q.discount.Currency,
+	})
+}
+
+// returnRedeemed gives back what a cancelled booking redeemed: its credit,
+// and its use of a coupon.
+func (s *QuoteService) returnRedeemed(ctx context.Context, tx *gorm.DB, booking *models.Booking) error {
+	if err := s.returnCoupon(ctx, tx, booking); err != nil {
+		return err
+	}
+	return s.returnCredit(ctx, tx, booking)
+}
+
+func (s *QuoteService) returnCoupon(ctx context.Context, tx *gorm.DB, booking *models.Booking) error {
+	if booking.CouponID == nil {
+		return nil
+	}
+	coupons := s.coupons.WithTx(tx)
+	coupon, err := coupons.FindByIDForUpdate(ctx, *booking.CouponID)
+	if err != nil {
+		return err
+	}
+	coupon.Uses--
+	if err := coupons.Update(ctx, coupon); err != nil {
+		return err
+	}
+	if err := coupons.DeleteRedemption(ctx, booking.ID); err != nil {
+		return err
+	}
+	booking.CouponID = nil
+	return nil
+}
+
+// returnCredit gives the credit a cancelled booking redeemed back to its
+// pack.
+func (s *QuoteService) returnCredit(ctx context.Context, tx *gorm.DB, booking *models.Booking) error {
+	if booking.CreditPackID == nil {
+		return nil
+	}
+	pack, err := s.packs.WithTx(tx).FindByIDForUpdate(ctx, *booking.CreditPackID)
+	if err != nil {
+		return err
+	}
+	pack.Remaining++
+	if err := s.packs.WithTx(tx).Update(ctx, pack); err != nil {
+		return err
+	}
+	booking.CreditPackID = nil
+	booking.CreditValue = money.New(0, booking.Currency)
+	return nil
+}
+
+func distinct(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// percent formats basis points as a percentage, e.g. 1950 as "19.5%".
+func percent(bps int) string {
+	return strconv.FormatFloat(float64(bps)/100, 'f', -1, 64) + "%"
+}
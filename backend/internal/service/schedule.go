@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// MaxBufferMin caps offer buffers and freelancer travel time, which bounds
+// how far around an appointment conflicts have to be searched for.
+const MaxBufferMin = 12 * 60
+
+var (
+	ErrScheduleConflict = errors.New("overlaps another appointment of this freelancer, including buffer and travel time")
+	ErrInTimeOff        = errors.New("falls inside the freelancer's time off")
+)
+
+// scheduleChecker decides whether a freelancer can take an appointment at a
+// given time, looking at time off and at the buffered appointments of all
+// their offers.
+type scheduleChecker struct {
+	slots    *repository.AvailabilitySlotRepository
+	settings *repository.FreelancerSettingsRepository
+	timeOff  *repository.TimeOffRepository
+}
+
+func (c scheduleChecker) withTx(tx *gorm.DB) scheduleChecker {
+	return scheduleChecker{c.slots.WithTx(tx), c.settings.WithTx(tx), c.timeOff.WithTx(tx)}
+}
+
+// check reports ErrInTimeOff when [start, end) of an offer overlaps the
+// freelancer's time off, and ErrScheduleConflict when, widened by the offer's
+// buffers, it overlaps an occupied slot of any of the freelancer's offers
+// widened by that offer's buffers and the freelancer's travel time. Slots
+// listed in ignore are skipped. Stays only have to stay clear of time off;
+// their nights have a capacity of their own.
+func (c scheduleChecker) check(
+	ctx context.Context,
+	offer *models.ServiceOffer,
+	start, end time.Time,
+	ignore ...uuid.UUID,
+) error {
+	off, err := c.timeOff.ListOverlapping(ctx, offer.FreelancerID, start, end)
+	if err != nil {
+		return err
+	}
+	if len(off) > 0 {
+		return ErrInTimeOff
+	}
+	if offer.IsStay() {
+		return nil
+	}
+
+	prefs, err := c.settings.Find(ctx, offer.FreelancerID)
+	if err != nil {
+		return err
+	}
+	from := start.Add(-minutes(offer.BufferBeforeMin))
+	to := end.Add(minutes(offer.BufferAfterMin))
+	margin := minutes(MaxBufferMin + prefs.TravelTimeMin)
+
+	occupied, err := c.slots.ListOccupiedByFreelancer(ctx, offer.FreelancerID, from.Add(-margin), to.Add(margin))
+	if err != nil {
+		return err
+	}
+	travel := minutes(prefs.TravelTimeMin)
+	for _, o := range occupied {
+		if contains(ignore, o.ID) {
+			continue
+		}
+		busyFrom := o.StartTime.Add(-minutes(o.BufferBeforeMin) - travel)
+		busyTo := o.EndTime.Add(minutes(o.BufferAfterMin) + travel)
+		if busyFrom.Before(to) && busyTo.After(from) {
+			return ErrScheduleConflict
+		}
+	}
+	return nil
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
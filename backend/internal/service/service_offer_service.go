@@ -2,18 +2,38 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sort"
 
 	"github.com/google/uuid"
 	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
 	"github.com/shardy678/pet-freelance/backend/internal/repository"
 )
 
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+var ErrInvalidSort = errors.New("sort must be price_asc or price_desc, with a currency to compare prices in")
+
+// DisplayOffer is an offer with its price converted for display into the
+// currency the client asked for. DisplayPrice is nil without a rate for the
+// offer's currency.
+type DisplayOffer struct {
+	models.ServiceOffer
+	DisplayPrice    *money.Money `json:"displayPrice,omitempty"`
+	DisplayCurrency string       `json:"displayCurrency,omitempty"`
+}
+
 type ServiceOfferService struct {
-	repo *repository.ServiceOfferRepository
+	repo  *repository.ServiceOfferRepository
+	rates *ExchangeRateService
 }
 
-func NewServiceOfferService(r *repository.ServiceOfferRepository) *ServiceOfferService {
-	return &ServiceOfferService{repo: r}
+func NewServiceOfferService(r *repository.ServiceOfferRepository, rates *ExchangeRateService) *ServiceOfferService {
+	return &ServiceOfferService{repo: r, rates: rates}
 }
 
 func (s *ServiceOfferService) CreateOffer(ctx context.Context, freelancerID uuid.UUID, inp *models.ServiceOffer) (*models.ServiceOffer, error) {
@@ -35,3 +55,46 @@ func (s *ServiceOfferService) ListOffers(ctx context.Context) ([]models.ServiceO
 func (s *ServiceOfferService) ListByService(ctx context.Context, serviceID uuid.UUID) ([]models.ServiceOffer, error) {
 	return s.repo.ListByService(ctx, serviceID)
 }
+
+// Display converts the offers' prices into currency, when set, and sorts
+// them by the converted price when sortBy is set. Offers that cannot be
+// converted sort last.
+func (s *ServiceOfferService) Display(ctx context.Context, offers []models.ServiceOffer, currency, sortBy string) ([]DisplayOffer, error) {
+	out := make([]DisplayOffer, len(offers))
+	for i := range offers {
+		out[i].ServiceOffer = offers[i]
+	}
+	if sortBy != "" && (currency == "" || sortBy != SortPriceAsc && sortBy != SortPriceDesc) {
+		return nil, ErrInvalidSort
+	}
+	if currency == "" {
+		return out, nil
+	}
+
+	rates, err := s.rates.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rates[currency]; !ok {
+		return nil, ErrNoExchangeRate
+	}
+	for i := range out {
+		if p, ok := rates.Convert(out[i].Price, currency); ok {
+			out[i].DisplayPrice = &p
+			out[i].DisplayCurrency = currency
+		}
+	}
+	if sortBy != "" {
+		sort.SliceStable(out, func(i, j int) bool {
+			a, b := out[i].DisplayPrice, out[j].DisplayPrice
+			if a == nil || b == nil {
+				return b == nil && a != nil
+			}
+			if sortBy == SortPriceDesc {
+				return a.Amount > b.Amount
+			}
+			return a.Amount < b.Amount
+		})
+	}
+	return out, nil
+}
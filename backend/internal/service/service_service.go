@@ -44,3 +44,16 @@ func (s *ServiceService) Get(ctx context.Context, id uuid.UUID) (*models.Service
 func (s *ServiceService) List(ctx context.Context) ([]models.Service, error) {
 	return s.repo.ListAll(ctx)
 }
+
+// SetCommission sets the commission taken on bookings of the service, in
+// basis points; nil goes back to the platform default.
+func (s *ServiceService) SetCommission(ctx context.Context, id uuid.UUID, bps *int) (*models.Service, error) {
+	found, err := s.repo.SetCommission(ctx, id, bps)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrServiceNotFound
+	}
+	return s.Get(ctx, id)
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/money"
+	"gorm.io/gorm"
+)
+
+const maxStayNights = 60
+
+var (
+	ErrNotStayOffer        = errors.New("offer is not a nightly stay offer")
+	ErrInvalidStayDates    = errors.New("check-out must be after check-in, check-in in the future and the stay at most 60 nights")
+	ErrCapacityBelowBooked = errors.New("capacity cannot go below the number of pets already booked")
+)
+
+// StayUnavailableError lists the nights of a requested stay that are closed
+// or already full.
+type StayUnavailableError struct {
+	Nights []time.Time
+}
+
+func (e *StayUnavailableError) Error() string {
+	dates := make([]string, len(e.Nights))
+	for i, n := range e.Nights {
+		dates[i] = n.Format("2006-01-02")
+	}
+	return "no capacity left on " + strings.Join(dates, ", ")
+}
+
+// StayResult is a stay booking together with what it costs.
+type StayResult struct {
+	Booking  *models.Booking `json:"booking"`
+	Nights   int             `json:"nights"`
+	Total    money.Money     `json:"total"`
+	Currency string          `json:"currency"`
+}
+
+// StayService manages per-night capacity for boarding and sitting offers
+// and books date ranges against it.
+type StayService struct {
+	bookings *BookingService
+}
+
+func NewStayService(bookings *BookingService) *StayService {
+	return &StayService{bookings}
+}
+
+// SetCapacity opens the nights in [from, to) of the freelancer's stay offer
+// with the given capacity, creating or resizing each night.
+func (s *StayService) SetCapacity(
+	ctx context.Context,
+	offerID, actorID uuid.UUID,
+	from, to time.Time,
+	capacity int,
+) ([]models.StayNight, error) {
+	from, to = dateOnly(from), dateOnly(to)
+	if !to.After(from) || to.Sub(from) > 366*24*time.Hour {
+		return nil, ErrInvalidStayDates
+	}
+	b := s.bookings
+	var nights []models.StayNight
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		offer, err := b.findOffer(ctx, tx, offerID)
+		if err != nil {
+			return err
+		}
+		if offer.FreelancerID != actorID {
+			return ErrNotOfferFreelancer
+		}
+		if !offer.IsStay() {
+			return ErrNotStayOffer
+		}
+
+		existing, err := b.nightRepo.WithTx(tx).ListByOfferForUpdate(ctx, offerID, from, to)
+		if err != nil {
+			return err
+		}
+		byDate := make(map[time.Time]*models.StayNight, len(existing))
+		for i := range existing {
+			byDate[dateOnly(existing[i].Date)] = &existing[i]
+		}
+
+		for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+			night, ok := byDate[d]
+			if ok {
+				if capacity < night.Booked {
+					return ErrCapacityBelowBooked
+				}
+				night.Capacity = capacity
+				err = b.nightRepo.WithTx(tx).Update(ctx, night)
+			} else {
+				night = &models.StayNight{OfferID: offerID, Date: d, Capacity: capacity}
+				err = b.nightRepo.WithTx(tx).Create(ctx, night)
+			}
+			if err != nil {
+				return err
+			}
+			night.Remaining = night.Capacity - night.Booked
+			nights = append(nights, *night)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nights, nil
+}
+
+// ListNights returns the open nights of an offer in [from, to).
+func (s *StayService) ListNights(ctx context.Context, offerID uuid.UUID, from, to time.Time) ([]models.StayNight, error) {
+	return s.bookings.nightRepo.ListByOffer(ctx, offerID, dateOnly(from), dateOnly(to))
+}
+
+// BookStay books every night from checkIn up to (not including) checkOut,
+// provided each one still has capacity and the freelancer is not off, and
+// prices the stay per night.
+func (s *StayService) BookStay(
+	ctx context.Context,
+	offerID, ownerID uuid.UUID,
+	checkIn, checkOut time.Time,
+	extras Extras,
+) (*StayResult, error) {
+	checkIn, checkOut = dateOnly(checkIn), dateOnly(checkOut)
+	nightCount := int(checkOut.Sub(checkIn).Hours() / 24)
+	if nightCount < 1 || nightCount > maxStayNights {
+		return nil, ErrInvalidStayDates
+	}
+	b := s.bookings
+	result := &StayResult{Nights: nightCount}
+	var offer *models.ServiceOffer
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		offer, err = b.findOffer(ctx, tx, offerID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !offer.IsStay():
+			return ErrNotStayOffer
+		case !offer.IsActive:
+			return ErrOfferInactive
+		case offer.FreelancerID == ownerID:
+			return ErrOwnOffer
+		}
+		loc, err := b.location(ctx, tx, offer.FreelancerID)
+		if err != nil {
+			return err
+		}
+		arrive := atTimeOfDay(checkIn, offer.CheckInTime, 14, loc)
+		leave := atTimeOfDay(checkOut, offer.CheckOutTime, 11, loc)
+		if !arrive.After(time.Now()) {
+			return ErrInvalidStayDates
+		}
+		if err := b.schedule.withTx(tx).check(ctx, offer, arrive, leave); err != nil {
+			return err
+		}
+
+		nights, err := b.nightRepo.WithTx(tx).ListByOfferForUpdate(ctx, offerID, checkIn, checkOut)
+		if err != nil {
+			return err
+		}
+		byDate := make(map[time.Time]*models.StayNight, len(nights))
+		for i := range nights {
+			byDate[dateOnly(nights[i].Date)] = &nights[i]
+		}
+		unavailable := &StayUnavailableError{}
+		for d := checkIn; d.Before(checkOut); d = d.AddDate(0, 0, 1) {
+			if n, ok := byDate[d]; !ok || n.Booked >= n.Capacity {
+				unavailable.Nights = append(unavailable.Nights, d)
+			}
+		}
+		if len(unavailable.Nights) > 0 {
+			return unavailable
+		}
+		for i := range nights {
+			nights[i].Booked++
+			if err := b.nightRepo.WithTx(tx).Update(ctx, &nights[i]); err != nil {
+				return err
+			}
+		}
+
+		result.Booking = &models.Booking{
+			OfferID:  offerID,
+			OwnerID:  ownerID,
+			CheckIn:  &arrive,
+			CheckOut: &leave,
+			Nights:   nightCount,
+		}
+		item := quoteItem{start: arrive, nights: nightCount, Extras: extras}
+		if err := b.priceBooking(ctx, tx, offer, result.Booking, item); err != nil {
+			return err
+		}
+		return b.bookingRepo.WithTx(tx).Create(ctx, result.Booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Total = result.Booking.Total
+	result.Currency = offer.Currency
+	msg := fmt.Sprintf(
+		"%q from %s to %s (%d nights, %s).",
+		offer.Title, checkIn.Format("Jan 2, 2006"), checkOut.Format("Jan 2, 2006"),
+		nightCount, result.Total,
+	)
+	for _, userID := range []uuid.UUID{ownerID, offer.FreelancerID} {
+		if err := b.activitySvc.Emit(ctx, userID, "Stay booked", msg, "appointment"); err != nil {
+			fmt.Printf("warning: could not emit activity: %v\n", err)
+		}
+	}
+	return result, nil
+}
+
+// releaseNights gives the nights of a stay booking back to the offer.
+func (s *BookingService) releaseNights(ctx context.Context, tx *gorm.DB, booking *models.Booking, offer *models.ServiceOffer) error {
+	loc, err := s.location(ctx, tx, offer.FreelancerID)
+	if err != nil {
+		return err
+	}
+	from := dateOnly(booking.CheckIn.In(loc))
+	nights, err := s.nightRepo.WithTx(tx).ListByOfferForUpdate(ctx, booking.OfferID, from, from.AddDate(0, 0, booking.Nights))
+	if err != nil {
+		return err
+	}
+	for i := range nights {
+		if nights[i].Booked > 0 {
+			nights[i].Booked--
+		}
+		if err := s.nightRepo.WithTx(tx).Update(ctx, &nights[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dateOnly truncates t to midnight UTC of its calendar date.
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// atTimeOfDay places an "HH:MM" clock time in loc on date, falling back to
+// defaultHour when hhmm does not parse.
+func atTimeOfDay(date time.Time, hhmm string, defaultHour int, loc *time.Location) time.Time {
+	h, m := defaultHour, 0
+	if t, err := time.Parse("15:04", hhmm); err == nil {
+		h, m = t.Hour(), t.Minute()
+	}
+	y, mo, d := date.Date()
+	return time.Date(y, mo, d, h, m, 0, 0, loc)
+}
+
+// location returns the freelancer's time zone, UTC unless they set a known
+// one.
+func (s *BookingService) location(ctx context.Context, tx *gorm.DB, freelancerID uuid.UUID) (*time.Location, error) {
+	settings, err := s.schedule.settings.WithTx(tx).Find(ctx, freelancerID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidTimeOff  = errors.New("time off must end after it starts and last at most a year")
+	ErrTimeOffNotFound = errors.New("time off not found")
+)
+
+// TimeOffConflict is an active booking that falls into a time-off period and
+// still needs to be rescheduled or cancelled.
+type TimeOffConflict struct {
+	Booking    models.Booking `json:"booking"`
+	OfferTitle string         `json:"offerTitle"`
+	StartTime  time.Time      `json:"startTime"`
+	EndTime    time.Time      `json:"endTime"`
+}
+
+type TimeOffResult struct {
+	TimeOff   *models.TimeOff   `json:"timeOff"`
+	Conflicts []TimeOffConflict `json:"conflicts"`
+}
+
+type TimeOffService struct {
+	repo        *repository.TimeOffRepository
+	bookingRepo *repository.BookingRepository
+	slotRepo    *repository.AvailabilitySlotRepository
+	offerRepo   *repository.ServiceOfferRepository
+}
+
+func NewTimeOffService(
+	repo *repository.TimeOffRepository,
+	bookingRepo *repository.BookingRepository,
+	slotRepo *repository.AvailabilitySlotRepository,
+	offerRepo *repository.ServiceOfferRepository,
+) *TimeOffService {
+	return &TimeOffService{repo: repo, bookingRepo: bookingRepo, slotRepo: slotRepo, offerRepo: offerRepo}
+}
+
+// Create records a time-off period and reports the bookings already inside it.
+func (s *TimeOffService) Create(
+	ctx context.Context,
+	freelancerID uuid.UUID,
+	start, end time.Time,
+	reason string,
+) (*TimeOffResult, error) {
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		return nil, ErrInvalidTimeOff
+	}
+	off := &models.TimeOff{FreelancerID: freelancerID, StartsAt: start.UTC(), EndsAt: end.UTC(), Reason: reason}
+	if err := s.repo.Create(ctx, off); err != nil {
+		return nil, err
+	}
+	conflicts, err := s.conflicts(ctx, off)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeOffResult{TimeOff: off, Conflicts: conflicts}, nil
+}
+
+// List returns the freelancer's current and upcoming time off.
+func (s *TimeOffService) List(ctx context.Context, freelancerID uuid.UUID) ([]models.TimeOff, error) {
+	return s.repo.ListByFreelancer(ctx, freelancerID, time.Now())
+}
+
+// Get returns one of the freelancer's time-off periods with its conflicts.
+func (s *TimeOffService) Get(ctx context.Context, id, freelancerID uuid.UUID) (*TimeOffResult, error) {
+	off, err := s.find(ctx, id, freelancerID)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := s.conflicts(ctx, off)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeOffResult{TimeOff: off, Conflicts: conflicts}, nil
+}
+
+// Delete ends a time-off period early, showing its slots again.
+func (s *TimeOffService) Delete(ctx context.Context, id, freelancerID uuid.UUID) error {
+	if _, err := s.find(ctx, id, freelancerID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *TimeOffService) find(ctx context.Context, id, freelancerID uuid.UUID) (*models.TimeOff, error) {
+	off, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimeOffNotFound
+		}
+		return nil, err
+	}
+	if off.FreelancerID != freelancerID {
+		return nil, ErrTimeOffNotFound
+	}
+	return off, nil
+}
+
+func (s *TimeOffService) conflicts(ctx context.Context, off *models.TimeOff) ([]TimeOffConflict, error) {
+	bookings, err := s.bookingRepo.ListActiveByFreelancer(ctx, off.FreelancerID, off.StartsAt, off.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[uuid.UUID]string)
+	out := make([]TimeOffConflict, 0, len(bookings))
+	for _, b := range bookings {
+		if _, ok := titles[b.OfferID]; !ok {
+			offer, err := s.offerRepo.FindByID(ctx, b.OfferID)
+			if err != nil {
+				return nil, err
+			}
+			titles[b.OfferID] = offer.Title
+		}
+		c := TimeOffConflict{Booking: b, OfferTitle: titles[b.OfferID]}
+		if b.IsStay() {
+			c.StartTime, c.EndTime = *b.CheckIn, *b.CheckOut
+		} else {
+			slot, err := s.slotRepo.FindByID(ctx, b.SlotID)
+			if err != nil {
+				return nil, err
+			}
+			c.StartTime, c.EndTime = slot.StartTime, slot.EndTime
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
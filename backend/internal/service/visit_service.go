@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// checkInLead is how long before the start a freelancer may check in
+	checkInLead       = 2 * time.Hour
+	MaxVisitPhotos    = 20
+	MaxVisitPhotoSize = 10 << 20
+)
+
+var (
+	ErrBookingNotConfirmed = errors.New("booking must be confirmed before the visit")
+	ErrTooEarlyToCheckIn   = errors.New("check-in opens two hours before the start")
+	ErrAlreadyCheckedIn    = errors.New("already checked in for this booking")
+	ErrNotCheckedIn        = errors.New("check in before reporting on the visit")
+	ErrAlreadyCheckedOut   = errors.New("already checked out of this booking")
+	ErrReportClosed        = errors.New("the visit report can no longer be changed")
+	ErrVisitReportNotFound = errors.New("visit report not found")
+	ErrInvalidPhoto        = errors.New("photo must be a JPEG, PNG, WebP or GIF image of at most 10 MB")
+	ErrTooManyPhotos       = errors.New("a visit report holds at most 20 photos")
+)
+
+// photoTypes maps the accepted image types to their file extension.
+var photoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// Coordinates is where the freelancer was when checking in or out.
+type Coordinates struct {
+	Lat float64
+	Lng float64
+}
+
+// ReportUpdate changes a visit report; nil fields are kept.
+type ReportUpdate struct {
+	Notes     *string
+	Checklist *models.VisitChecklist
+}
+
+// VisitService records check-in, check-out and the report of a visit.
+// Checking out completes the booking and tells the owner how it went.
+type VisitService struct {
+	bookings *BookingService
+	reports  *repository.VisitReportRepository
+	photoDir string
+}
+
+func NewVisitService(bookings *BookingService, reports *repository.VisitReportRepository, photoDir string) *VisitService {
+	return &VisitService{bookings, reports, photoDir}
+}
+
+// CheckIn starts the visit of a confirmed booking.
+func (s *VisitService) CheckIn(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	at *Coordinates,
+) (*models.VisitReport, error) {
+	b := s.bookings
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+		report  *models.VisitReport
+	)
+	now := time.Now()
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockForFreelancer(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		if booking.Status != models.BookingStatusConfirmed {
+			return ErrBookingNotConfirmed
+		}
+		start, err := s.startOf(ctx, tx, booking)
+		if err != nil {
+			return err
+		}
+		if now.Before(start.Add(-checkInLead)) {
+			return ErrTooEarlyToCheckIn
+		}
+
+		report, err = s.reports.WithTx(tx).FindByBookingForUpdate(ctx, booking.ID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			report = &models.VisitReport{BookingID: booking.ID, FreelancerID: actorID}
+		case err != nil:
+			return err
+		case report.CheckedInAt != nil:
+			return ErrAlreadyCheckedIn
+		}
+		report.CheckedInAt = &now
+		if at != nil {
+			report.CheckInLat, report.CheckInLng = &at.Lat, &at.Lng
+		}
+		if report.CreatedAt.IsZero() {
+			return s.reports.WithTx(tx).Create(ctx, report)
+		}
+		return s.reports.WithTx(tx).Update(ctx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.emit(ctx, booking.OwnerID, "Visit started",
+		fmt.Sprintf("The freelancer checked in for %q at %s.", offer.Title, now.Format("15:04")))
+	return report, nil
+}
+
+// CheckOut ends the visit, applies the last changes to the report and marks
+// the booking completed.
+func (s *VisitService) CheckOut(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	at *Coordinates,
+	upd ReportUpdate,
+) (*models.VisitReport, error) {
+	b := s.bookings
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+		report  *models.VisitReport
+	)
+	now := time.Now()
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, offer, err = s.lockForFreelancer(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		report, err = s.lockReport(ctx, tx, booking.ID)
+		if err != nil {
+			return err
+		}
+		if report.CheckedOutAt != nil {
+			return ErrAlreadyCheckedOut
+		}
+		// the booking may have been cancelled since check-in
+		if booking.Status != models.BookingStatusConfirmed {
+			return ErrBookingNotConfirmed
+		}
+		report.CheckedOutAt = &now
+		if at != nil {
+			report.CheckOutLat, report.CheckOutLng = &at.Lat, &at.Lng
+		}
+		upd.apply(report)
+		if err := s.reports.WithTx(tx).Update(ctx, report); err != nil {
+			return err
+		}
+
+		booking.Status = models.BookingStatusCompleted
+		booking.CompletedAt = &now
+		if err := b.bookingRepo.WithTx(tx).Update(ctx, booking); err != nil {
+			return err
+		}
+		for _, userID := range []uuid.UUID{booking.OwnerID, offer.FreelancerID} {
+			err := b.reliability.WithTx(tx).Record(ctx, &models.ReliabilityEvent{
+				UserID:    userID,
+				BookingID: booking.ID,
+				Kind:      models.ReliabilityCompleted,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.notifyCompleted(ctx, booking)
+
+	if full, err := s.reports.FindByBooking(ctx, booking.ID); err == nil {
+		report = full
+	}
+	s.emit(ctx, booking.OwnerID, "Visit completed", visitSummary(offer, report))
+	return report, nil
+}
+
+// UpdateReport changes the notes and checklist from check-in until a day
+// after check-out.
+func (s *VisitService) UpdateReport(
+	ctx context.Context,
+	bookingID, actorID uuid.UUID,
+	upd ReportUpdate,
+) (*models.VisitReport, error) {
+	b := s.bookings
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		booking, _, err := s.lockForFreelancer(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		report, err := s.lockReport(ctx, tx, booking.ID)
+		if err != nil {
+			return err
+		}
+		if !report.Editable(time.Now()) {
+			return ErrReportClosed
+		}
+		upd.apply(report)
+		return s.reports.WithTx(tx).Update(ctx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.reports.FindByBooking(ctx, bookingID)
+}
+
+// AddPhoto stores an image and attaches it to the booking's report. The file
+// is removed again unless the photo is committed with the report.
+func (s *VisitService) AddPhoto(ctx context.Context, bookingID, actorID uuid.UUID, data []byte) (*models.VisitPhoto, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := photoTypes[contentType]
+	if !ok || len(data) > MaxVisitPhotoSize {
+		return nil, ErrInvalidPhoto
+	}
+
+	b := s.bookings
+	var (
+		photo *models.VisitPhoto
+		full  string
+	)
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		booking, _, err := s.lockForFreelancer(ctx, tx, bookingID, actorID)
+		if err != nil {
+			return err
+		}
+		report, err := s.lockReport(ctx, tx, booking.ID)
+		if err != nil {
+			return err
+		}
+		if !report.Editable(time.Now()) {
+			return ErrReportClosed
+		}
+		n, err := s.reports.WithTx(tx).CountPhotos(ctx, report.ID)
+		if err != nil {
+			return err
+		}
+		if n >= MaxVisitPhotos {
+			return ErrTooManyPhotos
+		}
+
+		photo = &models.VisitPhoto{ID: uuid.New(), ReportID: report.ID, ContentType: contentType, Size: int64(len(data))}
+		photo.StoragePath = filepath.Join(report.ID.String(), photo.ID.String()+ext)
+		full = filepath.Join(s.photoDir, photo.StoragePath)
+		if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, data, 0o640); err != nil {
+			return err
+		}
+		return s.reports.WithTx(tx).AddPhoto(ctx, photo)
+	})
+	if err != nil {
+		if full != "" {
+			os.Remove(full)
+		}
+		return nil, err
+	}
+	return photo, nil
+}
+
+// Get returns the booking's report to either party.
+func (s *VisitService) Get(ctx context.Context, bookingID, actorID uuid.UUID) (*models.VisitReport, error) {
+	if err := s.checkParty(ctx, bookingID, actorID); err != nil {
+		return nil, err
+	}
+	report, err := s.reports.FindByBooking(ctx, bookingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVisitReportNotFound
+	}
+	return report, err
+}
+
+// Photo returns a photo of the booking's report and the path of its file.
+func (s *VisitService) Photo(ctx context.Context, bookingID, photoID, actorID uuid.UUID) (*models.VisitPhoto, string, error) {
+	report, err := s.Get(ctx, bookingID, actorID)
+	if err != nil {
+		return nil, "", err
+	}
+	photo, err := s.reports.FindPhoto(ctx, report.ID, photoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrVisitReportNotFound
+		}
+		return nil, "", err
+	}
+	return photo, filepath.Join(s.photoDir, photo.StoragePath), nil
+}
+
+func (s *VisitService) checkParty(ctx context.Context, bookingID, actorID uuid.UUID) error {
+	b := s.bookings
+	booking, err := b.bookingRepo.FindByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrBookingNotFound
+		}
+		return err
+	}
+	offer, err := b.offerRepo.FindByID(ctx, booking.OfferID)
+	if err != nil {
+		return err
+	}
+	if actorID != booking.OwnerID && actorID != offer.FreelancerID {
+		return ErrNotBookingParty
+	}
+	return nil
+}
+
+// lockForFreelancer locks the booking and checks that actorID is the offer's
+// freelancer.
+func (s *VisitService) lockForFreelancer(
+	ctx context.Context,
+	tx *gorm.DB,
+	bookingID, actorID uuid.UUID,
+) (*models.Booking, *models.ServiceOffer, error) {
+	booking, offer, err := s.bookings.lockBookingForParty(ctx, tx, bookingID, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actorID != offer.FreelancerID {
+		return nil, nil, ErrNotOfferFreelancer
+	}
+	return booking, offer, nil
+}
+
+func (s *VisitService) lockReport(ctx context.Context, tx *gorm.DB, bookingID uuid.UUID) (*models.VisitReport, error) {
+	report, err := s.reports.WithTx(tx).FindByBookingForUpdate(ctx, bookingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotCheckedIn
+	}
+	return report, err
+}
+
+// startOf returns when the booking is due to start.
+func (s *VisitService) startOf(ctx context.Context, tx *gorm.DB, booking *models.Booking) (time.Time, error) {
+	if booking.IsStay() {
+		return *booking.CheckIn, nil
+	}
+	slot, err := s.bookings.slotRepo.WithTx(tx).FindByID(ctx, booking.SlotID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return slot.StartTime, nil
+}
+
+func (s *VisitService) emit(ctx context.Context, userID uuid.UUID, title, msg string) {
+	if err := s.bookings.activitySvc.Emit(ctx, userID, title, msg, "visit"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+}
+
+func (u ReportUpdate) apply(r *models.VisitReport) {
+	if u.Notes != nil {
+		r.Notes = *u.Notes
+	}
+	if u.Checklist != nil {
+		r.Checklist = *u.Checklist
+	}
+}
+
+// visitSummary tells the owner how long the visit took, what was done and
+// how many photos were taken.
+func visitSummary(offer *models.ServiceOffer, r *models.VisitReport) string {
+	var sb strings.Builder
+	minutes := int(r.CheckedOutAt.Sub(*r.CheckedInAt).Round(time.Minute).Minutes())
+	fmt.Fprintf(&sb, "The visit for %q is done after %d minutes.", offer.Title, minutes)
+	if done := r.Checklist.Done(); len(done) > 0 {
+		fmt.Fprintf(&sb, " Done: %s.", strings.Join(done, ", "))
+	}
+	if n := len(r.Photos); n > 0 {
+		fmt.Fprintf(&sb, " %d photo(s) attached.", n)
+	}
+	if r.Notes != "" {
+		fmt.Fprintf(&sb, " Notes: %s", r.Notes)
+	}
+	return sb.String()
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shardy678/pet-freelance/backend/internal/models"
+	"github.com/shardy678/pet-freelance/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSlotHasSeats      = errors.New("slot still has free seats, book it directly")
+	ErrAlreadyWaitlisted = errors.New("already on the waitlist for this slot")
+	ErrNotWaitlisted     = errors.New("not on the waitlist for this slot")
+	ErrNoWaitlistOffer   = errors.New("no seat is currently offered to you for this slot")
+)
+
+// WaitlistService queues owners for fully booked slots. Each seat freed by a
+// cancellation or reschedule is held for the first owner in line, who may
+// claim it until the offer expires; then it moves on down the queue.
+type WaitlistService struct {
+	bookings *BookingService
+	repo     *repository.WaitlistRepository
+	offerTTL time.Duration
+}
+
+func NewWaitlistService(bookings *BookingService, repo *repository.WaitlistRepository, offerTTL time.Duration) *WaitlistService {
+	s := &WaitlistService{bookings, repo, offerTTL}
+	bookings.OnSeatReleased(s.seatReleased)
+	return s
+}
+
+// Join puts ownerID at the end of the slot's waitlist.
+func (s *WaitlistService) Join(ctx context.Context, slotID, ownerID uuid.UUID) (*models.WaitlistEntry, error) {
+	b := s.bookings
+	var entry *models.WaitlistEntry
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		slot, offer, err := s.lockSlot(ctx, tx, slotID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case offer.FreelancerID == ownerID:
+			return ErrOwnOffer
+		case !slot.StartTime.After(time.Now()):
+			return ErrSlotInPast
+		case slot.HasSeat():
+			return ErrSlotHasSeats
+		}
+		if _, err := s.repo.WithTx(tx).FindOpen(ctx, slotID, ownerID); err == nil {
+			return ErrAlreadyWaitlisted
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		entry = &models.WaitlistEntry{SlotID: slotID, OwnerID: ownerID, Status: models.WaitlistWaiting}
+		if err := s.repo.WithTx(tx).Create(ctx, entry); err != nil {
+			return err
+		}
+		return s.setPosition(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Get returns the owner's open entry for a slot with its queue position.
+func (s *WaitlistService) Get(ctx context.Context, slotID, ownerID uuid.UUID) (*models.WaitlistEntry, error) {
+	entry, err := s.repo.FindOpen(ctx, slotID, ownerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotWaitlisted
+		}
+		return nil, err
+	}
+	if err := s.setPosition(ctx, s.bookings.db, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Leave takes the owner off the waitlist, passing a seat held for them to
+// the next owner in line.
+func (s *WaitlistService) Leave(ctx context.Context, slotID, ownerID uuid.UUID) error {
+	return s.bookings.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry, err := s.repo.WithTx(tx).FindOpen(ctx, slotID, ownerID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotWaitlisted
+			}
+			return err
+		}
+		if entry.Status == models.WaitlistOffered {
+			if err := s.passOn(ctx, tx, slotID); err != nil {
+				return err
+			}
+		}
+		entry.Status = models.WaitlistLeft
+		return s.repo.WithTx(tx).Update(ctx, entry)
+	})
+}
+
+// Claim books the seat held for the owner while their offer is still open and
+// the freelancer is still free at the slot's time.
+func (s *WaitlistService) Claim(ctx context.Context, slotID, ownerID uuid.UUID) (*models.Booking, error) {
+	b := s.bookings
+	var (
+		booking *models.Booking
+		offer   *models.ServiceOffer
+	)
+	now := time.Now()
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry, err := s.repo.WithTx(tx).FindOpen(ctx, slotID, ownerID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoWaitlistOffer
+			}
+			return err
+		}
+		if entry.Status != models.WaitlistOffered || entry.OfferExpiresAt.Before(now) {
+			return ErrNoWaitlistOffer
+		}
+		var slot *models.AvailabilitySlot
+		slot, offer, err = s.lockSlot(ctx, tx, slotID)
+		if err != nil {
+			return err
+		}
+		slot.Unhold()
+		if err := validateBooking(offer, slot, ownerID, now); err != nil {
+			return err
+		}
+		if err := b.checkSchedule(ctx, tx, offer, slot); err != nil {
+			return err
+		}
+
+		booking = &models.Booking{OfferID: offer.ID, OwnerID: ownerID}
+		if err := b.reserveSlot(ctx, tx, offer, slot, booking, Extras{}); err != nil {
+			return err
+		}
+		entry.Status = models.WaitlistClaimed
+		entry.BookingID = &booking.ID
+		return s.repo.WithTx(tx).Update(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("You claimed your waitlisted seat for %q.", offer.Title)
+	if err := b.activitySvc.Emit(ctx, ownerID, "Waitlist seat booked", msg, "waitlist"); err != nil {
+		fmt.Printf("warning: could not emit activity: %v\n", err)
+	}
+	return booking, nil
+}
+
+// ExpireOffers closes every offer whose claim window ended before now and
+// hands the seat to the next owner in line.
+func (s *WaitlistService) ExpireOffers(ctx context.Context, now time.Time) error {
+	expired, err := s.repo.ListExpiredOffers(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, e := range expired {
+		err := s.bookings.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			entry, err := s.repo.WithTx(tx).FindByIDForUpdate(ctx, e.ID)
+			if err != nil {
+				return err
+			}
+			if entry.Status != models.WaitlistOffered || !entry.OfferExpiresAt.Before(now) {
+				return nil
+			}
+			entry.Status = models.WaitlistExpired
+			if err := s.repo.WithTx(tx).Update(ctx, entry); err != nil {
+				return err
+			}
+			return s.passOn(ctx, tx, entry.SlotID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run expires offers every interval until ctx is done.
+func (s *WaitlistService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.ExpireOffers(ctx, now); err != nil {
+				log.Printf("waitlist: expiring offers failed: %v", err)
+			}
+		}
+	}
+}
+
+// seatReleased is the BookingService seat listener: it holds a freed seat
+// for the first waiting owner, if any.
+func (s *WaitlistService) seatReleased(ctx context.Context, tx *gorm.DB, slot *models.AvailabilitySlot) error {
+	if !slot.HasSeat() || !slot.StartTime.After(time.Now()) {
+		return nil
+	}
+	return s.offerNext(ctx, tx, slot)
+}
+
+// passOn frees the seat held on a slot and offers it to the next owner.
+func (s *WaitlistService) passOn(ctx context.Context, tx *gorm.DB, slotID uuid.UUID) error {
+	slot, err := s.bookings.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	slot.Unhold()
+	if slot.StartTime.After(time.Now()) {
+		if err := s.offerNext(ctx, tx, slot); err != nil {
+			return err
+		}
+	}
+	return s.bookings.slotRepo.WithTx(tx).Update(ctx, slot)
+}
+
+// offerNext holds one seat of slot for the longest-waiting owner and tells
+// them how long they have to claim it. The caller saves slot.
+func (s *WaitlistService) offerNext(ctx context.Context, tx *gorm.DB, slot *models.AvailabilitySlot) error {
+	entry, err := s.repo.WithTx(tx).NextWaiting(ctx, slot.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expires := now.Add(s.offerTTL)
+	entry.Status = models.WaitlistOffered
+	entry.OfferedAt = &now
+	entry.OfferExpiresAt = &expires
+	if err := s.repo.WithTx(tx).Update(ctx, entry); err != nil {
+		return err
+	}
+	slot.Hold()
+
+	msg := fmt.Sprintf(
+		"A seat opened up on %s. It is held for you until %s — claim it before then.",
+		slot.StartTime.Format("Jan 2, 2006 at 15:04"), expires.Format("Jan 2, 2006 at 15:04"),
+	)
+	return s.bookings.activitySvc.WithTx(tx).Emit(ctx, entry.OwnerID, "Waitlist seat available", msg, "waitlist")
+}
+
+func (s *WaitlistService) lockSlot(ctx context.Context, tx *gorm.DB, slotID uuid.UUID) (*models.AvailabilitySlot, *models.ServiceOffer, error) {
+	slot, err := s.bookings.slotRepo.WithTx(tx).FindByIDForUpdate(ctx, slotID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSlotNotFound
+		}
+		return nil, nil, err
+	}
+	offer, err := s.bookings.findOffer(ctx, tx, slot.OfferID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slot, offer, nil
+}
+
+// setPosition fills in the entry's place in the queue; offered entries are
+// at the front.
+func (s *WaitlistService) setPosition(ctx context.Context, tx *gorm.DB, entry *models.WaitlistEntry) error {
+	if entry.Status != models.WaitlistWaiting {
+		entry.Position = 0
+		return nil
+	}
+	ahead, err := s.repo.WithTx(tx).CountAhead(ctx, entry)
+	if err != nil {
+		return err
+	}
+	entry.Position = int(ahead) + 1
+	return nil
+}
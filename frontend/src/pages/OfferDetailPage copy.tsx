@@ -14,7 +14,7 @@ type Offer = {
     id: string;
     title: string;
     description: string;
-    price: number;
+    price: string;
     currency: string;
     priceType: 'hourly' | 'fixed';
     durationEstimateMin: number;
@@ -130,7 +130,7 @@ export default function OfferDetailPage() {
                     <h1 className="text-3xl font-bold mb-2">{offer.title}</h1>
                     <p className="text-gray-700 mb-4">{offer.description}</p>
                     <div className="text-lg font-semibold">
-                        {offer.currency} {offer.price.toFixed(2)}{' '}
+                        {offer.currency} {offer.price}{' '}
                         {offer.priceType === 'hourly' ? '/hr' : ''}
                     </div>
                     <p className="text-sm text-gray-500">
@@ -266,7 +266,7 @@ export default function OfferDetailPage() {
                             </p>
                             <p className="mb-4">
                                 <strong>Price:</strong> {offer.currency}{' '}
-                                {offer.price.toFixed(2)}
+                                {offer.price}
                             </p>
                             <div className="flex justify-end space-x-2">
                                 <button
@@ -46,7 +46,7 @@ type Offer = {
   id: string;
   title: string;
   description: string;
-  price: number;
+  price: string;
   currency: string;
   priceType: 'hourly' | 'fixed';
   durationEstimateMin: number;
@@ -162,7 +162,7 @@ export default function OfferDetailPage() {
           <div className="flex items-center">
             <DollarSign className="h-5 w-5 mr-1 text-muted-foreground" />
             <span className="text-xl font-medium">
-              {offer.currency} {offer.price.toFixed(2)}
+              {offer.currency} {offer.price}
             </span>
             <span className="text-muted-foreground ml-1">
               {offer.priceType === 'hourly' ? 'per hour' : 'fixed price'}
@@ -378,7 +378,7 @@ export default function OfferDetailPage() {
               <div className="space-y-2">
                 <h4 className="font-medium">Price</h4>
                 <p className="text-lg font-semibold">
-                  {offer.currency} {offer.price.toFixed(2)}
+                  {offer.currency} {offer.price}
                 </p>
               </div>
             </div>
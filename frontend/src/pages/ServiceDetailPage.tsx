@@ -8,7 +8,8 @@ type Service = {
     id: string;
     name: string;
     description?: string;
-    basePrice: number;
+    basePrice: string;
+    currency: string;
     defaultDurationMin: number;
 };
 
@@ -16,7 +17,7 @@ type ServiceOffer = {
     id: string;
     title: string;
     description: string;
-    price: number;
+    price: string;
     currency: string;
     priceType: 'hourly' | 'fixed';
     durationEstimateMin: number;
@@ -75,7 +76,7 @@ export default function ServiceDetailPage() {
           <p className="mt-2 text-gray-600">{service.description}</p>
         )}
         <p className="mt-4 text-sm text-gray-500">
-          From ${service.basePrice.toFixed(2)} &mdash; {service.defaultDurationMin} min
+          From {service.currency} {service.basePrice} &mdash; {service.defaultDurationMin} min
         </p>
       </header>
 
@@ -102,7 +103,7 @@ export default function ServiceDetailPage() {
                 <CardContent>
                   <div className="flex items-center justify-between text-sm text-gray-500">
                     <span>
-                      {offer.currency} {offer.price.toFixed(2)}
+                      {offer.currency} {offer.price}
                       {offer.priceType === 'hourly' ? '/hr' : ''}
                     </span>
                     <span>≈ {offer.durationEstimateMin} min</span>
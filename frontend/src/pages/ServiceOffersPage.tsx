@@ -7,7 +7,7 @@ type ServiceOffer = {
   id: string;
   title: string;
   description: string;
-  price: number;
+  price: string;
   currency: string;
   priceType: 'hourly' | 'fixed';
   durationEstimateMin: number;
@@ -56,7 +56,7 @@ export default function ServiceOffersPage() {
               <CardContent>
                 <div className="flex items-center justify-between text-sm text-gray-500">
                   <span>
-                    {offer.currency} {offer.price.toFixed(2)}{offer.priceType === 'hourly' ? '/hr' : ''}
+                    {offer.currency} {offer.price}{offer.priceType === 'hourly' ? '/hr' : ''}
                   </span>
                   <span>≈ {offer.durationEstimateMin} min</span>
                 </div>
@@ -9,7 +9,8 @@ type Service = {
   id: string;
   name: string;
   description?: string;
-  basePrice: number;
+  basePrice: string;
+  currency: string;
   defaultDurationMin: number;
 };
 
@@ -60,7 +61,7 @@ export default function ServicesPage() {
               </CardHeader>
               <CardContent>
                 <p className="text-sm text-gray-500">
-                  From ${svc.basePrice.toFixed(2)} &mdash; {svc.defaultDurationMin} min
+                  From {svc.currency} {svc.basePrice} &mdash; {svc.defaultDurationMin} min
                 </p>
               </CardContent>
             </Link>